@@ -33,6 +33,8 @@ const (
 	MaximumRequestBodySize = 10 * 1024 * 1024 // bytes
 )
 
+// EnvAsHeader sets a header for each of the selected environment variables, given as NAME to send the
+// value of the variable in the environment of fn or as NAME=value
 func EnvAsHeader(req *http.Request, selectedEnv []string) {
 	detectedEnv := os.Environ()
 	if len(selectedEnv) > 0 {
@@ -40,24 +42,37 @@ func EnvAsHeader(req *http.Request, selectedEnv []string) {
 	}
 
 	for _, e := range detectedEnv {
-		kv := strings.Split(e, "=")
+		kv := strings.SplitN(e, "=", 2)
 		name := kv[0]
-		req.Header.Set(name, os.Getenv(name))
+		if len(kv) == 2 {
+			req.Header.Set(name, kv[1])
+		} else {
+			req.Header.Set(name, os.Getenv(name))
+		}
 	}
 }
 
 // InvokeRequest are the parameters provided to Invoke
 type InvokeRequest struct {
-	URL         string
-	Content     io.Reader
-	Env         []string
-	ContentType string
+	URL          string
+	Method       string
+	Content      io.Reader
+	Env          []string
+	ContentType  string
 	FnInvokeType string
-	FnIntent    string
-	IsDryRun    bool
-	// TODO headers should be their real type?
+	FnIntent     string
+	IsDryRun     bool
+	// Headers are set last, so they replace the headers of the other fields
+	Headers http.Header
+	// Stream sends Content as it is read instead of buffering it, for providers that do not sign the
+	// request body. ContentLength is its size when known, the body is sent chunked otherwise.
+	Stream        bool
+	ContentLength int64
 }
 
+// ErrRequestBodyTooLarge is returned by Invoke for a buffered request body over MaximumRequestBodySize
+var ErrRequestBodyTooLarge = fmt.Errorf("request body is larger than the maximum of %d bytes", MaximumRequestBodySize)
+
 // Invoke calls the fn invoke API
 func Invoke(provider provider.Provider, ireq InvokeRequest) (*http.Response, error) {
 	invokeURL := ireq.URL
@@ -65,20 +80,35 @@ func Invoke(provider provider.Provider, ireq InvokeRequest) (*http.Response, err
 	env := ireq.Env
 	contentType := ireq.ContentType
 	method := "POST"
+	if ireq.Method != "" {
+		method = strings.ToUpper(ireq.Method)
+	}
 
-	// Read the request body (up to the maximum size), as this is used in the
-	// authentication signature (Content-Length & Date must be set correctly)
-	var buffer bytes.Buffer
-	if content != nil {
-		_, err := io.Copy(&buffer, io.LimitReader(content, MaximumRequestBodySize))
-		if err != nil {
-			return nil, fmt.Errorf("Error creating request body: %s", err)
+	var body io.Reader
+	if ireq.Stream {
+		body = content
+	} else {
+		// Read the request body (up to the maximum size), as this is used in the
+		// authentication signature (Content-Length & Date must be set correctly)
+		var buffer bytes.Buffer
+		if content != nil {
+			n, err := io.Copy(&buffer, io.LimitReader(content, MaximumRequestBodySize+1))
+			if err != nil {
+				return nil, fmt.Errorf("Error creating request body: %s", err)
+			}
+			if n > MaximumRequestBodySize {
+				return nil, ErrRequestBodyTooLarge
+			}
 		}
+		body = &buffer
 	}
-	req, err := http.NewRequest(method, invokeURL, &buffer)
+	req, err := http.NewRequest(method, invokeURL, body)
 	if err != nil {
 		return nil, fmt.Errorf("Error creating request to service: %s", err)
 	}
+	if ireq.Stream && ireq.ContentLength > 0 {
+		req.ContentLength = ireq.ContentLength
+	}
 
 	if contentType != "" {
 		req.Header.Set("Content-Type", contentType)
@@ -98,12 +128,18 @@ func Invoke(provider provider.Provider, ireq InvokeRequest) (*http.Response, err
 	if len(env) > 0 {
 		EnvAsHeader(req, env)
 	}
+	for name, values := range ireq.Headers {
+		req.Header.Del(name)
+		for _, v := range values {
+			req.Header.Add(name, v)
+		}
+	}
 
 	transport := provider.WrapCallTransport(http.DefaultTransport)
 	httpClient := http.Client{Transport: transport}
 
 	if logger.DebugEnabled() {
-		b, err := httputil.DumpRequestOut(req, content != nil)
+		b, err := httputil.DumpRequestOut(req, content != nil && !ireq.Stream)
 		if err != nil {
 			fmt.Fprintln(os.Stderr, "error dumping req", err)
 		}
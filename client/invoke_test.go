@@ -89,4 +89,91 @@ func TestInvokeSetsIsDryRunHeader(t *testing.T) {
 	}
 	defer resp.Body.Close()
 	_, _ = io.ReadAll(resp.Body)
-}
\ No newline at end of file
+}
+func TestInvokeSendsMethodHeadersAndEnv(t *testing.T) {
+	t.Setenv("FN_INVOKE_TEST_ENV", "from-environment")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPut {
+			t.Fatalf("expected PUT, got %s", r.Method)
+		}
+		if got := r.Header.Values("X-Tenant"); len(got) != 2 || got[0] != "a" || got[1] != "b" {
+			t.Fatalf("expected both X-Tenant headers, got %q", got)
+		}
+		if got := r.Header.Get("Content-Type"); got != "application/cloudevents+json" {
+			t.Fatalf("expected the Content-Type header to replace --content-type, got %q", got)
+		}
+		if got := r.Header.Get("FN_INVOKE_TEST_ENV"); got != "from-environment" {
+			t.Fatalf("expected the env variable value, got %q", got)
+		}
+		if got := r.Header.Get("STAGE"); got != "test" {
+			t.Fatalf("expected the given env value, got %q", got)
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	resp, err := Invoke(&invokeTestProvider{}, InvokeRequest{
+		URL:         server.URL,
+		Method:      "put",
+		ContentType: "application/json",
+		Env:         []string{"FN_INVOKE_TEST_ENV", "STAGE=test"},
+		Headers: http.Header{
+			"X-Tenant":     {"a", "b"},
+			"Content-Type": {"application/cloudevents+json"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Invoke() error = %v", err)
+	}
+	resp.Body.Close()
+}
+
+func TestInvokeRefusesOversizedBody(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Fatal("unexpected request with a truncated body")
+	}))
+	defer server.Close()
+
+	_, err := Invoke(&invokeTestProvider{}, InvokeRequest{
+		URL:     server.URL,
+		Content: io.LimitReader(zeros{}, MaximumRequestBodySize+1),
+	})
+	if err != ErrRequestBodyTooLarge {
+		t.Fatalf("expected ErrRequestBodyTooLarge, got %v", err)
+	}
+}
+
+func TestInvokeStreamsBody(t *testing.T) {
+	size := int64(MaximumRequestBodySize + 1024)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.ContentLength != size {
+			t.Fatalf("expected Content-Length %d, got %d", size, r.ContentLength)
+		}
+		n, _ := io.Copy(io.Discard, r.Body)
+		if n != size {
+			t.Fatalf("expected the whole body, got %d bytes", n)
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	resp, err := Invoke(&invokeTestProvider{}, InvokeRequest{
+		URL:           server.URL,
+		Content:       io.LimitReader(zeros{}, size),
+		Stream:        true,
+		ContentLength: size,
+	})
+	if err != nil {
+		t.Fatalf("Invoke() error = %v", err)
+	}
+	resp.Body.Close()
+}
+
+type zeros struct{}
+
+func (zeros) Read(b []byte) (int, error) {
+	for i := range b {
+		b[i] = 0
+	}
+	return len(b), nil
+}
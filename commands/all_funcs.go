@@ -0,0 +1,102 @@
+/*
+ * Copyright (c) 2019, 2020 Oracle and/or its affiliates. All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package commands
+
+import (
+	"fmt"
+	"io"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	common "github.com/fnproject/cli/common"
+)
+
+// allFuncsResult is the outcome of building or deploying a single function with --all
+type allFuncsResult struct {
+	name     string
+	image    string
+	duration time.Duration
+	skipped  bool
+	err      error
+}
+
+// runAllFuncsParallel runs up to parallel functions at a time. The output of each function is prefixed
+// with its name, and the results are returned in the order of funcs. With jsonOut, out carries JSON lines
+// that name their function, so its lines are kept whole but not prefixed.
+func runAllFuncsParallel(funcs []deployAllFunc, parallel int, jsonOut bool, out, errOut io.Writer, run func(f deployAllFunc, out, errOut io.Writer) allFuncsResult) []allFuncsResult {
+	width := 0
+	for _, f := range funcs {
+		if len(f.ff.Name) > width {
+			width = len(f.ff.Name)
+		}
+	}
+
+	results := make([]allFuncsResult, len(funcs))
+	sem := make(chan struct{}, parallel)
+	var wg sync.WaitGroup
+	for i, f := range funcs {
+		wg.Add(1)
+		go func(i int, f deployAllFunc) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			prefix := fmt.Sprintf("[%-*s] ", width, f.ff.Name)
+			outPrefix := prefix
+			if jsonOut {
+				outPrefix = ""
+			}
+			fOut := common.NewPrefixWriter(out, outPrefix)
+			fErrOut := common.NewPrefixWriter(errOut, prefix)
+			start := time.Now()
+			results[i] = run(f, fOut, fErrOut)
+			results[i].duration = time.Since(start)
+			fOut.Close()
+			fErrOut.Close()
+		}(i, f)
+	}
+	wg.Wait()
+	return results
+}
+
+// printAllFuncsSummary prints a table of the functions that were built or deployed, action being build or
+// deploy and done its past tense, and returns an error if any of them failed
+func printAllFuncsSummary(out io.Writer, results []allFuncsResult, action, done string) error {
+	failed := 0
+	fmt.Fprintln(out)
+	w := tabwriter.NewWriter(out, 0, 8, 1, '\t', 0)
+	fmt.Fprint(w, "FUNCTION", "\t", "STATUS", "\t", "DURATION", "\t", "DETAILS", "\n")
+	for _, r := range results {
+		status, details := done, r.image
+		if r.skipped {
+			status, details = "skipped", "unchanged since last "+action
+		}
+		if r.err != nil {
+			failed++
+			status, details = "failed", r.err.Error()
+		}
+		fmt.Fprint(w, r.name, "\t", status, "\t", r.duration.Round(time.Second), "\t", details, "\n")
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d functions failed to %s", failed, len(results), action)
+	}
+	return nil
+}
@@ -0,0 +1,57 @@
+package commands
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/fnproject/cli/common"
+)
+
+func TestRunAllFuncsParallel(t *testing.T) {
+	var funcs []deployAllFunc
+	for _, name := range []string{"a", "bb", "c"} {
+		funcs = append(funcs, deployAllFunc{path: name + "/func.yaml", ff: &common.FuncFileV20180708{Name: name}})
+	}
+	var out, errOut bytes.Buffer
+	results := runAllFuncsParallel(funcs, 2, false, &out, &errOut, func(f deployAllFunc, out, errOut io.Writer) allFuncsResult {
+		fmt.Fprintf(out, "building %s\n", f.ff.Name)
+		return allFuncsResult{name: f.ff.Name, skipped: f.ff.Name == "c"}
+	})
+	if len(results) != 3 || results[0].name != "a" || results[1].name != "bb" || !results[2].skipped {
+		t.Fatalf("expected the results in function order, got %+v", results)
+	}
+	for _, line := range []string{"[a ] building a", "[bb] building bb", "[c ] building c"} {
+		if !strings.Contains(out.String(), line+"\n") {
+			t.Fatalf("expected %q in the output, got:\n%s", line, out.String())
+		}
+	}
+}
+
+func TestPrintAllFuncsSummarySkipped(t *testing.T) {
+	var out bytes.Buffer
+	if err := printAllFuncsSummary(&out, []allFuncsResult{{name: "hello", skipped: true}}, "deploy", "deployed"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(out.String(), "skipped") || !strings.Contains(out.String(), "unchanged since last deploy") {
+		t.Fatalf("unexpected summary:\n%s", out.String())
+	}
+}
+
+func TestRunAllFuncsParallelJSONOut(t *testing.T) {
+	funcs := []deployAllFunc{{path: "a/func.yaml", ff: &common.FuncFileV20180708{Name: "a"}}}
+	var out, errOut bytes.Buffer
+	runAllFuncsParallel(funcs, 2, true, &out, &errOut, func(f deployAllFunc, out, errOut io.Writer) allFuncsResult {
+		fmt.Fprint(out, `{"function":"a"}`+"\n")
+		fmt.Fprint(errOut, "building a\n")
+		return allFuncsResult{name: f.ff.Name}
+	})
+	if out.String() != `{"function":"a"}`+"\n" {
+		t.Fatalf("expected the JSON lines unprefixed, got %q", out.String())
+	}
+	if errOut.String() != "[a] building a\n" {
+		t.Fatalf("expected stderr prefixed, got %q", errOut.String())
+	}
+}
@@ -0,0 +1,728 @@
+/*
+ * Copyright (c) 2019, 2020 Oracle and/or its affiliates. All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package commands
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	client "github.com/fnproject/cli/client"
+	common "github.com/fnproject/cli/common"
+	apps "github.com/fnproject/cli/objects/app"
+	function "github.com/fnproject/cli/objects/fn"
+	trigger "github.com/fnproject/cli/objects/trigger"
+	v2Client "github.com/fnproject/fn_go/clientv2"
+	models "github.com/fnproject/fn_go/modelsv2"
+	fnprovider "github.com/fnproject/fn_go/provider"
+	"github.com/urfave/cli"
+)
+
+const (
+	applyCreate = "create"
+	applyUpdate = "update"
+	applyDelete = "delete"
+	// applyReplace deletes and recreates a trigger, for changes the server can't make in place
+	applyReplace = "replace"
+
+	applyKindApp      = "app"
+	applyKindFunction = "function"
+	applyKindTrigger  = "trigger"
+
+	applyUnsetValue = "<unset>"
+)
+
+// ApplyCommand returns apply cli.command
+func ApplyCommand() cli.Command {
+	cmd := applycmd{}
+	return cli.Command{
+		Name:  "apply",
+		Usage: "\tMake the functions server match the app.yaml and func.yaml files of an application",
+		Before: func(cxt *cli.Context) error {
+			provider, err := client.CurrentProvider()
+			if err != nil {
+				return err
+			}
+			cmd.provider = provider
+			cmd.clientV2 = provider.APIClientv2()
+			return nil
+		},
+		Category: "DEVELOPMENT COMMANDS",
+		Description: "This command compares the app declared in `app.yaml` and every function below it with the functions server, " +
+			"prints a plan of the apps, functions and triggers that will be created, updated or deleted and applies it. " +
+			"Images are not built or pushed, each function uses the image and version recorded in its func.yaml. " +
+			"Functions and triggers that are not declared locally are only deleted with --prune.",
+		ArgsUsage: "[app-directory]",
+		Flags:     cmd.flags(),
+		Action:    cmd.apply,
+	}
+}
+
+type applycmd struct {
+	clientV2 *v2Client.Fn
+	provider fnprovider.Provider
+
+	appName string
+	prune   bool
+	dryRun  bool
+	force   bool
+}
+
+func (a *applycmd) flags() []cli.Flag {
+	return []cli.Flag{
+		cli.StringFlag{
+			Name:        "app",
+			Usage:       "App name to apply to, overrides the name in app.yaml",
+			Destination: &a.appName,
+		},
+		cli.BoolFlag{
+			Name:        "prune",
+			Usage:       "Delete functions and triggers that are not declared locally",
+			Destination: &a.prune,
+		},
+		cli.BoolFlag{
+			Name:        "dry-run",
+			Usage:       "Only print the plan, do not change anything on the functions server",
+			Destination: &a.dryRun,
+		},
+		cli.BoolFlag{
+			Name:        "force, f",
+			Usage:       "Do not ask for confirmation before deleting resources",
+			Destination: &a.force,
+		},
+		cli.StringFlag{
+			Name:  "working-dir,w",
+			Usage: "Specify the working directory of the app, must be the full path.",
+		},
+	}
+}
+
+// applyFn is a function and its triggers
+type applyFn struct {
+	fn       *models.Fn
+	triggers []*models.Trigger
+}
+
+// applyState is an app with its functions, either declared locally or deployed
+type applyState struct {
+	app *models.App
+	fns map[string]*applyFn
+}
+
+// fieldChange is the change of a single field of a resource
+type fieldChange struct {
+	field string
+	from  string
+	to    string
+}
+
+// applyChange is a single create, update or delete of a resource
+type applyChange struct {
+	action string
+	kind   string
+	name   string
+	fields []fieldChange
+
+	app     *models.App
+	fn      *models.Fn
+	trigger *models.Trigger
+	fnName  string
+}
+
+// applyPlan is the ordered list of changes that makes the server match the local state
+type applyPlan struct {
+	changes []applyChange
+	// unmanaged lists remote resources that are not declared locally and are kept because --prune was not set
+	unmanaged []string
+}
+
+func (a *applycmd) apply(c *cli.Context) error {
+	dir := common.GetDir(c)
+	if path := c.Args().First(); path != "" {
+		dir = filepath.Join(dir, path)
+	}
+
+	local, err := loadLocalApplyState(dir, a.appName)
+	if err != nil {
+		return err
+	}
+	remote, err := loadRemoteApplyState(a.clientV2, local.app.Name)
+	if err != nil {
+		return err
+	}
+
+	plan := computeApplyPlan(local, remote, a.prune)
+	plan.print(os.Stdout)
+	if a.dryRun || len(plan.changes) == 0 {
+		return nil
+	}
+
+	if !a.force {
+		fns, triggers := plan.deletions()
+		if (len(fns) > 0 || len(triggers) > 0) && !common.UserConfirmedMultiResourceDeletion(nil, fns, triggers) {
+			return errors.New("deletion not confirmed, nothing was applied")
+		}
+	}
+
+	err = a.execute(c, plan, remote)
+	if cacheErr := common.InvalidateInvokeEndpointCacheForApp(a.provider, local.app.Name); cacheErr != nil {
+		fmt.Fprintf(os.Stderr, "Warning: unable to invalidate invoke endpoint cache: %v\n", cacheErr)
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Println("Apply complete.")
+	return nil
+}
+
+// loadLocalApplyState reads the app file in dir and every func file below it
+func loadLocalApplyState(dir, appName string) (*applyState, error) {
+	appf, err := common.LoadAppfile(dir)
+	if err != nil {
+		return nil, err
+	}
+	if appName == "" {
+		appName = appf.Name
+	}
+	if appName == "" {
+		return nil, errors.New("App name must be provided, try `--app APP_NAME`")
+	}
+
+	state := &applyState{
+		app: &models.App{
+			Name:        appName,
+			Config:      appf.Config,
+			Annotations: appf.Annotations,
+		},
+		fns: map[string]*applyFn{},
+	}
+	if appf.SyslogURL != "" {
+		state.app.SyslogURL = &appf.SyslogURL
+	}
+
+	err = common.WalkFuncsV20180708(dir, func(path string, ff *common.FuncFileV20180708, err error) error {
+		if err != nil {
+			return fmt.Errorf("%s: %v", path, err)
+		}
+		name := applyFuncName(dir, path, ff)
+		if _, ok := state.fns[name]; ok {
+			return fmt.Errorf("function %s is declared more than once, second declaration in %s", name, path)
+		}
+		ff.Name = name
+
+		isPBF := ff.Deploy != nil && ff.Deploy.OCI != nil && ff.Deploy.OCI.PBF != nil && strings.TrimSpace(ff.Deploy.OCI.PBF.ListingID) != ""
+		if ff.Version == "" && !isPBF {
+			return fmt.Errorf("function %s in %s has no version, build and push its image with `fn deploy` or `fn push` first", name, path)
+		}
+
+		fn := &models.Fn{Name: name}
+		if err := function.WithFuncFileV20180708(ff, fn); err != nil {
+			return fmt.Errorf("%s: %v", path, err)
+		}
+		local := &applyFn{fn: fn}
+		for _, t := range ff.Triggers {
+			local.triggers = append(local.triggers, &models.Trigger{
+				Name:   t.Name,
+				Type:   t.Type,
+				Source: t.Source,
+			})
+		}
+		state.fns[name] = local
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return state, nil
+}
+
+// applyFuncName returns the function name from its func file, or like `deploy --all`
+// derives it from the path of the function relative to the app directory
+func applyFuncName(root, funcfilePath string, ff *common.FuncFileV20180708) string {
+	if ff.Name != "" {
+		return ff.Name
+	}
+	dir := filepath.Dir(funcfilePath)
+	rel, err := filepath.Rel(root, dir)
+	if err != nil || rel == "." {
+		return filepath.Base(dir)
+	}
+	return strings.Replace(filepath.ToSlash(rel), "/", "-", -1)
+}
+
+// loadRemoteApplyState fetches the app with all its functions and triggers, the app is nil if it doesn't exist
+func loadRemoteApplyState(client *v2Client.Fn, appName string) (*applyState, error) {
+	state := &applyState{fns: map[string]*applyFn{}}
+
+	app, err := apps.GetAppByName(client, appName)
+	if _, ok := err.(apps.NameNotFoundError); ok {
+		return state, nil
+	} else if err != nil {
+		return nil, err
+	}
+	state.app = app
+
+	fns, err := common.ListAllFnsInApp(client, app)
+	if err != nil {
+		return nil, err
+	}
+	for _, fn := range fns {
+		triggers, err := common.ListAllTriggersInFunc(client, fn)
+		if err != nil {
+			return nil, err
+		}
+		state.fns[fn.Name] = &applyFn{fn: fn, triggers: triggers}
+	}
+	return state, nil
+}
+
+// computeApplyPlan returns the changes that make remote match local. Creates and updates
+// come first, in app, function, trigger order, followed by deletes of triggers and then functions.
+func computeApplyPlan(local, remote *applyState, prune bool) *applyPlan {
+	plan := &applyPlan{}
+	var deletes []applyChange
+
+	if remote.app == nil {
+		plan.changes = append(plan.changes, applyChange{
+			action: applyCreate, kind: applyKindApp, name: local.app.Name,
+			fields: diffApp(local.app, &models.App{}),
+			app:    local.app,
+		})
+	} else if fields := diffApp(local.app, remote.app); len(fields) > 0 {
+		desired := *local.app
+		desired.Config = withRemovedConfig(local.app.Config, remote.app.Config)
+		plan.changes = append(plan.changes, applyChange{
+			action: applyUpdate, kind: applyKindApp, name: local.app.Name,
+			fields: fields,
+			app:    &desired,
+		})
+	}
+
+	for _, name := range sortedApplyFnNames(local.fns) {
+		lf := local.fns[name]
+		rf, exists := remote.fns[name]
+		if !exists {
+			plan.changes = append(plan.changes, applyChange{
+				action: applyCreate, kind: applyKindFunction, name: name,
+				fields: diffFn(lf.fn, &models.Fn{}),
+				fn:     lf.fn,
+			})
+			for _, t := range lf.triggers {
+				plan.changes = append(plan.changes, applyChange{
+					action: applyCreate, kind: applyKindTrigger, name: name + "/" + t.Name,
+					fields:  diffTrigger(t, &models.Trigger{}),
+					trigger: t, fnName: name,
+				})
+			}
+			continue
+		}
+
+		if fields := diffFn(lf.fn, rf.fn); len(fields) > 0 {
+			desired := *lf.fn
+			desired.ID = rf.fn.ID
+			desired.Config = withRemovedConfig(lf.fn.Config, rf.fn.Config)
+			plan.changes = append(plan.changes, applyChange{
+				action: applyUpdate, kind: applyKindFunction, name: name,
+				fields: fields,
+				fn:     &desired,
+			})
+		}
+
+		remoteTriggers := map[string]*models.Trigger{}
+		for _, t := range rf.triggers {
+			remoteTriggers[t.Name] = t
+		}
+		localTriggers := map[string]bool{}
+		for _, t := range lf.triggers {
+			localTriggers[t.Name] = true
+			rt, exists := remoteTriggers[t.Name]
+			if !exists {
+				plan.changes = append(plan.changes, applyChange{
+					action: applyCreate, kind: applyKindTrigger, name: name + "/" + t.Name,
+					fields:  diffTrigger(t, &models.Trigger{}),
+					trigger: t, fnName: name,
+				})
+				continue
+			}
+			if fields := diffTrigger(t, rt); len(fields) > 0 {
+				desired := *t
+				desired.ID = rt.ID
+				action := applyUpdate
+				if t.Type != rt.Type {
+					// the type of a trigger can't be updated
+					action = applyReplace
+				}
+				plan.changes = append(plan.changes, applyChange{
+					action: action, kind: applyKindTrigger, name: name + "/" + t.Name,
+					fields:  fields,
+					trigger: &desired, fnName: name,
+				})
+			}
+		}
+		for _, t := range rf.triggers {
+			if localTriggers[t.Name] {
+				continue
+			}
+			if !prune {
+				plan.unmanaged = append(plan.unmanaged, applyKindTrigger+" "+name+"/"+t.Name)
+				continue
+			}
+			deletes = append(deletes, applyChange{
+				action: applyDelete, kind: applyKindTrigger, name: name + "/" + t.Name,
+				trigger: t, fnName: name,
+			})
+		}
+	}
+
+	var fnDeletes []applyChange
+	for _, name := range sortedApplyFnNames(remote.fns) {
+		if _, ok := local.fns[name]; ok {
+			continue
+		}
+		if !prune {
+			plan.unmanaged = append(plan.unmanaged, applyKindFunction+" "+name)
+			continue
+		}
+		rf := remote.fns[name]
+		for _, t := range rf.triggers {
+			deletes = append(deletes, applyChange{
+				action: applyDelete, kind: applyKindTrigger, name: name + "/" + t.Name,
+				trigger: t, fnName: name,
+			})
+		}
+		fnDeletes = append(fnDeletes, applyChange{
+			action: applyDelete, kind: applyKindFunction, name: name,
+			fn: rf.fn,
+		})
+	}
+
+	plan.changes = append(plan.changes, deletes...)
+	plan.changes = append(plan.changes, fnDeletes...)
+	return plan
+}
+
+func sortedApplyFnNames(fns map[string]*applyFn) []string {
+	names := make([]string, 0, len(fns))
+	for name := range fns {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// withRemovedConfig returns the local config with an empty value for every key that only
+// exists remotely, which removes the key when the resource is updated
+func withRemovedConfig(local, remote map[string]string) map[string]string {
+	config := map[string]string{}
+	for k, v := range local {
+		config[k] = v
+	}
+	for k := range remote {
+		if _, ok := local[k]; !ok {
+			config[k] = ""
+		}
+	}
+	if len(config) == 0 {
+		return nil
+	}
+	return config
+}
+
+// diffApp compares the fields of an app that can be declared in app.yaml
+func diffApp(local, remote *models.App) []fieldChange {
+	var fields []fieldChange
+	if local.SyslogURL != nil && (remote.SyslogURL == nil || *local.SyslogURL != *remote.SyslogURL) {
+		from := applyUnsetValue
+		if remote.SyslogURL != nil {
+			from = formatApplyValue(*remote.SyslogURL)
+		}
+		fields = append(fields, fieldChange{field: "syslog_url", from: from, to: formatApplyValue(*local.SyslogURL)})
+	}
+	fields = append(fields, diffConfig("config", local.Config, remote.Config)...)
+	fields = append(fields, diffAnnotations("annotations", local.Annotations, remote.Annotations)...)
+	return fields
+}
+
+// diffFn compares the fields of a function that can be declared in func.yaml, fields that
+// are not set locally are left to the server and not compared
+func diffFn(local, remote *models.Fn) []fieldChange {
+	var fields []fieldChange
+	if local.Image != "" && local.Image != remote.Image {
+		fields = append(fields, fieldChange{field: "image", from: formatApplyString(remote.Image), to: formatApplyValue(local.Image)})
+	}
+	if local.Memory != 0 && local.Memory != remote.Memory {
+		fields = append(fields, fieldChange{field: "memory", from: formatApplyUint(remote.Memory), to: formatApplyValue(local.Memory)})
+	}
+	if c, ok := diffInt32Ptr("timeout", local.Timeout, remote.Timeout); ok {
+		fields = append(fields, c)
+	}
+	if c, ok := diffInt32Ptr("idle_timeout", local.IdleTimeout, remote.IdleTimeout); ok {
+		fields = append(fields, c)
+	}
+	fields = append(fields, diffConfig("config", local.Config, remote.Config)...)
+	fields = append(fields, diffAnnotations("annotations", local.Annotations, remote.Annotations)...)
+	return fields
+}
+
+// diffTrigger compares the fields of a trigger that can be declared in func.yaml
+func diffTrigger(local, remote *models.Trigger) []fieldChange {
+	var fields []fieldChange
+	if local.Type != remote.Type {
+		fields = append(fields, fieldChange{field: "type", from: formatApplyString(remote.Type), to: formatApplyValue(local.Type)})
+	}
+	if local.Source != remote.Source {
+		fields = append(fields, fieldChange{field: "source", from: formatApplyString(remote.Source), to: formatApplyValue(local.Source)})
+	}
+	fields = append(fields, diffAnnotations("annotations", local.Annotations, remote.Annotations)...)
+	return fields
+}
+
+func diffInt32Ptr(field string, local, remote *int32) (fieldChange, bool) {
+	if local == nil || (remote != nil && *local == *remote) {
+		return fieldChange{}, false
+	}
+	from := applyUnsetValue
+	if remote != nil {
+		from = formatApplyValue(*remote)
+	}
+	return fieldChange{field: field, from: from, to: formatApplyValue(*local)}, true
+}
+
+// diffConfig compares every config key, keys that only exist remotely are removed
+func diffConfig(field string, local, remote map[string]string) []fieldChange {
+	keys := map[string]bool{}
+	for k := range local {
+		keys[k] = true
+	}
+	for k := range remote {
+		keys[k] = true
+	}
+
+	var fields []fieldChange
+	for _, k := range sortedKeys(keys) {
+		lv, lok := local[k]
+		rv, rok := remote[k]
+		if lok && rok && lv == rv {
+			continue
+		}
+		c := fieldChange{field: field + "." + k, from: applyUnsetValue, to: applyUnsetValue}
+		if rok {
+			c.from = formatApplyValue(rv)
+		}
+		if lok {
+			c.to = formatApplyValue(lv)
+		}
+		fields = append(fields, c)
+	}
+	return fields
+}
+
+// diffAnnotations only compares annotations declared locally, as the server adds its own annotations
+func diffAnnotations(field string, local, remote map[string]interface{}) []fieldChange {
+	keys := map[string]bool{}
+	for k := range local {
+		keys[k] = true
+	}
+
+	var fields []fieldChange
+	for _, k := range sortedKeys(keys) {
+		lv := formatApplyValue(local[k])
+		rv, ok := remote[k]
+		if ok && formatApplyValue(rv) == lv {
+			continue
+		}
+		c := fieldChange{field: field + "." + k, from: applyUnsetValue, to: lv}
+		if ok {
+			c.from = formatApplyValue(rv)
+		}
+		fields = append(fields, c)
+	}
+	return fields
+}
+
+func sortedKeys(keys map[string]bool) []string {
+	sorted := make([]string, 0, len(keys))
+	for k := range keys {
+		sorted = append(sorted, k)
+	}
+	sort.Strings(sorted)
+	return sorted
+}
+
+func formatApplyValue(v interface{}) string {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprintf("%v", v)
+	}
+	return string(b)
+}
+
+func formatApplyString(s string) string {
+	if s == "" {
+		return applyUnsetValue
+	}
+	return formatApplyValue(s)
+}
+
+func formatApplyUint(u uint64) string {
+	if u == 0 {
+		return applyUnsetValue
+	}
+	return formatApplyValue(u)
+}
+
+// deletions returns the functions and triggers the plan deletes
+func (p *applyPlan) deletions() ([]*models.Fn, []*models.Trigger) {
+	var fns []*models.Fn
+	var triggers []*models.Trigger
+	for _, ch := range p.changes {
+		if ch.action != applyDelete && ch.action != applyReplace {
+			continue
+		}
+		switch ch.kind {
+		case applyKindFunction:
+			fns = append(fns, ch.fn)
+		case applyKindTrigger:
+			triggers = append(triggers, ch.trigger)
+		}
+	}
+	return fns, triggers
+}
+
+func (p *applyPlan) print(out io.Writer) {
+	if len(p.changes) == 0 {
+		fmt.Fprintln(out, "No changes. The functions server matches the local configuration.")
+	}
+
+	counts := map[string]int{}
+	for _, ch := range p.changes {
+		counts[ch.action]++
+		switch ch.action {
+		case applyCreate:
+			fmt.Fprintf(out, "  + %s %s\n", ch.kind, ch.name)
+			for _, f := range ch.fields {
+				fmt.Fprintf(out, "      %s = %s\n", f.field, f.to)
+			}
+		case applyUpdate:
+			fmt.Fprintf(out, "  ~ %s %s\n", ch.kind, ch.name)
+			for _, f := range ch.fields {
+				fmt.Fprintf(out, "      %s: %s -> %s\n", f.field, f.from, f.to)
+			}
+		case applyDelete:
+			fmt.Fprintf(out, "  - %s %s\n", ch.kind, ch.name)
+		case applyReplace:
+			fmt.Fprintf(out, "-/+ %s %s (deleted and recreated)\n", ch.kind, ch.name)
+			for _, f := range ch.fields {
+				fmt.Fprintf(out, "      %s: %s -> %s\n", f.field, f.from, f.to)
+			}
+		}
+	}
+	if len(p.changes) > 0 {
+		fmt.Fprintf(out, "Plan: %d to create, %d to update, %d to replace, %d to delete.\n", counts[applyCreate], counts[applyUpdate], counts[applyReplace], counts[applyDelete])
+	}
+
+	if len(p.unmanaged) > 0 {
+		fmt.Fprintf(out, "%d remote resources are not declared locally and will be kept, use --prune to delete them:\n", len(p.unmanaged))
+		for _, r := range p.unmanaged {
+			fmt.Fprintf(out, "    %s\n", r)
+		}
+	}
+}
+
+// execute applies the plan in order, stopping at the first failure
+func (a *applycmd) execute(c *cli.Context, plan *applyPlan, remote *applyState) error {
+	var appID string
+	if remote.app != nil {
+		appID = remote.app.ID
+	}
+	fnIDs := map[string]string{}
+	for name, rf := range remote.fns {
+		fnIDs[name] = rf.fn.ID
+	}
+	// images are checked before anything changes, so a refused image doesn't leave the app half applied
+	for _, ch := range plan.changes {
+		if ch.kind != applyKindFunction || (ch.action != applyCreate && ch.action != applyUpdate) {
+			continue
+		}
+		image, err := common.EnforceContextSignature(ch.fn.Image)
+		if err != nil {
+			return fmt.Errorf("function %s: %v", ch.name, err)
+		}
+		ch.fn.Image = image
+	}
+
+	for _, ch := range plan.changes {
+		var err error
+		switch ch.kind + "/" + ch.action {
+		case applyKindApp + "/" + applyCreate:
+			var created *models.App
+			created, err = apps.CreateApp(a.clientV2, ch.app)
+			if err == nil {
+				appID = created.ID
+			}
+		case applyKindApp + "/" + applyUpdate:
+			_, err = apps.PutApp(a.clientV2, appID, ch.app)
+			if err == nil {
+				fmt.Println("App", ch.name, "updated")
+			}
+		case applyKindFunction + "/" + applyCreate:
+			var created *models.Fn
+			created, err = function.CreateFn(a.clientV2, appID, ch.fn)
+			if err == nil {
+				fnIDs[ch.name] = created.ID
+			}
+		case applyKindFunction + "/" + applyUpdate:
+			err = function.PutFn(a.clientV2, ch.fn.ID, ch.fn)
+			if err == nil {
+				fmt.Println("Function", ch.name, "updated")
+			}
+		case applyKindFunction + "/" + applyDelete:
+			err = common.DeleteFunctions(c, a.clientV2, []*models.Fn{ch.fn})
+		case applyKindTrigger + "/" + applyCreate:
+			ch.trigger.AppID = appID
+			ch.trigger.FnID = fnIDs[ch.fnName]
+			err = trigger.CreateTrigger(a.clientV2, ch.trigger)
+		case applyKindTrigger + "/" + applyUpdate:
+			ch.trigger.AppID = appID
+			ch.trigger.FnID = fnIDs[ch.fnName]
+			err = trigger.PutTrigger(a.clientV2, ch.trigger)
+			if err == nil {
+				fmt.Println("Trigger", ch.name, "updated")
+			}
+		case applyKindTrigger + "/" + applyReplace:
+			ch.trigger.AppID = appID
+			ch.trigger.FnID = fnIDs[ch.fnName]
+			err = common.DeleteTriggers(c, a.clientV2, []*models.Trigger{ch.trigger})
+			if err == nil {
+				replacement := *ch.trigger
+				replacement.ID = ""
+				err = trigger.CreateTrigger(a.clientV2, &replacement)
+			}
+		case applyKindTrigger + "/" + applyDelete:
+			err = common.DeleteTriggers(c, a.clientV2, []*models.Trigger{ch.trigger})
+		}
+		if err != nil {
+			return fmt.Errorf("failed to %s %s %s: %v", ch.action, ch.kind, ch.name, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,212 @@
+package commands
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	models "github.com/fnproject/fn_go/modelsv2"
+)
+
+func int32Ptr(i int32) *int32 { return &i }
+
+func applyActions(plan *applyPlan) []string {
+	var actions []string
+	for _, ch := range plan.changes {
+		actions = append(actions, ch.action+" "+ch.kind+" "+ch.name)
+	}
+	return actions
+}
+
+func TestComputeApplyPlanCreatesMissingResources(t *testing.T) {
+	local := &applyState{
+		app: &models.App{Name: "myapp"},
+		fns: map[string]*applyFn{
+			"hello": {
+				fn:       &models.Fn{Name: "hello", Image: "hello:0.0.1"},
+				triggers: []*models.Trigger{{Name: "hello", Type: "http", Source: "/hello"}},
+			},
+		},
+	}
+	remote := &applyState{fns: map[string]*applyFn{}}
+
+	plan := computeApplyPlan(local, remote, false)
+	got := strings.Join(applyActions(plan), ", ")
+	expected := "create app myapp, create function hello, create trigger hello/hello"
+	if got != expected {
+		t.Fatalf("expected plan %q, got %q", expected, got)
+	}
+}
+
+func TestComputeApplyPlanUpdatesChangedFields(t *testing.T) {
+	local := &applyState{
+		app: &models.App{Name: "myapp", Config: map[string]string{"A": "1"}},
+		fns: map[string]*applyFn{
+			"hello": {
+				fn: &models.Fn{Name: "hello", Image: "hello:0.0.2", Memory: 256, Timeout: int32Ptr(30),
+					Config: map[string]string{"KEEP": "x"}},
+				triggers: []*models.Trigger{{Name: "hello", Type: "http", Source: "/hello2"}},
+			},
+		},
+	}
+	remote := &applyState{
+		app: &models.App{ID: "app1", Name: "myapp", Config: map[string]string{"A": "1"}},
+		fns: map[string]*applyFn{
+			"hello": {
+				fn: &models.Fn{ID: "fn1", Name: "hello", Image: "hello:0.0.1", Memory: 256, Timeout: int32Ptr(30),
+					Config:      map[string]string{"KEEP": "x", "OLD": "y"},
+					Annotations: map[string]interface{}{"fnproject.io/fn/invokeEndpoint": "http://example.com"}},
+				triggers: []*models.Trigger{{ID: "t1", Name: "hello", Type: "http", Source: "/hello"}},
+			},
+		},
+	}
+
+	plan := computeApplyPlan(local, remote, false)
+	got := strings.Join(applyActions(plan), ", ")
+	expected := "update function hello, update trigger hello/hello"
+	if got != expected {
+		t.Fatalf("expected plan %q, got %q", expected, got)
+	}
+
+	fnChange := plan.changes[0]
+	var fields []string
+	for _, f := range fnChange.fields {
+		fields = append(fields, f.field)
+	}
+	if strings.Join(fields, ",") != "image,config.OLD" {
+		t.Fatalf("expected image and config.OLD to change, got %v", fields)
+	}
+	if fnChange.fn.ID != "fn1" {
+		t.Fatalf("expected update to target remote function ID, got %q", fnChange.fn.ID)
+	}
+	if v, ok := fnChange.fn.Config["OLD"]; !ok || v != "" {
+		t.Fatalf("expected removed config key to be sent empty, got %v", fnChange.fn.Config)
+	}
+	if plan.changes[1].trigger.ID != "t1" {
+		t.Fatalf("expected trigger update to target remote trigger ID, got %q", plan.changes[1].trigger.ID)
+	}
+}
+
+func TestComputeApplyPlanPrune(t *testing.T) {
+	local := &applyState{
+		app: &models.App{Name: "myapp"},
+		fns: map[string]*applyFn{
+			"hello": {fn: &models.Fn{Name: "hello", Image: "hello:0.0.1"}},
+		},
+	}
+	remote := &applyState{
+		app: &models.App{ID: "app1", Name: "myapp"},
+		fns: map[string]*applyFn{
+			"hello": {
+				fn:       &models.Fn{ID: "fn1", Name: "hello", Image: "hello:0.0.1"},
+				triggers: []*models.Trigger{{ID: "t1", Name: "stale", Type: "http", Source: "/stale"}},
+			},
+			"old": {
+				fn:       &models.Fn{ID: "fn2", Name: "old", Image: "old:0.0.1"},
+				triggers: []*models.Trigger{{ID: "t2", Name: "old", Type: "http", Source: "/old"}},
+			},
+		},
+	}
+
+	plan := computeApplyPlan(local, remote, false)
+	if len(plan.changes) != 0 {
+		t.Fatalf("expected no changes without --prune, got %v", applyActions(plan))
+	}
+	if strings.Join(plan.unmanaged, ", ") != "trigger hello/stale, function old" {
+		t.Fatalf("expected unmanaged resources to be reported, got %v", plan.unmanaged)
+	}
+
+	plan = computeApplyPlan(local, remote, true)
+	got := strings.Join(applyActions(plan), ", ")
+	expected := "delete trigger hello/stale, delete trigger old/old, delete function old"
+	if got != expected {
+		t.Fatalf("expected plan %q, got %q", expected, got)
+	}
+	fns, triggers := plan.deletions()
+	if len(fns) != 1 || len(triggers) != 2 {
+		t.Fatalf("expected 1 function and 2 triggers to be deleted, got %d and %d", len(fns), len(triggers))
+	}
+}
+
+func TestApplyPlanPrintNoChanges(t *testing.T) {
+	var out bytes.Buffer
+	(&applyPlan{}).print(&out)
+	if !strings.Contains(out.String(), "No changes") {
+		t.Fatalf("expected no changes message, got %q", out.String())
+	}
+}
+
+func TestLoadLocalApplyState(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fn-apply")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	files := map[string]string{
+		"app.yaml": "name: myapp\nconfig:\n  A: \"1\"\n",
+		"hello/func.yaml": "schema_version: 20180708\nname: hello\nversion: 0.0.1\nruntime: go\n" +
+			"triggers:\n- name: hello\n  type: http\n  source: /hello\n",
+		"sub/world/func.yaml": "schema_version: 20180708\nversion: 0.0.3\nruntime: go\nmemory: 256\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	state, err := loadLocalApplyState(dir, "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if state.app.Name != "myapp" || state.app.Config["A"] != "1" {
+		t.Fatalf("unexpected app %+v", state.app)
+	}
+	if len(state.fns) != 2 {
+		t.Fatalf("expected 2 functions, got %d", len(state.fns))
+	}
+	hello := state.fns["hello"]
+	if hello == nil || hello.fn.Image != "hello:0.0.1" || len(hello.triggers) != 1 {
+		t.Fatalf("unexpected hello function %+v", hello)
+	}
+	world := state.fns["sub-world"]
+	if world == nil || world.fn.Image != "sub-world:0.0.3" || world.fn.Memory != 256 {
+		t.Fatalf("unexpected sub-world function %+v", world)
+	}
+}
+
+func TestComputeApplyPlanReplacesTriggerWithChangedType(t *testing.T) {
+	local := &applyState{
+		app: &models.App{Name: "myapp"},
+		fns: map[string]*applyFn{
+			"hello": {
+				fn:       &models.Fn{Name: "hello", Image: "hello:0.0.1"},
+				triggers: []*models.Trigger{{Name: "hello", Type: "cloudevent", Source: "/hello"}},
+			},
+		},
+	}
+	remote := &applyState{
+		app: &models.App{ID: "app1", Name: "myapp"},
+		fns: map[string]*applyFn{
+			"hello": {
+				fn:       &models.Fn{ID: "fn1", Name: "hello", Image: "hello:0.0.1"},
+				triggers: []*models.Trigger{{ID: "t1", Name: "hello", Type: "http", Source: "/hello"}},
+			},
+		},
+	}
+
+	plan := computeApplyPlan(local, remote, false)
+	if got := strings.Join(applyActions(plan), ", "); got != "replace trigger hello/hello" {
+		t.Fatalf("expected the trigger to be replaced, got %q", got)
+	}
+	if _, triggers := plan.deletions(); len(triggers) != 1 || triggers[0].ID != "t1" {
+		t.Fatalf("expected the replaced trigger to need confirmation, got %v", triggers)
+	}
+}
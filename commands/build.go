@@ -17,11 +17,15 @@
 package commands
 
 import (
+	"errors"
 	"fmt"
 	"github.com/fnproject/cli/common"
 	"github.com/urfave/cli"
+	"io"
+	"io/ioutil"
 	"os"
 	"path/filepath"
+	"time"
 )
 
 // BuildCommand returns build cli.command
@@ -41,7 +45,15 @@ func BuildCommand() cli.Command {
 }
 
 type buildcmd struct {
-	noCache bool
+	noCache        bool
+	localDebug     bool
+	dockerfileOnly bool
+	dockerfileOut  string
+	all            bool
+	parallel       int
+
+	// buildOptions are the build flags, shared by every function that is built
+	buildOptions common.BuildOptions
 }
 
 func (b *buildcmd) flags() []cli.Flag {
@@ -60,6 +72,69 @@ func (b *buildcmd) flags() []cli.Flag {
 			Name:  "build-arg",
 			Usage: "Set build-time variables",
 		},
+		cli.StringFlag{
+			Name:        "sbom",
+			Usage:       "Generate an SBOM of the built image in this format (spdx or cyclonedx) with syft",
+			Destination: &b.buildOptions.Attestations.SBOM,
+		},
+		cli.BoolFlag{
+			Name:        "provenance",
+			Usage:       "Write a provenance document of the build recording func.yaml, the base images and the build args",
+			Destination: &b.buildOptions.Attestations.Provenance,
+		},
+		cli.BoolFlag{
+			Name:        "push-attestations",
+			Usage:       "Attach the SBOM and provenance to the pushed image as OCI artifacts with oras",
+			Destination: &b.buildOptions.Attestations.Push,
+		},
+		cli.StringSliceFlag{
+			Name:  "cache-from",
+			Usage: "Import build cache from a registry image or a buildx cache source such as type=local,src=DIR, replacing cache_from of func.yaml",
+		},
+		cli.StringSliceFlag{
+			Name:  "cache-to",
+			Usage: "Export build cache to a registry image or a buildx cache destination such as type=registry,ref=IMAGE,mode=max, replacing cache_to of func.yaml",
+		},
+		cli.StringSliceFlag{
+			Name:  "platform",
+			Usage: "Build a local image for these platforms, e.g. linux/amd64,linux/arm64, replacing platforms of func.yaml. Ignored when the app shape decides the platforms",
+		},
+		cli.BoolFlag{
+			Name:        "load",
+			Usage:       "With several platforms, also load the image of the host platform into docker",
+			Destination: &b.buildOptions.LoadHostPlatform,
+		},
+		cli.BoolFlag{
+			Name:        "local-debug",
+			Usage:       "Build the function image with the remote debug options of fn deploy --local-debug",
+			Destination: &b.localDebug,
+		},
+		cli.BoolFlag{
+			Name:        "dockerfile-only",
+			Usage:       "Print the Dockerfile generated for the function instead of building it",
+			Destination: &b.dockerfileOnly,
+		},
+		cli.StringFlag{
+			Name:        "dockerfile-out",
+			Usage:       "With --dockerfile-only, write the Dockerfile to this file instead of stdout",
+			Destination: &b.dockerfileOut,
+		},
+		cli.StringFlag{
+			Name:        "progress",
+			Usage:       "Build output: tty prints dots, plain the container engine output, json an event per build step",
+			Destination: &b.buildOptions.Progress,
+		},
+		cli.BoolFlag{
+			Name:        "all",
+			Usage:       "If in root directory containing `app.yaml`, this will build all functions",
+			Destination: &b.all,
+		},
+		cli.IntFlag{
+			Name:        "parallel",
+			Usage:       "With --all, build up to this many functions at a time",
+			Value:       1,
+			Destination: &b.parallel,
+		},
 		cli.StringFlag{
 			Name:  "working-dir, w",
 			Usage: "Specify the working directory to build a function, must be the full path.",
@@ -69,13 +144,44 @@ func (b *buildcmd) flags() []cli.Flag {
 
 // build will take the found valid function and build it
 func (b *buildcmd) build(c *cli.Context) error {
+	b.buildOptions.Verbose = common.IsVerbose()
+	b.buildOptions.BuildArgs = c.StringSlice("build-arg")
+	b.buildOptions.NoCache = b.noCache
+	b.buildOptions.LocalDebug = b.localDebug
+	b.buildOptions.Cache = common.BuildCache{From: c.StringSlice("cache-from"), To: c.StringSlice("cache-to")}
+	b.buildOptions.Platforms = common.ParsePlatforms(c.StringSlice("platform"))
+	if err := b.buildOptions.Validate(); err != nil {
+		return err
+	}
 	dir := common.GetDir(c)
 
+	if b.parallel < 1 {
+		return errors.New("--parallel must be at least 1")
+	}
+	if b.parallel > 1 && !b.all {
+		return errors.New("--parallel can only be used with --all")
+	}
+
 	path := c.Args().First()
 	if path != "" {
-		fmt.Printf("Building function at: ./%s\n", path)
 		dir = filepath.Join(dir, path)
 	}
+	if b.all {
+		if b.dockerfileOnly || b.dockerfileOut != "" {
+			return errors.New("--dockerfile-only and --dockerfile-out can't be used with --all")
+		}
+		return b.buildAll(c, dir)
+	}
+	if b.dockerfileOnly {
+		return b.writeDockerfile(dir)
+	}
+	if b.dockerfileOut != "" {
+		return errors.New("--dockerfile-out can only be used with --dockerfile-only")
+	}
+	info := b.buildOptions.InfoOutput()
+	if path != "" {
+		fmt.Fprintf(info, "Building function at: ./%s\n", path)
+	}
 
 	err := os.Chdir(dir)
 	if err != nil {
@@ -95,15 +201,13 @@ func (b *buildcmd) build(c *cli.Context) error {
 			return err
 		}
 
-		buildArgs := c.StringSlice("build-arg")
-
 		// Passing empty shape for build command
-		ff, err = common.BuildFuncV20180708(common.IsVerbose(), fpath, ff, buildArgs, b.noCache, "", false)
+		ff, err = common.BuildFuncV20180708WithOptions(fpath, ff, b.buildOptions)
 		if err != nil {
 			return err
 		}
 
-		fmt.Printf("Function %v built successfully.\n", ff.ImageNameV20180708())
+		fmt.Fprintf(info, "Function %v built successfully.\n", ff.ImageNameV20180708())
 		return nil
 
 	default:
@@ -112,13 +216,84 @@ func (b *buildcmd) build(c *cli.Context) error {
 			return err
 		}
 
-		buildArgs := c.StringSlice("build-arg")
-		ff, err = common.BuildFunc(common.IsVerbose(), fpath, ff, buildArgs, b.noCache)
+		ff, err = common.BuildFuncWithOptions(fpath, ff, b.buildOptions)
 		if err != nil {
 			return err
 		}
 
-		fmt.Printf("Function %v built successfully.\n", ff.ImageName())
+		fmt.Fprintf(info, "Function %v built successfully.\n", ff.ImageName())
 		return nil
 	}
 }
+
+// writeDockerfile writes the Dockerfile fn build generates for the function in dir, without building it
+func (b *buildcmd) writeDockerfile(dir string) error {
+	fpath, ff, err := common.FindAndParseFuncFileV20180708(dir)
+	if err != nil {
+		return err
+	}
+	dockerfile, err := common.GenerateDockerfileV20180708(filepath.Dir(fpath), ff, b.localDebug)
+	if err != nil {
+		return err
+	}
+	if b.dockerfileOut == "" || b.dockerfileOut == "-" {
+		fmt.Print(dockerfile)
+		return nil
+	}
+	if err := ioutil.WriteFile(b.dockerfileOut, []byte(dockerfile), 0644); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "Dockerfile of %s written to %s\n", ff.Name, b.dockerfileOut)
+	return nil
+}
+
+// buildAll builds every function of the app whose app.yaml is in dir, up to b.parallel at a time.
+// The images are only built locally, so it needs no registry or server. Every function is attempted
+// and a summary is printed at the end.
+func (b *buildcmd) buildAll(c *cli.Context, dir string) error {
+	if _, err := common.LoadAppfile(dir); err != nil {
+		return err
+	}
+	funcs, err := findAllFuncs(dir, dir)
+	if err != nil {
+		return err
+	}
+	if len(funcs) == 0 {
+		return errors.New("No functions found to build")
+	}
+
+	for _, f := range funcs {
+		if f.ff.Name == "" {
+			f.ff.Name = filepath.Base(filepath.Dir(f.path))
+		}
+	}
+
+	build := func(f deployAllFunc, out, errOut io.Writer) allFuncsResult {
+		opts := b.buildOptions
+		opts.Out, opts.ErrOut = out, errOut
+		opts.Prefixed = b.parallel > 1
+		// Passing empty shape for build command
+		ff, err := common.BuildFuncV20180708WithOptions(f.path, f.ff, opts)
+		result := allFuncsResult{name: f.ff.Name, image: f.ff.ImageNameV20180708(), err: err}
+		if ff != nil {
+			result.image = ff.ImageNameV20180708()
+		}
+		return result
+	}
+
+	info := b.buildOptions.InfoOutput()
+	if b.parallel == 1 {
+		results := make([]allFuncsResult, len(funcs))
+		for i, f := range funcs {
+			fmt.Fprintf(info, "Building function %s\n", f.ff.Name)
+			start := time.Now()
+			results[i] = build(f, os.Stdout, os.Stderr)
+			results[i].duration = time.Since(start)
+		}
+		return printAllFuncsSummary(info, results, "build", "built")
+	}
+
+	fmt.Fprintf(info, "Building %d functions with parallelism %d\n", len(funcs), b.parallel)
+	results := runAllFuncsParallel(funcs, b.parallel, b.buildOptions.Progress == common.BuildProgressJSON, os.Stdout, os.Stderr, build)
+	return printAllFuncsSummary(info, results, "build", "built")
+}
@@ -0,0 +1,52 @@
+package commands
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPrintBuildAllSummary(t *testing.T) {
+	var out bytes.Buffer
+	err := printAllFuncsSummary(&out, []allFuncsResult{
+		{name: "hello", image: "hello:0.0.2", duration: 3 * time.Second},
+		{name: "world", duration: time.Second, err: errors.New("error running docker build: exit status 1")},
+	}, "build", "built")
+	if err == nil || err.Error() != "1 of 2 functions failed to build" {
+		t.Fatalf("expected a failure count error, got %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 3 || !strings.Contains(lines[1], "built") || !strings.Contains(lines[2], "failed") {
+		t.Fatalf("unexpected summary:\n%s", out.String())
+	}
+}
+
+func TestFindAllFuncs(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fn-build-all")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	for _, d := range []string{"users/create", "orders"} {
+		os.MkdirAll(filepath.Join(dir, d), 0755)
+	}
+	ioutil.WriteFile(filepath.Join(dir, "users", "create", "func.yaml"), []byte("schema_version: 20180708\nruntime: go\n"), 0644)
+	ioutil.WriteFile(filepath.Join(dir, "orders", "func.yaml"), []byte("schema_version: 20180708\nname: orders-api\nruntime: go\n"), 0644)
+
+	funcs, err := findAllFuncs(dir, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, f := range funcs {
+		names = append(names, f.ff.Name)
+	}
+	if strings.Join(names, ",") != "orders-api,users-create" {
+		t.Fatalf("expected the functions named after their directories, got %v", names)
+	}
+}
@@ -34,6 +34,7 @@ type Cmd map[string]cli.Command
 
 // Commands map of all top-level commands
 var Commands = Cmd{
+	"apply":        ApplyCommand(),
 	"build":        BuildCommand(),
 	"build-server": BuildServerCommand(),
 	"bump":         common.BumpCommand(),
@@ -44,17 +45,23 @@ var Commands = Cmd{
 	"create":       CreateCommand(),
 	"delete":       DeleteCommand(),
 	"deploy":       DeployCommand(),
+	"diff":         DiffCommand(),
+	"eject":        EjectCommand(),
 	"get":          GetCommand(),
 	"init":         InitCommand(),
 	"inspect":      InspectCommand(),
 	"list":         ListCommand(),
+	"lock":         LockCommand(),
 	"migrate":      MigrateCommand(),
 	"push":         PushCommand(),
+	"rollback":     RollbackCommand(),
 	"start":        StartCommand(),
 	"stop":         StopCommand(),
+	"test":         TestCommand(),
 	"unset":        UnsetCommand(),
 	"update":       UpdateCommand(),
 	"use":          UseCommand(),
+	"verify":       VerifyCommand(),
 }
 
 var CreateCmds = Cmd{
@@ -110,12 +117,17 @@ var InspectCmds = Cmd{
 }
 
 var ListCmds = Cmd{
-	"config":    ConfigCommand("list"),
-	"apps":      app.List(),
-	"functions": fn.List(),
-	"pbfs":      pbf.List(),
-	"triggers":  trigger.List(),
-	"contexts":  context.List(),
+	"config":      ConfigCommand("list"),
+	"apps":        app.List(),
+	"functions":   fn.List(),
+	"pbfs":        pbf.List(),
+	"triggers":    trigger.List(),
+	"contexts":    context.List(),
+	"deployments": ListDeploymentsCommand(),
+}
+
+var RollbackCmds = Cmd{
+	"function": RollbackFunctionCommand(),
 }
 
 var UnsetCmds = Cmd{
@@ -135,6 +147,10 @@ var UseCmds = Cmd{
 	"context": context.Use(),
 }
 
+var VerifyCmds = Cmd{
+	"image": VerifyImageCommand(),
+}
+
 // GetCommands returns a list of cli.commands
 func GetCommands(commands map[string]cli.Command) []cli.Command {
 	cmds := []cli.Command{}
@@ -23,6 +23,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
 	"path/filepath"
@@ -34,6 +35,7 @@ import (
 
 	client "github.com/fnproject/cli/client"
 	common "github.com/fnproject/cli/common"
+	"github.com/fnproject/cli/config"
 	apps "github.com/fnproject/cli/objects/app"
 	function "github.com/fnproject/cli/objects/fn"
 	trigger "github.com/fnproject/cli/objects/trigger"
@@ -85,7 +87,7 @@ var RegionsWithOldKMSEndpoints = map[ociCommon.Region]struct{}{
 
 // DeployCommand returns deploy cli.command
 func DeployCommand() cli.Command {
-	cmd := deploycmd{}
+	cmd := deploycmd{out: os.Stdout, errOut: os.Stderr}
 	var flags []cli.Flag
 	flags = append(flags, cmd.flags()...)
 	return cli.Command{
@@ -122,6 +124,23 @@ type deploycmd struct {
 	registry   string
 	all        bool
 	noBump     bool
+	parallel   int
+	force      bool
+	dryRun     bool
+	strategy   string
+	switchTo   string
+	smoke      bool
+	skipSmoke  bool
+
+	// buildOptions are the build flags, shared by every function that is deployed
+	buildOptions common.BuildOptions
+
+	// plan collects the app, function and trigger changes of a --dry-run
+	plan *applyPlan
+
+	// out and errOut receive the progress of a deploy, with --parallel every function gets its own prefixed writers
+	out    io.Writer
+	errOut io.Writer
 }
 
 func (p *deploycmd) flags() []cli.Flag {
@@ -146,6 +165,11 @@ func (p *deploycmd) flags() []cli.Flag {
 			Usage:       "Don't use Docker cache for the build",
 			Destination: &p.noCache,
 		},
+		cli.StringFlag{
+			Name:        "progress",
+			Usage:       "Build output: tty prints dots, plain the container engine output, json an event per build step",
+			Destination: &p.buildOptions.Progress,
+		},
 		cli.BoolFlag{
 			Name:        "local, skip-push", // todo: deprecate skip-push
 			Usage:       "Do not push Docker built images onto Docker Hub - useful for local development.",
@@ -166,6 +190,43 @@ func (p *deploycmd) flags() []cli.Flag {
 			Usage:       "If in root directory containing `app.yaml`, this will deploy all functions",
 			Destination: &p.all,
 		},
+		cli.IntFlag{
+			Name:        "parallel",
+			Usage:       "Number of functions to build, push and update concurrently with --all",
+			Value:       1,
+			Destination: &p.parallel,
+		},
+		cli.BoolFlag{
+			Name:        "force",
+			Usage:       "With --all, also deploy functions that are unchanged since their last deploy",
+			Destination: &p.force,
+		},
+		cli.BoolFlag{
+			Name:        "dry-run",
+			Usage:       "Print what would be bumped, built, pushed, created and updated without changing anything",
+			Destination: &p.dryRun,
+		},
+		cli.StringFlag{
+			Name:        "strategy",
+			Usage:       "How to release the new version: inplace updates the function, bluegreen deploys it as <name>-<version> and switches the http triggers of func.yaml to it",
+			Value:       deployStrategyInPlace,
+			Destination: &p.strategy,
+		},
+		cli.StringFlag{
+			Name:        "switch-to",
+			Usage:       "With --strategy bluegreen, switch the http triggers of func.yaml to a version deployed before, without building or deploying",
+			Destination: &p.switchTo,
+		},
+		cli.BoolFlag{
+			Name:        "smoke",
+			Usage:       "Invoke the function once it is deployed and fail unless it responds with a 2xx status, even if func.yaml has no smoke section",
+			Destination: &p.smoke,
+		},
+		cli.BoolFlag{
+			Name:        "skip-smoke",
+			Usage:       "Don't run the smoke test of func.yaml after the deploy",
+			Destination: &p.skipSmoke,
+		},
 		cli.BoolFlag{
 			Name:        "no-bump",
 			Usage:       "Do not bump the version, assuming external version management",
@@ -175,6 +236,33 @@ func (p *deploycmd) flags() []cli.Flag {
 			Name:  "build-arg",
 			Usage: "Set build time variables",
 		},
+		cli.StringFlag{
+			Name:        "sbom",
+			Usage:       "Generate an SBOM of the built image in this format (spdx or cyclonedx) with syft",
+			Destination: &p.buildOptions.Attestations.SBOM,
+		},
+		cli.BoolFlag{
+			Name:        "provenance",
+			Usage:       "Write a provenance document of the build recording func.yaml, the base images and the build args",
+			Destination: &p.buildOptions.Attestations.Provenance,
+		},
+		cli.BoolFlag{
+			Name:        "push-attestations",
+			Usage:       "Attach the SBOM and provenance to the pushed image as OCI artifacts with oras",
+			Destination: &p.buildOptions.Attestations.Push,
+		},
+		cli.StringSliceFlag{
+			Name:  "cache-from",
+			Usage: "Import build cache from a registry image or a buildx cache source such as type=local,src=DIR, replacing cache_from of func.yaml",
+		},
+		cli.StringSliceFlag{
+			Name:  "cache-to",
+			Usage: "Export build cache to a registry image or a buildx cache destination such as type=registry,ref=IMAGE,mode=max, replacing cache_to of func.yaml",
+		},
+		cli.StringSliceFlag{
+			Name:  "platform",
+			Usage: "Build a local image for these platforms, e.g. linux/amd64,linux/arm64, replacing platforms of func.yaml. Ignored when the app shape decides the platforms",
+		},
 		cli.StringFlag{
 			Name:  "working-dir,w",
 			Usage: "Specify the working directory to deploy a function, must be the full path.",
@@ -193,6 +281,44 @@ func (p *deploycmd) flags() []cli.Flag {
 // is the one that lives in the same directory as the app.yaml.
 func (p *deploycmd) deploy(c *cli.Context) error {
 
+	if p.parallel < 1 {
+		return errors.New("--parallel must be at least 1")
+	}
+	if p.parallel > 1 && !p.all {
+		return errors.New("--parallel can only be used with --all")
+	}
+	if p.smoke && p.skipSmoke {
+		return errors.New("--smoke and --skip-smoke can't be used together")
+	}
+	p.buildOptions.Cache = common.BuildCache{From: c.StringSlice("cache-from"), To: c.StringSlice("cache-to")}
+	p.buildOptions.Platforms = common.ParsePlatforms(c.StringSlice("platform"))
+	// local deploys run the image of the host platform
+	p.buildOptions.LoadHostPlatform = p.local || p.localDebug
+	if err := p.buildOptions.Validate(); err != nil {
+		return err
+	}
+	if p.buildOptions.Progress == common.BuildProgressJSON && !p.dryRun {
+		// stdout only carries the build events, the progress of the deploy goes to stderr
+		p.buildOptions.Out, p.out = p.out, p.errOut
+	}
+	switch p.strategy {
+	case "", deployStrategyInPlace:
+	case deployStrategyBlueGreen:
+		if p.dryRun {
+			return fmt.Errorf("--dry-run can't be used with --strategy %s", deployStrategyBlueGreen)
+		}
+	default:
+		return fmt.Errorf("unknown deploy strategy %q, use %s or %s", p.strategy, deployStrategyInPlace, deployStrategyBlueGreen)
+	}
+	if p.switchTo != "" {
+		if p.strategy != deployStrategyBlueGreen {
+			return fmt.Errorf("--switch-to can only be used with --strategy %s", deployStrategyBlueGreen)
+		}
+		if p.all {
+			return errors.New("--switch-to can't be used with --all")
+		}
+	}
+
 	appName := ""
 	dir := common.GetDir(c)
 
@@ -232,6 +358,25 @@ func (p *deploycmd) deploy(c *cli.Context) error {
 		}
 	}
 
+	if p.dryRun {
+		p.plan = &applyPlan{}
+		app, err := p.planApp(appName, &appfApp, appf != nil)
+		if err != nil {
+			return err
+		}
+		if p.all {
+			err = p.deployAll(c, app)
+		} else {
+			err = p.deploySingle(c, app)
+		}
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(p.out, "Dry run, nothing was built, pushed or changed:")
+		p.plan.print(p.out)
+		return nil
+	}
+
 	// find and create/update app if required
 	app, err := apps.GetAppByName(p.clientV2, appName)
 	if _, ok := err.(apps.NameNotFoundError); ok && p.createApp {
@@ -272,17 +417,11 @@ func (p *deploycmd) deploySingle(c *cli.Context, app *models.App) error {
 		// if we're in the context of an app, first arg is path to the function
 		path := c.Args().First()
 		if path != "" {
-			fmt.Printf("Deploying function at: ./%s\n", path)
+			fmt.Fprintf(p.out, "Deploying function at: ./%s\n", path)
 		}
 		dir = filepath.Join(wd, path)
 	}
 
-	err := os.Chdir(dir)
-	if err != nil {
-		return err
-	}
-	defer os.Chdir(wd)
-
 	fpath, ff, err := common.FindAndParseFuncFileV20180708(dir)
 	if err != nil {
 		return err
@@ -301,61 +440,206 @@ func (p *deploycmd) deployAll(c *cli.Context, app *models.App) error {
 		// if we're in the context of an app, first arg is path to the function
 		path := c.Args().First()
 		if path != "" {
-			fmt.Printf("Deploying function at: ./%s\n", path)
+			fmt.Fprintf(p.out, "Deploying function at: ./%s\n", path)
 		}
 		dir = filepath.Join(wd, path)
 	}
 
-	var funcFound bool
+	funcs, err := findAllFuncs(dir, wd)
+	if err != nil {
+		return err
+	}
+
+	if len(funcs) == 0 {
+		return errors.New("No functions found to deploy")
+	}
+
+	if p.parallel > 1 && !p.dryRun {
+		return p.deployAllParallel(c, app, dir, funcs)
+	}
+	var skipped []string
+	for _, f := range funcs {
+		wasSkipped, err := p.deployIfChanged(c, app, dir, f)
+		if err != nil {
+			return fmt.Errorf("deploy error on %s: %v", f.path, err)
+		}
+		if wasSkipped {
+			skipped = append(skipped, f.ff.Name)
+		}
+	}
+	if len(skipped) > 0 {
+		fmt.Fprintf(p.out, "Skipped %d unchanged functions: %s. Use --force to deploy them anyway.\n", len(skipped), strings.Join(skipped, ", "))
+	}
+	return nil
+}
+
+// findAllFuncs returns the functions under dir, functions without a name are named after their
+// directory relative to wd
+func findAllFuncs(dir, wd string) ([]deployAllFunc, error) {
+	var funcs []deployAllFunc
 	err := common.WalkFuncsV20180708(dir, func(path string, ff *common.FuncFileV20180708, err error) error {
 		if err != nil { // probably some issue with funcfile parsing, can decide to handle this differently if we'd like
 			return err
 		}
-		dir := filepath.Dir(path)
-		if dir != wd {
-			// change dirs
-			err = os.Chdir(dir)
-			if err != nil {
-				return err
-			}
-		}
-		p2 := strings.TrimPrefix(dir, wd)
+		p2 := strings.TrimPrefix(filepath.Dir(path), wd)
 		if ff.Name == "" {
 			ff.Name = strings.Replace(p2, "/", "-", -1)
 			if strings.HasPrefix(ff.Name, "-") {
 				ff.Name = ff.Name[1:]
 			}
 		}
+		funcs = append(funcs, deployAllFunc{path: path, ff: ff})
+		return nil
+	})
+	return funcs, err
+}
 
-		err = p.deployFuncV20180708(c, app, path, ff)
+// deployIfChanged deploys a function found by deployAll, unless its sources are unchanged since its
+// last deploy and the deployed function still runs the image of that deploy. It reports whether the
+// function was skipped.
+func (p *deploycmd) deployIfChanged(c *cli.Context, app *models.App, root string, f deployAllFunc) (bool, error) {
+	state := common.NewDefaultDeployState()
+	key := common.NewDeployStateKey(p.provider, app.Name, f.ff.Name)
+	isPBFDeploy := f.ff.Deploy != nil && f.ff.Deploy.OCI != nil && f.ff.Deploy.OCI.PBF != nil && strings.TrimSpace(f.ff.Deploy.OCI.PBF.ListingID) != ""
+
+	if !p.force && !isPBFDeploy {
+		hash, err := common.FuncSourceHash(root, f.path, f.ff, p.buildInputs(c, app))
 		if err != nil {
-			return fmt.Errorf("deploy error on %s: %v", path, err)
+			fmt.Fprintf(p.errOut, "Warning: unable to hash the sources of %s, deploying it: %v\n", f.ff.Name, err)
+		} else if last, ok := state.Get(key); ok && last.SourceHash == hash && last.IsImage(p.deployedImage(app, last.FunctionName(f.ff.Name))) {
+			fmt.Fprintf(p.out, "Skipping %s, unchanged since it was deployed with image %s\n", f.ff.Name, last.Image)
+			return true, nil
 		}
+	}
 
-		now := time.Now()
-		os.Chtimes(path, now, now)
-		funcFound = true
-		return nil
-	})
+	if p.dryRun {
+		return false, p.deployFuncV20180708(c, app, f.path, f.ff)
+	}
+	if err := p.deployFuncV20180708(c, app, f.path, f.ff); err != nil {
+		return false, err
+	}
+	now := time.Now()
+	os.Chtimes(f.path, now, now)
+
+	if !isPBFDeploy {
+		if err := p.recordDeploy(c, app, root, f, state, key); err != nil {
+			fmt.Fprintf(p.errOut, "Warning: unable to record the deploy of %s, it will be deployed again next time: %v\n", f.ff.Name, err)
+		}
+	}
+	return false, nil
+}
+
+// recordDeploy stores the source hash and image of a deployed function. The hash is taken from
+// the func file as it is after the deploy, as the build may stamp build and run images into it.
+func (p *deploycmd) recordDeploy(c *cli.Context, app *models.App, root string, f deployAllFunc, state *common.DeployState, key common.DeployStateKey) error {
+	ff, err := common.ParseFuncFileV20180708(f.path)
 	if err != nil {
 		return err
 	}
+	ff.Name = f.ff.Name
+	hash, err := common.FuncSourceHash(root, f.path, ff, p.buildInputs(c, app))
+	if err != nil {
+		return err
+	}
+	entry := common.DeployStateEntry{SourceHash: hash, Image: ff.ImageNameV20180708()}
+	if p.strategy == deployStrategyBlueGreen {
+		entry.Function = blueGreenFnName(ff.Name, ff.Version)
+	}
+	if !p.local && !p.localDebug {
+		// the digest is only known once the image was pushed
+		if containerEngineType, err := common.GetContainerEngineType(); err == nil {
+			entry.ImageDigest, _ = lookupImageDigest(containerEngineType, entry.Image)
+		}
+	}
+	return state.Put(key, entry)
+}
 
-	if !funcFound {
-		return errors.New("No functions found to deploy")
+// buildInputs returns the settings other than the function sources that change its image
+func (p *deploycmd) buildInputs(c *cli.Context, app *models.App) []string {
+	inputs := append([]string{}, c.StringSlice("build-arg")...)
+	inputs = append(inputs, fmt.Sprintf("local=%t", p.local), fmt.Sprintf("local-debug=%t", p.localDebug))
+	if !p.local && !p.localDebug {
+		shape := app.Shape
+		if shape == "" {
+			shape = common.DefaultAppShape
+		}
+		inputs = append(inputs, "shape="+shape)
 	}
+	return inputs
+}
 
-	return nil
+// deployedImage returns the image of the deployed function, or an empty string if it can't be fetched
+func (p *deploycmd) deployedImage(app *models.App, fnName string) string {
+	if app.ID == "" {
+		// the app is only created by a dry run
+		return ""
+	}
+	fn, err := function.GetFnByName(p.clientV2, app.ID, fnName)
+	if err != nil {
+		return ""
+	}
+	return fn.Image
+}
+
+// deployAllFunc is a function found by deployAll or by build --all
+type deployAllFunc struct {
+	path string
+	ff   *common.FuncFileV20180708
+}
+
+// deployAllParallel deploys up to p.parallel functions at a time. Every function is attempted,
+// their output is prefixed with the function name and a summary is printed at the end.
+func (p *deploycmd) deployAllParallel(c *cli.Context, app *models.App, root string, funcs []deployAllFunc) error {
+	// resolve the shape once, so concurrent deploys only read the app
+	if !p.local && !p.localDebug && app.Shape == "" {
+		app.Shape = common.DefaultAppShape
+	}
+
+	fmt.Fprintf(p.out, "Deploying %d functions to app: %s with parallelism %d\n", len(funcs), app.Name, p.parallel)
+	jsonOut := p.buildOptions.Progress == common.BuildProgressJSON
+	fnOut := p.out
+	if jsonOut {
+		fnOut = p.buildOptions.Out
+	}
+	results := runAllFuncsParallel(funcs, p.parallel, jsonOut, fnOut, p.errOut, func(f deployAllFunc, out, errOut io.Writer) allFuncsResult {
+		fp := *p
+		fp.out = out
+		fp.errOut = errOut
+		fp.buildOptions.Prefixed = true
+		if jsonOut {
+			fp.buildOptions.Out, fp.out = out, errOut
+		}
+		skipped, err := fp.deployIfChanged(c, app, root, f)
+		return allFuncsResult{name: f.ff.Name, image: f.ff.ImageNameV20180708(), skipped: skipped, err: err}
+	})
+	return printAllFuncsSummary(p.out, results, "deploy", "deployed")
 }
 
 func (p *deploycmd) deployFuncV20180708(c *cli.Context, app *models.App, funcfilePath string, funcfile *common.FuncFileV20180708) error {
 	if funcfile.Name == "" {
 		funcfile.Name = filepath.Base(filepath.Dir(funcfilePath)) // todo: should probably make a copy of ff before changing it
 	}
-	common.WarnIfOCIManagedFunctionSettingsUnsupported(os.Stderr, p.provider, funcfile.Name, funcfile)
+	common.WarnIfOCIManagedFunctionSettingsUnsupported(p.errOut, p.provider, funcfile.Name, funcfile)
+	if funcfile.Smoke != nil {
+		if err := funcfile.Smoke.Validate(); err != nil {
+			return err
+		}
+		if funcfile.Smoke.Rollback && p.strategy == deployStrategyBlueGreen {
+			fmt.Fprintf(p.errOut, "Warning: smoke.rollback is ignored with --strategy %s, the triggers are only switched once the smoke test passes\n", deployStrategyBlueGreen)
+		}
+	}
+	if p.dryRun {
+		return p.planFunc(c, app, funcfilePath, funcfile)
+	}
+	if p.switchTo != "" {
+		return p.switchBlueGreen(app, funcfile, p.switchTo)
+	}
 
 	oracleProvider, _ := getOracleProvider()
 	isPBFDeploy := funcfile.Deploy != nil && funcfile.Deploy.OCI != nil && funcfile.Deploy.OCI.PBF != nil && strings.TrimSpace(funcfile.Deploy.OCI.PBF.ListingID) != ""
+	if isPBFDeploy && p.strategy == deployStrategyBlueGreen {
+		return fmt.Errorf("--strategy %s can't be used to deploy %s from a pre-built function listing", deployStrategyBlueGreen, funcfile.Name)
+	}
 	if !isPBFDeploy && oracleProvider != nil && oracleProvider.ImageCompartmentID != "" {
 		// If the provider is Oracle and ImageCompartmentID is present, we need to deploy image to the ImageCompartmentID.
 		// The repository name should be unique throughout a tenancy. We check if a repository exists in the compartment and create it if it doesn't already exist.
@@ -384,7 +668,7 @@ func (p *deploycmd) deployFuncV20180708(c *cli.Context, app *models.App, funcfil
 		}
 	}
 
-	fmt.Printf("Deploying %s to app: %s\n", funcfile.Name, app.Name)
+	fmt.Fprintf(p.out, "Deploying %s to app: %s\n", funcfile.Name, app.Name)
 	if !p.noBump {
 		funcfile2, err := common.BumpItV20180708(funcfilePath, common.Patch)
 		if err != nil {
@@ -404,7 +688,6 @@ func (p *deploycmd) deployFuncV20180708(c *cli.Context, app *models.App, funcfil
 			shape = app.Shape
 			if shape == "" {
 				shape = common.DefaultAppShape
-				app.Shape = shape
 			}
 
 			if _, ok := common.ShapeMap[shape]; !ok {
@@ -412,82 +695,79 @@ func (p *deploycmd) deployFuncV20180708(c *cli.Context, app *models.App, funcfil
 			}
 		}
 
-		_, err := common.BuildFuncV20180708(common.IsVerbose(), funcfilePath, funcfile, buildArgs, p.noCache, shape, p.localDebug)
+		opts := p.buildOptions
+		opts.Verbose, opts.BuildArgs, opts.NoCache, opts.Shape, opts.LocalDebug = common.IsVerbose(), buildArgs, p.noCache, shape, p.localDebug
+		if opts.Out == nil {
+			opts.Out = p.out
+		}
+		opts.ErrOut = p.errOut
+		_, err := common.BuildFuncV20180708WithOptions(funcfilePath, funcfile, opts)
 		if err != nil {
 			return err
 		}
 
-		if err := p.signImage(funcfile); err != nil {
+		if err := p.signImage(funcfilePath, funcfile); err != nil {
 			return err
 		}
 	}
-	if err := p.updateFunction(c, app.ID, funcfile); err != nil {
+	if p.strategy == deployStrategyBlueGreen {
+		return p.deployBlueGreen(app, funcfilePath, funcfile)
+	}
+	previous, err := p.updateFunction(c, app, funcfilePath, funcfile)
+	if err != nil {
 		return err
 	}
 	if err := common.InvalidateInvokeEndpointCacheForFunction(p.provider, app.Name, funcfile.Name); err != nil {
-		fmt.Fprintf(os.Stderr, "Warning: unable to invalidate invoke endpoint cache: %v\n", err)
+		fmt.Fprintf(p.errOut, "Warning: unable to invalidate invoke endpoint cache: %v\n", err)
+	}
+	if p.runsSmokeTest(funcfile) {
+		return p.smokeTestDeploy(app, funcfile, previous)
 	}
 	return nil
 }
 
-func (p *deploycmd) updateFunction(c *cli.Context, appID string, ff *common.FuncFileV20180708) error {
+// updateFunction creates or updates the function and triggers of a func file. It returns the function
+// as it was before the update, nil when it was created or its triggers couldn't be listed.
+func (p *deploycmd) updateFunction(c *cli.Context, app *models.App, funcfilePath string, ff *common.FuncFileV20180708) (*common.FnSnapshot, error) {
+	appID := app.ID
 	if ff.Deploy != nil && ff.Deploy.OCI != nil && ff.Deploy.OCI.PBF != nil && strings.TrimSpace(ff.Deploy.OCI.PBF.ListingID) != "" {
-		fmt.Printf("Updating function %s using PBF listing %s...\n", ff.Name, ff.Deploy.OCI.PBF.ListingID)
+		fmt.Fprintf(p.out, "Updating function %s using PBF listing %s...\n", ff.Name, ff.Deploy.OCI.PBF.ListingID)
 	} else {
-		fmt.Printf("Updating function %s using image %s...\n", ff.Name, ff.ImageNameV20180708())
-	}
-	var detachedSeconds int
-	if ff.Deploy != nil && ff.Deploy.OCI != nil && ff.Deploy.OCI.DetachedMode != nil && ff.Deploy.OCI.DetachedMode.Timeout != "" {
-		_, seconds, err := common.ParseDetachedTimeoutSpec(ff.Deploy.OCI.DetachedMode.Timeout)
-		if err != nil {
-			return err
-		}
-		detachedSeconds = seconds
-	}
-	if ff.Deploy != nil && ff.Deploy.OCI != nil && ff.Deploy.OCI.ProvisionedConcurrency != nil {
-		if err := common.ValidateProvisionedConcurrencyConfig(ff.Deploy.OCI.ProvisionedConcurrency); err != nil {
-			return err
-		}
-	}
-
-	fn := &models.Fn{}
-	if err := function.WithFuncFileV20180708(ff, fn); err != nil {
-		return fmt.Errorf("Error getting function with funcfile: %s", err)
-	}
-	if ff.Deploy != nil && ff.Deploy.OCI != nil && ff.Deploy.OCI.PBF != nil {
-		if err := function.ResolvePBFMemoryForListing(p.provider, fn, ff.Deploy.OCI.PBF.ListingID); err != nil {
-			return err
-		}
+		fmt.Fprintf(p.out, "Updating function %s using image %s...\n", ff.Name, ff.ImageNameV20180708())
 	}
-	if detachedSeconds > 0 && common.IsOracleProvider(p.provider) {
-		function.SetDetachedTimeoutAnnotation(fn, detachedSeconds)
+	fn, err := p.fnFromFuncFile(ff)
+	if err != nil {
+		return nil, err
 	}
-	if common.IsOracleProvider(p.provider) && ff.Deploy != nil && ff.Deploy.OCI != nil && ff.Deploy.OCI.DetachedMode != nil {
-		function.SetDestinationAnnotations(fn, ff.Deploy.OCI.DetachedMode.OnSuccess, ff.Deploy.OCI.DetachedMode.OnFailure)
+	if fn.Image, err = p.enforceSignature(funcfilePath, ff, fn.Image); err != nil {
+		return nil, err
 	}
 	created := false
+	history := common.NewDeployHistoryEntry(p.provider, common.DeployHistoryActionDeploy, app.Name, ff.Name)
 
 	fnRes, err := function.GetFnByName(p.clientV2, appID, ff.Name)
 	if _, ok := err.(function.NameNotFoundError); ok {
 		fn.Name = ff.Name
 		if ff.Deploy != nil && ff.Deploy.OCI != nil && ff.Deploy.OCI.ProvisionedConcurrency != nil && common.IsOracleProvider(p.provider) {
 			if err := function.SetProvisionedConcurrencyAnnotations(fn, ff.Deploy.OCI.ProvisionedConcurrency); err != nil {
-				return err
+				return nil, err
 			}
 		}
 		fn, err = function.CreateFn(p.clientV2, appID, fn)
 		if err != nil {
-			return err
+			return nil, err
 		}
 		created = true
 	} else if err != nil {
 		// probably service is down or something...
-		return err
+		return nil, err
 	} else {
+		history.OldImage = fnRes.Image
+		history.Previous = snapshotFunction(p.clientV2, fnRes, p.errOut)
 		fn.ID = fnRes.ID
 		err = function.PutFn(p.clientV2, fn.ID, fn)
 		if err != nil {
-			return err
+			return nil, err
 		}
 	}
 
@@ -505,98 +785,129 @@ func (p *deploycmd) updateFunction(c *cli.Context, appID string, ff *common.Func
 			if _, ok := err.(trigger.NameNotFoundError); ok {
 				err = trigger.CreateTrigger(p.clientV2, trig)
 				if err != nil {
-					return err
+					return nil, err
 				}
 			} else if err != nil {
-				return err
+				return nil, err
 			} else {
 				trig.ID = trigs.ID
 				err = trigger.PutTrigger(p.clientV2, trig)
 				if err != nil {
-					return err
+					return nil, err
 				}
 			}
 		}
 	}
 	if !created && ff.Deploy != nil && ff.Deploy.OCI != nil && ff.Deploy.OCI.ProvisionedConcurrency != nil && common.IsOracleProvider(p.provider) {
 		if err := function.ApplyProvisionedConcurrency(p.provider, fn.ID, ff.Deploy.OCI.ProvisionedConcurrency); err != nil {
-			return err
+			return nil, err
 		}
 	}
-	return nil
-}
 
-func getOracleProvider() (*oracle.OracleProvider, error) {
-	currentProvider, err := client.CurrentProvider()
-	if err != nil {
-		return nil, err
+	history.NewImage = fn.Image
+	history.GitCommit = common.GitCommitOf(filepath.Dir(funcfilePath))
+	if !p.local && !p.localDebug && fn.Image != "" {
+		// the digest is only known once the image was pushed
+		if containerEngineType, err := common.GetContainerEngineType(); err == nil {
+			history.ImageDigest, _ = lookupImageDigest(containerEngineType, fn.Image)
+		}
 	}
-	if oracleProvider, ok := currentProvider.(*oracle.OracleProvider); ok {
-		return oracleProvider, nil
+	if err := common.NewDefaultDeployHistory().Append(history); err != nil {
+		fmt.Fprintf(p.errOut, "Warning: unable to record deploy history: %v\n", err)
 	}
-	return nil, nil
+	return history.Previous, nil
 }
 
-func (p *deploycmd) signImage(funcfile *common.FuncFileV20180708) error {
-	signingDetails := funcfile.SigningDetails
-	signatureConfigured, err := isSignatureConfigured(signingDetails)
-	if err != nil {
-		return err
+// fnFromFuncFile returns the function that a deploy puts to the server for a func file
+func (p *deploycmd) fnFromFuncFile(ff *common.FuncFileV20180708) (*models.Fn, error) {
+	var detachedSeconds int
+	if ff.Deploy != nil && ff.Deploy.OCI != nil && ff.Deploy.OCI.DetachedMode != nil && ff.Deploy.OCI.DetachedMode.Timeout != "" {
+		_, seconds, err := common.ParseDetachedTimeoutSpec(ff.Deploy.OCI.DetachedMode.Timeout)
+		if err != nil {
+			return nil, err
+		}
+		detachedSeconds = seconds
 	}
-	if !signatureConfigured {
-		return nil
+	if ff.Deploy != nil && ff.Deploy.OCI != nil && ff.Deploy.OCI.ProvisionedConcurrency != nil {
+		if err := common.ValidateProvisionedConcurrencyConfig(ff.Deploy.OCI.ProvisionedConcurrency); err != nil {
+			return nil, err
+		}
 	}
-	oracleProvider, _ := getOracleProvider()
-	if oracleProvider == nil {
-		return nil
+
+	fn := &models.Fn{}
+	if err := function.WithFuncFileV20180708(ff, fn); err != nil {
+		return nil, fmt.Errorf("Error getting function with funcfile: %s", err)
 	}
-	fmt.Printf("Signing image %s using KmsKey %s...\n", funcfile.ImageNameV20180708(), signingDetails.KmsKeyId)
-	imageDigest, err := getImageDigest(funcfile)
-	if err != nil {
-		return err
+	if ff.Deploy != nil && ff.Deploy.OCI != nil && ff.Deploy.OCI.PBF != nil {
+		if err := function.ResolvePBFMemoryForListing(p.provider, fn, ff.Deploy.OCI.PBF.ListingID); err != nil {
+			return nil, err
+		}
 	}
-	fmt.Printf("Image digest is %s\n", imageDigest)
-	repositoryName, err := getRepositoryName(funcfile)
-	if err != nil {
-		return err
+	if detachedSeconds > 0 && common.IsOracleProvider(p.provider) {
+		function.SetDetachedTimeoutAnnotation(fn, detachedSeconds)
 	}
-	fmt.Printf("Image belongs to repository %s\n", repositoryName)
-	artifactsClient, err := artifacts.NewArtifactsClientWithConfigurationProvider(oracleProvider.ConfigurationProvider)
-	if err != nil {
-		return err
+	if common.IsOracleProvider(p.provider) && ff.Deploy != nil && ff.Deploy.OCI != nil && ff.Deploy.OCI.DetachedMode != nil {
+		function.SetDestinationAnnotations(fn, ff.Deploy.OCI.DetachedMode.OnSuccess, ff.Deploy.OCI.DetachedMode.OnFailure)
 	}
-	region := getRegion(oracleProvider)
-	artifactsClient.SetRegion(region)
-	imageId, compartmentId, err := getImageId(artifactsClient, "", signingDetails.ImageCompartmentId, repositoryName, imageDigest)
+	return fn, nil
+}
+
+// snapshotFunction returns the function with its triggers, so a deploy can be rolled back.
+// It returns nil when the triggers can't be listed.
+func snapshotFunction(client *v2Client.Fn, fn *models.Fn, errOut io.Writer) *common.FnSnapshot {
+	triggers, err := common.ListAllTriggersInFunc(client, fn)
 	if err != nil {
-		return err
+		fmt.Fprintf(errOut, "Warning: unable to record the triggers of %s, it can't be rolled back to its current version: %v\n", fn.Name, err)
+		return nil
 	}
-	signatureRequired, err := isSignatureRequired(artifactsClient, imageId, signingDetails)
+	return &common.FnSnapshot{Fn: fn, Triggers: triggers}
+}
+
+func getOracleProvider() (*oracle.OracleProvider, error) {
+	currentProvider, err := client.CurrentProvider()
 	if err != nil {
-		return err
+		return nil, err
 	}
-	if !signatureRequired {
-		fmt.Printf("Image %s is already signed by %s\n", funcfile.ImageNameV20180708(), signingDetails.KmsKeyId)
-		return nil
+	if oracleProvider, ok := currentProvider.(*oracle.OracleProvider); ok {
+		return oracleProvider, nil
 	}
-	message, signature, err := createImageSignature(oracleProvider, region, imageDigest, repositoryName, funcfile.SigningDetails)
-	if err != nil {
+	return nil, nil
+}
+
+func (p *deploycmd) signImage(funcfilePath string, funcfile *common.FuncFileV20180708) error {
+	signingDetails := funcfile.SigningDetails.WithKeysRelativeTo(filepath.Dir(funcfilePath))
+	signer, err := newImageSigner(signingDetails, p.out, p.errOut)
+	if err != nil || signer == nil {
 		return err
 	}
-	if err = uploadImageSignature(artifactsClient, compartmentId, imageId, message, signature, funcfile.SigningDetails); err == nil {
-		fmt.Printf("Successfully signed and uploaded image signature for %s\n", funcfile.ImageNameV20180708())
+	fmt.Fprintf(p.out, "Signing image %s using %s...\n", funcfile.ImageNameV20180708(), signingKeyDescription(signingDetails))
+	imageDigest, err := getImageDigest(funcfile, p.out)
+	if err != nil {
+		return err
 	}
-	return err
+	fmt.Fprintf(p.out, "Image digest is %s\n", imageDigest)
+	return signer.sign(funcfile.ImageNameV20180708(), imageDigest)
 }
 
 func isSignatureConfigured(signingDetails common.SigningDetails) (bool, error) {
-	configured := signingDetails.SigningAlgorithm != "" && signingDetails.KmsKeyId != "" &&
-		signingDetails.ImageCompartmentId != "" && signingDetails.KmsKeyVersionId != ""
-	if !configured && (signingDetails.SigningAlgorithm != "" || signingDetails.KmsKeyId != "" ||
-		signingDetails.ImageCompartmentId != "" || signingDetails.KmsKeyVersionId != "") {
-		return false, fmt.Errorf("signing_details is missing values for [%s] in func.yaml", findMissingValues(signingDetails))
+	switch provider := signingDetails.SigningProvider(); provider {
+	case "":
+		return false, nil
+	case common.SigningProviderCosign:
+		if signingDetails.Key == "" {
+			return false, fmt.Errorf("signing_details is missing values for [key] in func.yaml")
+		}
+		return true, nil
+	case common.SigningProviderOCIKMS:
+		configured := signingDetails.SigningAlgorithm != "" && signingDetails.KmsKeyId != "" &&
+			signingDetails.ImageCompartmentId != "" && signingDetails.KmsKeyVersionId != ""
+		if !configured {
+			return false, fmt.Errorf("signing_details is missing values for [%s] in func.yaml", findMissingValues(signingDetails))
+		}
+		return true, nil
+	default:
+		return false, fmt.Errorf("unknown signing provider %q in func.yaml, use %s or %s", provider, common.SigningProviderOCIKMS, common.SigningProviderCosign)
 	}
-	return configured, nil
 }
 
 func getRegion(oracleProvider *oracle.OracleProvider) string {
@@ -613,42 +924,58 @@ func getRegion(oracleProvider *oracle.OracleProvider) string {
 }
 
 func getRepositoryName(ff *common.FuncFileV20180708) (string, error) {
-	parts := strings.Split(ff.ImageNameV20180708(), ":")
+	return repositoryNameOfImage(ff.ImageNameV20180708())
+}
+
+// repositoryNameOfImage returns the OCI Registry repository of an image tagged with a version
+func repositoryNameOfImage(image string) (string, error) {
+	parts := strings.Split(image, ":")
 	if len(parts) != 2 {
-		return "", fmt.Errorf("cannot parse image %s", ff.ImageNameV20180708())
+		return "", fmt.Errorf("cannot parse image %s", image)
 	}
 	pattern := regexp.MustCompile("(.*)ocir\\.([^/]*)/([^/]*)/(.*)")
 	parts = pattern.FindStringSubmatch(parts[0])
 	if len(parts) != 5 {
-		return "", fmt.Errorf("cannot parse registry for image %s", ff.ImageNameV20180708())
+		return "", fmt.Errorf("cannot parse registry for image %s", image)
 	}
 	return parts[4], nil
 }
 
-func getImageDigest(ff *common.FuncFileV20180708) (string, error) {
+func getImageDigest(ff *common.FuncFileV20180708, out io.Writer) (string, error) {
 	containerEngineType, err := common.GetContainerEngineType()
 	if err != nil {
 		return "", err
 	}
-	fmt.Printf("Fetching image digest for %s\n", ff.ImageNameV20180708())
-	parts := strings.Split(ff.ImageNameV20180708(), ":")
+	fmt.Fprintf(out, "Fetching image digest for %s\n", ff.ImageNameV20180708())
+	return lookupImageDigest(containerEngineType, ff.ImageNameV20180708())
+}
+
+// lookupImageDigest returns the registry digest of a pushed image from the local image store
+func lookupImageDigest(containerEngineType, imageName string) (string, error) {
+	if digest, ok := common.PushedImageDigest(imageName); ok {
+		return digest, nil
+	}
+	if config.IsDaemonlessContainerEngine(containerEngineType) {
+		return "", fmt.Errorf("%s did not report the digest of %s", containerEngineType, imageName)
+	}
+	parts := strings.Split(imageName, ":")
 	if len(parts) < 2 {
-		return "", fmt.Errorf("failed to parse image %s", ff.ImageNameV20180708())
+		return "", fmt.Errorf("failed to parse image %s", imageName)
 	}
 	image, tag := parts[0], parts[1]
 	imageDigests, err := exec.Command(containerEngineType, "images", "--digests", image, "--format", "{{.Tag}} {{.Digest}}").Output()
 	if err != nil {
-		return "", fmt.Errorf("error while listing image digests for %s, %s", ff.ImageNameV20180708(), err)
+		return "", fmt.Errorf("error while listing image digests for %s, %s", imageName, err)
 	}
 	cmd := exec.Command("awk", fmt.Sprintf("{if ($1==\"%s\") print $2}", tag))
 	cmd.Stdin = bytes.NewBuffer(imageDigests)
 	output, err := cmd.Output()
 	if err != nil {
-		return "", fmt.Errorf("error parsing image digest output for %s, %s", ff.ImageNameV20180708(), err)
+		return "", fmt.Errorf("error parsing image digest output for %s, %s", imageName, err)
 	}
 	imageDigest := strings.ReplaceAll(string(output), "\n", "")
 	if imageDigest == "" {
-		return "", fmt.Errorf("failed to fetch image digest for %s", ff.ImageNameV20180708())
+		return "", fmt.Errorf("failed to fetch image digest for %s", imageName)
 	}
 	return imageDigest, nil
 }
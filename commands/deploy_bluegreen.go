@@ -0,0 +1,231 @@
+/*
+ * Copyright (c) 2019, 2020 Oracle and/or its affiliates. All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package commands
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	common "github.com/fnproject/cli/common"
+	function "github.com/fnproject/cli/objects/fn"
+	trigger "github.com/fnproject/cli/objects/trigger"
+	v2Client "github.com/fnproject/fn_go/clientv2"
+	models "github.com/fnproject/fn_go/modelsv2"
+)
+
+const (
+	deployStrategyInPlace   = "inplace"
+	deployStrategyBlueGreen = "bluegreen"
+
+	httpTriggerType = "http"
+)
+
+var invalidFnNameChars = regexp.MustCompile(`[^a-zA-Z0-9_-]`)
+
+// blueGreenFnName returns the name of the function that runs a version of a function side by side
+// with the other versions, e.g. hello-0-0-2 for version 0.0.2 of hello.
+func blueGreenFnName(fnName, version string) string {
+	if version == "" {
+		return fnName
+	}
+	return fnName + "-" + invalidFnNameChars.ReplaceAllString(version, "-")
+}
+
+// deployBlueGreen deploys the new version of a function as a separate function, optionally invokes it
+// and then points the http triggers declared in func.yaml at it. The function that served the triggers
+// before is left in place, so switching back only needs the triggers to be moved again.
+func (p *deploycmd) deployBlueGreen(app *models.App, funcfilePath string, ff *common.FuncFileV20180708) error {
+	httpTriggers, err := p.blueGreenTriggers(ff)
+	if err != nil {
+		return err
+	}
+
+	fn, err := p.fnFromFuncFile(ff)
+	if err != nil {
+		return err
+	}
+	if fn.Image, err = p.enforceSignature(funcfilePath, ff, fn.Image); err != nil {
+		return err
+	}
+	fn.Name = blueGreenFnName(ff.Name, ff.Version)
+	fmt.Fprintf(p.out, "Deploying function %s using image %s alongside the current version...\n", fn.Name, fn.Image)
+
+	history := common.NewDeployHistoryEntry(p.provider, common.DeployHistoryActionDeploy, app.Name, fn.Name)
+	existing, err := function.GetFnByName(p.clientV2, app.ID, fn.Name)
+	if _, ok := err.(function.NameNotFoundError); ok {
+		if _, err := function.CreateFn(p.clientV2, app.ID, fn); err != nil {
+			return err
+		}
+	} else if err != nil {
+		return err
+	} else {
+		// the same version was deployed before, e.g. with --no-bump
+		history.OldImage = existing.Image
+		history.Previous = snapshotFunction(p.clientV2, existing, p.errOut)
+		if err := function.PutFn(p.clientV2, existing.ID, fn); err != nil {
+			return err
+		}
+	}
+	newFn, err := function.GetFnByName(p.clientV2, app.ID, fn.Name)
+	if err != nil {
+		return err
+	}
+	history.NewImage = newFn.Image
+	history.GitCommit = common.GitCommitOf(filepath.Dir(funcfilePath))
+	if err := common.NewDefaultDeployHistory().Append(history); err != nil {
+		fmt.Fprintf(p.errOut, "Warning: unable to record deploy history: %v\n", err)
+	}
+
+	if p.runsSmokeTest(ff) {
+		fmt.Fprintf(p.out, "Running smoke test against %s before switching traffic to it...\n", newFn.Name)
+		if err := p.smokeTest(newFn, ff); err != nil {
+			return fmt.Errorf("smoke test of %s failed, triggers were not switched: %v", newFn.Name, err)
+		}
+	}
+
+	return p.switchTriggers(app, newFn, httpTriggers)
+}
+
+// switchBlueGreen points the http triggers of func.yaml back at a version deployed before with
+// --strategy bluegreen, without building or deploying anything.
+func (p *deploycmd) switchBlueGreen(app *models.App, ff *common.FuncFileV20180708, version string) error {
+	httpTriggers, err := p.blueGreenTriggers(ff)
+	if err != nil {
+		return err
+	}
+	name := blueGreenFnName(ff.Name, version)
+	fn, err := function.GetFnByName(p.clientV2, app.ID, name)
+	if _, ok := err.(function.NameNotFoundError); ok {
+		return fmt.Errorf("version %s of %s was not deployed with --strategy %s, function %s does not exist", version, ff.Name, deployStrategyBlueGreen, name)
+	} else if err != nil {
+		return err
+	}
+	fmt.Fprintf(p.out, "Switching the triggers of %s to %s using image %s...\n", ff.Name, fn.Name, fn.Image)
+	return p.switchTriggers(app, fn, httpTriggers)
+}
+
+// blueGreenTriggers returns the http triggers of func.yaml, the only ones a blue-green deploy switches
+func (p *deploycmd) blueGreenTriggers(ff *common.FuncFileV20180708) ([]common.Trigger, error) {
+	var httpTriggers []common.Trigger
+	for _, t := range ff.Triggers {
+		if t.Type != httpTriggerType {
+			fmt.Fprintf(p.errOut, "Warning: --strategy %s only switches http triggers, trigger %s of type %s is left unchanged\n", deployStrategyBlueGreen, t.Name, t.Type)
+			continue
+		}
+		httpTriggers = append(httpTriggers, t)
+	}
+	if len(httpTriggers) == 0 {
+		return nil, fmt.Errorf("--strategy %s needs an http trigger in the triggers section of func.yaml to switch", deployStrategyBlueGreen)
+	}
+	return httpTriggers, nil
+}
+
+// switchTriggers points the http triggers at newFn and reports where each of them pointed before
+func (p *deploycmd) switchTriggers(app *models.App, newFn *models.Fn, httpTriggers []common.Trigger) error {
+	appTriggers, err := common.ListAllTriggersInApp(p.clientV2, app)
+	if err != nil {
+		return err
+	}
+	for _, t := range httpTriggers {
+		previous, err := switchTrigger(p.clientV2, app.ID, newFn, t, appTriggers)
+		if err != nil {
+			return err
+		}
+		if previous == "" {
+			fmt.Fprintf(p.out, "Created trigger %s (%s) on %s\n", t.Name, t.Source, newFn.Name)
+		} else if previous == newFn.ID {
+			fmt.Fprintf(p.out, "Trigger %s (%s) already routes to %s\n", t.Name, t.Source, newFn.Name)
+		} else {
+			fmt.Fprintf(p.out, "Switched trigger %s (%s) to %s, the previous function %s is kept\n", t.Name, t.Source, newFn.Name, fnNameByID(p.clientV2, app, previous))
+		}
+	}
+	return nil
+}
+
+// switchTrigger points the app trigger with the source of t at fn, creating it when the app has no such
+// trigger. It returns the ID of the function the trigger pointed at before, empty when it was created.
+func switchTrigger(client *v2Client.Fn, appID string, fn *models.Fn, t common.Trigger, appTriggers []*models.Trigger) (string, error) {
+	// sources are unique within an app, so they identify the trigger whichever function it points at
+	current := findTriggerBySource(appTriggers, t.Type, t.Source)
+	trig := &models.Trigger{
+		AppID:  appID,
+		FnID:   fn.ID,
+		Name:   t.Name,
+		Type:   t.Type,
+		Source: t.Source,
+	}
+	if current == nil {
+		return "", trigger.CreateTrigger(client, trig)
+	}
+	if current.FnID == fn.ID {
+		return current.FnID, nil
+	}
+
+	trig.ID = current.ID
+	// annotations under fnproject.io/ belong to the server, the new trigger only keeps those of the user
+	trig.Annotations = withRemovedAnnotations(current.Annotations, nil)
+	if err := trigger.PutTrigger(client, trig); err != nil {
+		// servers that don't allow a trigger to move between functions get a new trigger instead. Sources
+		// are unique, so the old trigger has to go first and the source is briefly unrouted while it is
+		// replaced. If the new trigger can't be created, the old one is put back.
+		if err := common.DeleteTriggers(nil, client, []*models.Trigger{current}); err != nil {
+			return "", err
+		}
+		trig.ID = ""
+		if err := trigger.CreateTrigger(client, trig); err != nil {
+			restored := &models.Trigger{
+				AppID:       current.AppID,
+				FnID:        current.FnID,
+				Name:        current.Name,
+				Type:        current.Type,
+				Source:      current.Source,
+				Annotations: withRemovedAnnotations(current.Annotations, nil),
+			}
+			if rerr := trigger.CreateTrigger(client, restored); rerr != nil {
+				return "", fmt.Errorf("trigger %s could not be created on %s: %v, and restoring it on its previous function failed: %v", t.Name, fn.Name, err, rerr)
+			}
+			return "", fmt.Errorf("trigger %s could not be created on %s, it still routes to its previous function: %v", t.Name, fn.Name, err)
+		}
+	}
+	return current.FnID, nil
+}
+
+func findTriggerBySource(triggers []*models.Trigger, triggerType, source string) *models.Trigger {
+	normalized := "/" + strings.TrimPrefix(source, "/")
+	for _, t := range triggers {
+		if t.Type == triggerType && "/"+strings.TrimPrefix(t.Source, "/") == normalized {
+			return t
+		}
+	}
+	return nil
+}
+
+// fnNameByID returns the name of a function of the app, or its ID if it can't be found
+func fnNameByID(client *v2Client.Fn, app *models.App, fnID string) string {
+	fns, err := common.ListAllFnsInApp(client, app)
+	if err != nil {
+		return fnID
+	}
+	for _, fn := range fns {
+		if fn.ID == fnID {
+			return fn.Name
+		}
+	}
+	return fnID
+}
@@ -0,0 +1,93 @@
+package commands
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/fnproject/cli/common"
+	"github.com/fnproject/fn_go/clientv2"
+	"github.com/fnproject/fn_go/modelsv2"
+)
+
+func TestBlueGreenFnName(t *testing.T) {
+	for _, tc := range []struct {
+		name, version, want string
+	}{
+		{"hello", "0.0.2", "hello-0-0-2"},
+		{"hello", "1.2.3-rc.1", "hello-1-2-3-rc-1"},
+		{"hello", "", "hello"},
+	} {
+		if got := blueGreenFnName(tc.name, tc.version); got != tc.want {
+			t.Fatalf("blueGreenFnName(%q, %q) = %q, expected %q", tc.name, tc.version, got, tc.want)
+		}
+	}
+}
+
+func TestFindTriggerBySource(t *testing.T) {
+	triggers := []*modelsv2.Trigger{
+		{ID: "t1", Name: "hello", Type: "http", Source: "/hello", FnID: "fn1"},
+		{ID: "t2", Name: "world", Type: "http", Source: "world", FnID: "fn2"},
+	}
+	if got := findTriggerBySource(triggers, "http", "hello"); got == nil || got.ID != "t1" {
+		t.Fatalf("expected trigger t1, got %+v", got)
+	}
+	if got := findTriggerBySource(triggers, "http", "/world"); got == nil || got.ID != "t2" {
+		t.Fatalf("expected trigger t2, got %+v", got)
+	}
+	if got := findTriggerBySource(triggers, "http", "/other"); got != nil {
+		t.Fatalf("expected no trigger, got %+v", got)
+	}
+}
+
+func TestSwitchTriggerRestoresTriggerWhenRecreateFails(t *testing.T) {
+	var created []modelsv2.Trigger
+	var deleted []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.Method == http.MethodPut:
+			w.WriteHeader(http.StatusConflict)
+			w.Write([]byte(`{"message":"fn_id can not be changed"}`))
+		case r.Method == http.MethodDelete:
+			deleted = append(deleted, strings.TrimPrefix(r.URL.Path, "/v2/triggers/"))
+			w.WriteHeader(http.StatusNoContent)
+		case r.Method == http.MethodPost:
+			var trig modelsv2.Trigger
+			json.NewDecoder(r.Body).Decode(&trig)
+			created = append(created, trig)
+			if trig.FnID == "new" {
+				w.WriteHeader(http.StatusBadRequest)
+				w.Write([]byte(`{"message":"invalid trigger"}`))
+				return
+			}
+			json.NewEncoder(w).Encode(trig)
+		}
+	}))
+	defer srv.Close()
+	u, _ := url.Parse(srv.URL)
+	client := clientv2.NewHTTPClientWithConfig(nil, clientv2.DefaultTransportConfig().WithHost(u.Host).WithSchemes([]string{"http"}))
+
+	current := &modelsv2.Trigger{ID: "t1", AppID: "app", FnID: "old", Name: "hello", Type: "http", Source: "/hello",
+		Annotations: map[string]interface{}{"fnproject.io/trigger/httpEndpoint": "http://localhost/t/app/hello", "team": "a"}}
+	_, err := switchTrigger(client, "app", &modelsv2.Fn{ID: "new", Name: "hello-0-0-2"},
+		common.Trigger{Name: "hello", Type: "http", Source: "/hello"}, []*modelsv2.Trigger{current})
+	if err == nil {
+		t.Fatal("expected an error when the trigger can't be created on the new function")
+	}
+	if len(deleted) != 1 || deleted[0] != "t1" {
+		t.Fatalf("expected the old trigger to be deleted, got %v", deleted)
+	}
+	if len(created) != 2 || created[1].FnID != "old" || created[1].Source != "/hello" || created[1].ID != "" {
+		t.Fatalf("expected the old trigger to be restored, got %+v", created)
+	}
+	if _, ok := created[0].Annotations["fnproject.io/trigger/httpEndpoint"]; ok || created[0].Annotations["team"] != "a" {
+		t.Fatalf("expected the trigger of the new function to keep only the user annotations, got %v", created[0].Annotations)
+	}
+	if _, ok := created[1].Annotations["fnproject.io/trigger/httpEndpoint"]; ok || created[1].Annotations["team"] != "a" {
+		t.Fatalf("expected only the user annotations to be restored, got %v", created[1].Annotations)
+	}
+}
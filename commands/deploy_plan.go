@@ -0,0 +1,214 @@
+/*
+ * Copyright (c) 2019, 2020 Oracle and/or its affiliates. All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package commands
+
+import (
+	"fmt"
+	"strings"
+
+	common "github.com/fnproject/cli/common"
+	apps "github.com/fnproject/cli/objects/app"
+	function "github.com/fnproject/cli/objects/fn"
+	models "github.com/fnproject/fn_go/modelsv2"
+	"github.com/urfave/cli"
+)
+
+// planApp looks up the app a deploy would create or update and records the change in the plan.
+// An app that would be created is returned without an ID.
+func (p *deploycmd) planApp(appName string, appfApp *models.App, hasAppFile bool) (*models.App, error) {
+	app, err := apps.GetAppByName(p.clientV2, appName)
+	if _, ok := err.(apps.NameNotFoundError); ok && p.createApp {
+		p.plan.changes = append(p.plan.changes, applyChange{
+			action: applyCreate, kind: applyKindApp, name: appName,
+			fields: diffApp(appfApp, &models.App{}),
+			app:    appfApp,
+		})
+		return appfApp, nil
+	} else if err != nil {
+		return nil, err
+	}
+	if hasAppFile {
+		if fields := withoutConfigRemovals(diffApp(appfApp, app)); len(fields) > 0 {
+			p.plan.changes = append(p.plan.changes, applyChange{
+				action: applyUpdate, kind: applyKindApp, name: appName,
+				fields: fields,
+				app:    appfApp,
+			})
+		}
+	}
+	return app, nil
+}
+
+// planFunc prints the version bump, build and push a deploy of the function would make and records
+// its function and trigger changes in the plan. Nothing is built, pushed or changed on the server.
+func (p *deploycmd) planFunc(c *cli.Context, app *models.App, funcfilePath string, ff *common.FuncFileV20180708) error {
+	isPBFDeploy := ff.Deploy != nil && ff.Deploy.OCI != nil && ff.Deploy.OCI.PBF != nil && strings.TrimSpace(ff.Deploy.OCI.PBF.ListingID) != ""
+
+	fmt.Fprintf(p.out, "Planning deploy of %s to app: %s\n", ff.Name, app.Name)
+	if !p.noBump {
+		version, err := common.NextVersionV20180708(ff, common.Patch)
+		if err != nil {
+			return err
+		}
+		from := ff.Version
+		if from == "" {
+			from = applyUnsetValue
+		}
+		fmt.Fprintf(p.out, "  Would bump the version in %s from %s to %s\n", funcfilePath, from, version)
+		ff.Version = version
+	}
+
+	if !isPBFDeploy {
+		image := ff.ImageNameV20180708()
+		if !p.local && !p.localDebug {
+			shape := app.Shape
+			if shape == "" {
+				shape = common.DefaultAppShape
+			}
+			if _, ok := common.ShapeMap[shape]; !ok {
+				return fmt.Errorf("Invalid application : %s shape: %s", app.Name, shape)
+			}
+			if oracleProvider, _ := getOracleProvider(); oracleProvider != nil && oracleProvider.ImageCompartmentID != "" {
+				if repositoryName, err := getRepositoryName(ff); err == nil {
+					fmt.Fprintf(p.out, "  Would create repository %s in compartment %s if it doesn't exist\n", repositoryName, oracleProvider.ImageCompartmentID)
+				}
+			}
+			fmt.Fprintf(p.out, "  Would build image %s for shape %s\n", image, shape)
+			fmt.Fprintf(p.out, "  Would push image %s\n", image)
+			if signatureConfigured, err := isSignatureConfigured(ff.SigningDetails); err != nil {
+				return err
+			} else if signatureConfigured {
+				fmt.Fprintf(p.out, "  Would sign image %s using %s\n", image, signingKeyDescription(ff.SigningDetails))
+			}
+		} else {
+			if platforms := p.buildOptions.ForFuncFileV20180708(ff).Platforms; len(platforms) > 0 {
+				fmt.Fprintf(p.out, "  Would build image %s for %s without pushing it\n", image, strings.Join(platforms, ", "))
+			} else {
+				fmt.Fprintf(p.out, "  Would build image %s without pushing it\n", image)
+			}
+		}
+		if ff.SignaturePolicyV20180708().Enforce {
+			fmt.Fprintf(p.out, "  Would refuse image %s unless it is signed by a trusted key\n", image)
+		}
+		attestations := p.buildOptions.ForFuncFileV20180708(ff).Attestations
+		if err := attestations.Validate(); err != nil {
+			return err
+		}
+		if attestations.SBOM != "" {
+			fmt.Fprintf(p.out, "  Would generate a %s SBOM of image %s\n", attestations.SBOM, image)
+		}
+		if attestations.Provenance {
+			fmt.Fprintf(p.out, "  Would write the build provenance of image %s\n", image)
+		}
+		if !attestations.IsEmpty() && attestations.Push && !p.local && !p.localDebug {
+			fmt.Fprintf(p.out, "  Would attach the attestations to image %s\n", image)
+		}
+	}
+
+	if p.runsSmokeTest(ff) {
+		if ff.Smoke != nil && ff.Smoke.Rollback {
+			fmt.Fprintf(p.out, "  Would run the smoke test and roll %s back if it fails\n", ff.Name)
+		} else {
+			fmt.Fprintf(p.out, "  Would run the smoke test against %s\n", ff.Name)
+		}
+	}
+
+	fn, err := p.fnFromFuncFile(ff)
+	if err != nil {
+		return err
+	}
+	fn.Name = ff.Name
+	var triggers []*models.Trigger
+	for _, t := range ff.Triggers {
+		triggers = append(triggers, &models.Trigger{Name: t.Name, Type: t.Type, Source: t.Source})
+	}
+
+	if app.ID == "" {
+		// the app is created by this deploy, so is the function
+		p.plan.changes = append(p.plan.changes, deployChanges(ff.Name, fn, triggers, nil, nil)...)
+		return nil
+	}
+	remote, err := function.GetFnByName(p.clientV2, app.ID, ff.Name)
+	if _, ok := err.(function.NameNotFoundError); ok {
+		p.plan.changes = append(p.plan.changes, deployChanges(ff.Name, fn, triggers, nil, nil)...)
+		return nil
+	} else if err != nil {
+		return err
+	}
+	remoteTriggers, err := common.ListAllTriggersInFunc(p.clientV2, remote)
+	if err != nil {
+		return err
+	}
+	p.plan.changes = append(p.plan.changes, deployChanges(ff.Name, fn, triggers, remote, remoteTriggers)...)
+	return nil
+}
+
+// deployChanges returns the changes a deploy makes to a function and its triggers. Unlike apply,
+// deploy keeps config keys and triggers that are no longer declared in func.yaml, so those are not
+// changes. remote is nil when the function doesn't exist yet.
+func deployChanges(fnName string, local *models.Fn, localTriggers []*models.Trigger, remote *models.Fn, remoteTriggers []*models.Trigger) []applyChange {
+	var changes []applyChange
+	if remote == nil {
+		changes = append(changes, applyChange{
+			action: applyCreate, kind: applyKindFunction, name: fnName,
+			fields: diffFn(local, &models.Fn{}),
+			fn:     local,
+		})
+	} else if fields := withoutConfigRemovals(diffFn(local, remote)); len(fields) > 0 {
+		changes = append(changes, applyChange{
+			action: applyUpdate, kind: applyKindFunction, name: fnName,
+			fields: fields,
+			fn:     local,
+		})
+	}
+
+	existing := map[string]*models.Trigger{}
+	for _, t := range remoteTriggers {
+		existing[t.Name] = t
+	}
+	for _, t := range localTriggers {
+		rt, ok := existing[t.Name]
+		if !ok {
+			changes = append(changes, applyChange{
+				action: applyCreate, kind: applyKindTrigger, name: fnName + "/" + t.Name,
+				fields:  diffTrigger(t, &models.Trigger{}),
+				trigger: t, fnName: fnName,
+			})
+			continue
+		}
+		if fields := diffTrigger(t, rt); len(fields) > 0 {
+			changes = append(changes, applyChange{
+				action: applyUpdate, kind: applyKindTrigger, name: fnName + "/" + t.Name,
+				fields:  fields,
+				trigger: t, fnName: fnName,
+			})
+		}
+	}
+	return changes
+}
+
+// withoutConfigRemovals drops the config keys that only exist remotely, deploy leaves them in place
+func withoutConfigRemovals(fields []fieldChange) []fieldChange {
+	var kept []fieldChange
+	for _, f := range fields {
+		if strings.HasPrefix(f.field, "config.") && f.to == applyUnsetValue {
+			continue
+		}
+		kept = append(kept, f)
+	}
+	return kept
+}
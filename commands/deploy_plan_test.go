@@ -0,0 +1,62 @@
+package commands
+
+import (
+	"testing"
+
+	models "github.com/fnproject/fn_go/modelsv2"
+)
+
+func TestDeployChangesNewFunction(t *testing.T) {
+	local := &models.Fn{Name: "hello", Image: "hello:0.0.1", Memory: 256}
+	triggers := []*models.Trigger{{Name: "hello", Type: "http", Source: "/hello"}}
+
+	changes := deployChanges("hello", local, triggers, nil, nil)
+	if len(changes) != 2 {
+		t.Fatalf("expected a function and a trigger to be created, got %+v", changes)
+	}
+	if changes[0].action != applyCreate || changes[0].kind != applyKindFunction {
+		t.Fatalf("unexpected change %+v", changes[0])
+	}
+	if changes[1].action != applyCreate || changes[1].kind != applyKindTrigger || changes[1].name != "hello/hello" {
+		t.Fatalf("unexpected change %+v", changes[1])
+	}
+}
+
+func TestDeployChangesExistingFunction(t *testing.T) {
+	local := &models.Fn{Name: "hello", Image: "hello:0.0.2", Config: map[string]string{"A": "1"}}
+	remote := &models.Fn{ID: "fn1", Name: "hello", Image: "hello:0.0.1", Memory: 128, Timeout: int32Ptr(30),
+		Config: map[string]string{"A": "1", "OLD": "x"}}
+	triggers := []*models.Trigger{
+		{Name: "same", Type: "http", Source: "/same"},
+		{Name: "moved", Type: "http", Source: "/new"},
+	}
+	remoteTriggers := []*models.Trigger{
+		{ID: "t1", Name: "same", Type: "http", Source: "/same"},
+		{ID: "t2", Name: "moved", Type: "http", Source: "/old"},
+		{ID: "t3", Name: "undeclared", Type: "http", Source: "/undeclared"},
+	}
+
+	changes := deployChanges("hello", local, triggers, remote, remoteTriggers)
+	if len(changes) != 2 {
+		t.Fatalf("expected the function and one trigger to be updated, got %+v", changes)
+	}
+	fnChange := changes[0]
+	if fnChange.action != applyUpdate || fnChange.kind != applyKindFunction {
+		t.Fatalf("unexpected change %+v", fnChange)
+	}
+	// deploy keeps remote config keys, unset memory and timeouts, so only the image changes
+	if len(fnChange.fields) != 1 || fnChange.fields[0].field != "image" {
+		t.Fatalf("expected only the image to change, got %+v", fnChange.fields)
+	}
+	if changes[1].action != applyUpdate || changes[1].name != "hello/moved" {
+		t.Fatalf("unexpected change %+v", changes[1])
+	}
+}
+
+func TestDeployChangesUnchanged(t *testing.T) {
+	local := &models.Fn{Name: "hello", Image: "hello:0.0.1"}
+	remote := &models.Fn{ID: "fn1", Name: "hello", Image: "hello:0.0.1"}
+	if changes := deployChanges("hello", local, nil, remote, nil); len(changes) != 0 {
+		t.Fatalf("expected no changes, got %+v", changes)
+	}
+}
@@ -0,0 +1,101 @@
+/*
+ * Copyright (c) 2019, 2020 Oracle and/or its affiliates. All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package commands
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+
+	client "github.com/fnproject/cli/client"
+	common "github.com/fnproject/cli/common"
+	function "github.com/fnproject/cli/objects/fn"
+	models "github.com/fnproject/fn_go/modelsv2"
+)
+
+// runsSmokeTest reports whether a deploy of the func file checks the function once it is updated
+func (p *deploycmd) runsSmokeTest(ff *common.FuncFileV20180708) bool {
+	if p.skipSmoke {
+		return false
+	}
+	return p.smoke || ff.Smoke != nil
+}
+
+// smokeTestDeploy runs the smoke test against a function that was just updated. When the test fails
+// and the smoke section asks for it, the function is rolled back to how it was before the deploy.
+func (p *deploycmd) smokeTestDeploy(app *models.App, ff *common.FuncFileV20180708, previous *common.FnSnapshot) error {
+	fn, err := function.GetFnByName(p.clientV2, app.ID, ff.Name)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(p.out, "Running smoke test against %s...\n", ff.Name)
+	testErr := p.smokeTest(fn, ff)
+	if testErr == nil {
+		fmt.Fprintf(p.out, "Smoke test of %s passed\n", ff.Name)
+		return nil
+	}
+	if ff.Smoke == nil || !ff.Smoke.Rollback {
+		return fmt.Errorf("smoke test of %s failed: %v", ff.Name, testErr)
+	}
+	if previous == nil || previous.Fn == nil {
+		return fmt.Errorf("smoke test of %s failed and it can't be rolled back, it didn't exist or its triggers couldn't be listed before the deploy: %v", ff.Name, testErr)
+	}
+
+	fmt.Fprintf(p.out, "Smoke test of %s failed, rolling it back to %s...\n", ff.Name, previous.Fn.Image)
+	if err := rollbackFunction(p.clientV2, p.provider, app, fn, previous, p.errOut); err != nil {
+		return fmt.Errorf("smoke test of %s failed: %v, rolling it back also failed: %v", ff.Name, testErr, err)
+	}
+	return fmt.Errorf("smoke test of %s failed, it was rolled back to %s: %v", ff.Name, previous.Fn.Image, testErr)
+}
+
+// smokeTest invokes a function with the smoke test of its func file. Without a smoke section the function
+// is called with an empty body and must respond with a 2xx status.
+func (p *deploycmd) smokeTest(fn *models.Fn, ff *common.FuncFileV20180708) error {
+	smoke := ff.Smoke
+	if smoke == nil {
+		smoke = &common.SmokeTest{}
+	}
+	invokeURL, ok := fn.Annotations[FnInvokeEndpointAnnotation].(string)
+	if !ok {
+		return errors.New("Fn invoke url annotation not present, " + FnInvokeEndpointAnnotation)
+	}
+	payload, err := smoke.PayloadBody()
+	if err != nil {
+		return err
+	}
+	timeout, err := smoke.TimeoutDuration()
+	if err != nil {
+		return err
+	}
+	contentType := smoke.ContentType
+	if contentType == "" {
+		contentType = ff.Content_type
+	}
+
+	status, body, err := invokeWithTimeout(func(req client.InvokeRequest) (*http.Response, error) {
+		return invokeFunction(p.provider, req)
+	}, client.InvokeRequest{
+		URL:         invokeURL,
+		Content:     bytes.NewReader(payload),
+		ContentType: contentType,
+	}, timeout)
+	if err != nil {
+		return err
+	}
+	return smoke.Check(status, body)
+}
@@ -0,0 +1,128 @@
+package commands
+
+import (
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	cliClient "github.com/fnproject/cli/client"
+	"github.com/fnproject/cli/common"
+	"github.com/fnproject/fn_go/modelsv2"
+	"github.com/fnproject/fn_go/provider"
+)
+
+func TestSmokeTestWithoutSmokeSection(t *testing.T) {
+	restore := stubInvokeCommandDependencies(t)
+	defer restore()
+
+	status := http.StatusOK
+	var invokedURL string
+	invokeFunction = func(_ provider.Provider, req cliClient.InvokeRequest) (*http.Response, error) {
+		invokedURL = req.URL
+		return &http.Response{StatusCode: status, Header: http.Header{}, Body: io.NopCloser(strings.NewReader("boom\n"))}, nil
+	}
+
+	p := &deploycmd{provider: testInvokeProvider(t)}
+	ff := &common.FuncFileV20180708{Name: "hello"}
+	fn := &modelsv2.Fn{Name: "hello-0-0-2", Annotations: map[string]interface{}{FnInvokeEndpointAnnotation: "https://invoke.example.com/fn"}}
+	if err := p.smokeTest(fn, ff); err != nil {
+		t.Fatal(err)
+	}
+	if invokedURL != "https://invoke.example.com/fn" {
+		t.Fatalf("expected the new function to be invoked, got %q", invokedURL)
+	}
+
+	status = http.StatusBadGateway
+	err := p.smokeTest(fn, ff)
+	if err == nil || !strings.Contains(err.Error(), "502") || !strings.Contains(err.Error(), "boom") {
+		t.Fatalf("expected a failed smoke test, got %v", err)
+	}
+
+	if err := p.smokeTest(&modelsv2.Fn{Name: "hello"}, ff); err == nil {
+		t.Fatal("expected an error without an invoke endpoint")
+	}
+}
+
+func TestSmokeTestUsesSmokeSection(t *testing.T) {
+	restore := stubInvokeCommandDependencies(t)
+	defer restore()
+
+	var got cliClient.InvokeRequest
+	var payload string
+	invokeFunction = func(_ provider.Provider, req cliClient.InvokeRequest) (*http.Response, error) {
+		got = req
+		b, _ := ioutil.ReadAll(req.Content)
+		payload = string(b)
+		return &http.Response{StatusCode: http.StatusAccepted, Header: http.Header{}, Body: io.NopCloser(strings.NewReader(`{"message":"Hello Bob"}`))}, nil
+	}
+
+	p := &deploycmd{provider: testInvokeProvider(t)}
+	fn := &modelsv2.Fn{Name: "hello", Annotations: map[string]interface{}{FnInvokeEndpointAnnotation: "https://invoke.example.com/fn"}}
+	ff := &common.FuncFileV20180708{
+		Name:         "hello",
+		Content_type: "text/plain",
+		Smoke: &common.SmokeTest{
+			Payload:      map[interface{}]interface{}{"name": "Bob"},
+			ContentType:  "application/json",
+			ExpectStatus: http.StatusAccepted,
+			ExpectBody:   `Hello \w+`,
+		},
+	}
+	if err := p.smokeTest(fn, ff); err != nil {
+		t.Fatal(err)
+	}
+	if payload != `{"name":"Bob"}` || got.ContentType != "application/json" {
+		t.Fatalf("unexpected smoke request %q with content type %q", payload, got.ContentType)
+	}
+
+	ff.Smoke.ExpectBody = "Goodbye"
+	if err := p.smokeTest(fn, ff); err == nil {
+		t.Fatal("expected the body expression to fail the smoke test")
+	}
+}
+
+func TestSmokeTestTimeout(t *testing.T) {
+	restore := stubInvokeCommandDependencies(t)
+	defer restore()
+
+	release := make(chan struct{})
+	defer close(release)
+	invokeFunction = func(_ provider.Provider, req cliClient.InvokeRequest) (*http.Response, error) {
+		<-release
+		return nil, io.EOF
+	}
+
+	p := &deploycmd{provider: testInvokeProvider(t)}
+	fn := &modelsv2.Fn{Name: "hello", Annotations: map[string]interface{}{FnInvokeEndpointAnnotation: "https://invoke.example.com/fn"}}
+	ff := &common.FuncFileV20180708{Name: "hello", Smoke: &common.SmokeTest{Timeout: "10ms"}}
+
+	start := time.Now()
+	err := p.smokeTest(fn, ff)
+	if err == nil || !strings.Contains(err.Error(), "timed out") {
+		t.Fatalf("expected a timeout, got %v", err)
+	}
+	if time.Since(start) > 5*time.Second {
+		t.Fatal("smoke test did not honour its timeout")
+	}
+}
+
+func TestRunsSmokeTest(t *testing.T) {
+	withSmoke := &common.FuncFileV20180708{Smoke: &common.SmokeTest{}}
+	without := &common.FuncFileV20180708{}
+
+	if (&deploycmd{}).runsSmokeTest(without) {
+		t.Fatal("expected no smoke test without a smoke section or --smoke")
+	}
+	if !(&deploycmd{}).runsSmokeTest(withSmoke) {
+		t.Fatal("expected the smoke section to run")
+	}
+	if !(&deploycmd{smoke: true}).runsSmokeTest(without) {
+		t.Fatal("expected --smoke to run a smoke test")
+	}
+	if (&deploycmd{skipSmoke: true}).runsSmokeTest(withSmoke) {
+		t.Fatal("expected --skip-smoke to skip the smoke section")
+	}
+}
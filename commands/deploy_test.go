@@ -0,0 +1,38 @@
+package commands
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPrintDeployAllSummary(t *testing.T) {
+	var out bytes.Buffer
+	err := printAllFuncsSummary(&out, []allFuncsResult{
+		{name: "hello", image: "hello:0.0.2", duration: 3 * time.Second},
+		{name: "world", duration: time.Second, err: errors.New("error running docker build: exit status 1")},
+	}, "deploy", "deployed")
+	if err == nil || err.Error() != "1 of 2 functions failed to deploy" {
+		t.Fatalf("expected a failure count error, got %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("expected a header and 2 rows, got:\n%s", out.String())
+	}
+	if !strings.Contains(lines[1], "hello") || !strings.Contains(lines[1], "deployed") || !strings.Contains(lines[1], "hello:0.0.2") {
+		t.Fatalf("unexpected row for hello: %q", lines[1])
+	}
+	if !strings.Contains(lines[2], "world") || !strings.Contains(lines[2], "failed") || !strings.Contains(lines[2], "exit status 1") {
+		t.Fatalf("unexpected row for world: %q", lines[2])
+	}
+}
+
+func TestPrintDeployAllSummarySucceeds(t *testing.T) {
+	var out bytes.Buffer
+	if err := printAllFuncsSummary(&out, []allFuncsResult{{name: "hello", image: "hello:0.0.2"}}, "deploy", "deployed"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
@@ -0,0 +1,155 @@
+/*
+ * Copyright (c) 2019, 2020 Oracle and/or its affiliates. All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	common "github.com/fnproject/cli/common"
+	"github.com/fnproject/cli/config"
+	function "github.com/fnproject/cli/objects/fn"
+	"github.com/spf13/viper"
+	"github.com/urfave/cli"
+)
+
+// ListDeploymentsCommand returns the list deployments cli.command
+func ListDeploymentsCommand() cli.Command {
+	return cli.Command{
+		Name:     "deployments",
+		Aliases:  []string{"deployment", "deploys"},
+		Usage:    "List the deploys made from this machine",
+		Category: "MANAGEMENT COMMAND",
+		Description: "This command lists the deploys and rollbacks recorded by this CLI in the current context, oldest first, " +
+			"optionally limited to an app or a function.",
+		ArgsUsage: "[app-name] [function-name]",
+		Action:    listDeployments,
+		Flags: []cli.Flag{
+			cli.BoolFlag{
+				Name:  "all-contexts",
+				Usage: "List the deploys of every context instead of only the current one",
+			},
+			cli.StringFlag{
+				Name:  "output",
+				Usage: "Output format (json)",
+				Value: "",
+			},
+		},
+	}
+}
+
+// deployment is a deploy history entry as shown by list deployments
+type deployment struct {
+	Time        time.Time `json:"time"`
+	Action      string    `json:"action"`
+	Context     string    `json:"context"`
+	App         string    `json:"app"`
+	Function    string    `json:"function"`
+	OldImage    string    `json:"old_image,omitempty"`
+	NewImage    string    `json:"new_image,omitempty"`
+	ImageDigest string    `json:"image_digest,omitempty"`
+	GitCommit   string    `json:"git_commit,omitempty"`
+	User        string    `json:"user,omitempty"`
+}
+
+func listDeployments(c *cli.Context) error {
+	contextName := viper.GetString(config.CurrentContext)
+	if c.Bool("all-contexts") {
+		contextName = ""
+	}
+	entries, err := common.NewDefaultDeployHistory().AppEntries(contextName, c.Args().Get(0))
+	if err != nil {
+		return err
+	}
+	deployments := filterDeployments(entries, function.WithoutSlash(c.Args().Get(1)))
+
+	if strings.ToLower(c.String("output")) == "json" {
+		b, err := json.MarshalIndent(deployments, "", "    ")
+		if err != nil {
+			return err
+		}
+		fmt.Fprint(os.Stdout, string(b))
+		return nil
+	}
+	return printDeployments(os.Stdout, deployments)
+}
+
+// filterDeployments converts history entries to deployments, keeping those of fnName when it is set
+func filterDeployments(entries []common.DeployHistoryEntry, fnName string) []deployment {
+	deployments := []deployment{}
+	for _, e := range entries {
+		if fnName != "" && e.Key.FnName != fnName {
+			continue
+		}
+		deployments = append(deployments, deployment{
+			Time:        e.Time,
+			Action:      e.Action,
+			Context:     e.Key.Context,
+			App:         e.Key.AppName,
+			Function:    e.Key.FnName,
+			OldImage:    e.OldImage,
+			NewImage:    e.NewImage,
+			ImageDigest: e.ImageDigest,
+			GitCommit:   e.GitCommit,
+			User:        e.User,
+		})
+	}
+	return deployments
+}
+
+func printDeployments(out io.Writer, deployments []deployment) error {
+	w := tabwriter.NewWriter(out, 0, 8, 1, '\t', 0)
+	fmt.Fprint(w, "TIME", "\t", "ACTION", "\t", "CONTEXT", "\t", "APP", "\t", "FUNCTION", "\t", "OLD IMAGE", "\t", "NEW IMAGE", "\t", "DIGEST", "\t", "COMMIT", "\t", "USER", "\n")
+	for _, d := range deployments {
+		fmt.Fprint(w, d.Time.Local().Format(time.RFC3339), "\t", d.Action, "\t", d.Context, "\t", d.App, "\t", d.Function, "\t",
+			orDash(d.OldImage), "\t", orDash(d.NewImage), "\t", orDash(shortDigest(d.ImageDigest)), "\t", orDash(shortCommit(d.GitCommit)), "\t", orDash(d.User), "\n")
+	}
+	return w.Flush()
+}
+
+// shortDigest abbreviates a sha256 digest to the 12 characters commonly shown by container engines
+func shortDigest(digest string) string {
+	hex := strings.TrimPrefix(digest, "sha256:")
+	if len(hex) > 12 {
+		return "sha256:" + hex[:12]
+	}
+	return digest
+}
+
+// shortCommit abbreviates a git commit the way git log --oneline does, keeping a -dirty suffix
+func shortCommit(commit string) string {
+	hash, dirty := commit, ""
+	if strings.HasSuffix(commit, "-dirty") {
+		hash, dirty = strings.TrimSuffix(commit, "-dirty"), "-dirty"
+	}
+	if len(hash) > 7 {
+		hash = hash[:7]
+	}
+	return hash + dirty
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
@@ -0,0 +1,58 @@
+package commands
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fnproject/cli/common"
+)
+
+func TestFilterDeployments(t *testing.T) {
+	at := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	entries := []common.DeployHistoryEntry{
+		{Time: at, Action: common.DeployHistoryActionDeploy, Key: common.DeployStateKey{Context: "dev", AppName: "app", FnName: "hello"}, NewImage: "hello:0.0.2", User: "alice"},
+		{Time: at, Action: common.DeployHistoryActionDeploy, Key: common.DeployStateKey{Context: "dev", AppName: "app", FnName: "world"}, NewImage: "world:0.0.1"},
+	}
+
+	all := filterDeployments(entries, "")
+	if len(all) != 2 {
+		t.Fatalf("expected 2 deployments, got %d", len(all))
+	}
+	hello := filterDeployments(entries, "hello")
+	if len(hello) != 1 || hello[0].Function != "hello" || hello[0].App != "app" || hello[0].Context != "dev" || hello[0].User != "alice" {
+		t.Fatalf("unexpected deployments %+v", hello)
+	}
+	if none := filterDeployments(nil, ""); none == nil {
+		t.Fatal("expected an empty list rather than nil, so json output is []")
+	}
+}
+
+func TestPrintDeployments(t *testing.T) {
+	var out bytes.Buffer
+	err := printDeployments(&out, []deployment{{
+		Time:        time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC),
+		Action:      common.DeployHistoryActionDeploy,
+		App:         "app",
+		Function:    "hello",
+		NewImage:    "hello:0.0.2",
+		ImageDigest: "sha256:0123456789abcdef0123456789abcdef",
+		GitCommit:   "0123456789abcdef-dirty",
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 2 || !strings.HasPrefix(lines[0], "TIME") {
+		t.Fatalf("unexpected output:\n%s", out.String())
+	}
+	for _, want := range []string{"hello:0.0.2", "sha256:0123456789ab", "0123456-dirty"} {
+		if !strings.Contains(lines[1], want) {
+			t.Fatalf("expected %q in %q", want, lines[1])
+		}
+	}
+	if strings.Contains(lines[1], "0123456789abcdef0") {
+		t.Fatalf("expected the digest to be abbreviated in %q", lines[1])
+	}
+}
@@ -0,0 +1,241 @@
+/*
+ * Copyright (c) 2019, 2020 Oracle and/or its affiliates. All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	client "github.com/fnproject/cli/client"
+	common "github.com/fnproject/cli/common"
+	apps "github.com/fnproject/cli/objects/app"
+	function "github.com/fnproject/cli/objects/fn"
+	v2Client "github.com/fnproject/fn_go/clientv2"
+	models "github.com/fnproject/fn_go/modelsv2"
+	"github.com/urfave/cli"
+)
+
+// DiffCommand returns diff cli.command
+func DiffCommand() cli.Command {
+	cmd := diffcmd{}
+	return cli.Command{
+		Name:  "diff",
+		Usage: "\tShow the differences between a local function and the deployed function",
+		Before: func(cxt *cli.Context) error {
+			provider, err := client.CurrentProvider()
+			if err != nil {
+				return err
+			}
+			cmd.clientV2 = provider.APIClientv2()
+			return nil
+		},
+		Category: "DEVELOPMENT COMMANDS",
+		Description: "This command compares the image, memory, timeout, idle_timeout, config, annotations and triggers " +
+			"declared in func.yaml with the deployed function, printing every field that differs as `deployed -> local`. " +
+			"It exits with a non-zero status when the function has drifted.",
+		ArgsUsage: "[function-dir]",
+		Flags:     cmd.flags(),
+		Action:    cmd.diff,
+	}
+}
+
+type diffcmd struct {
+	clientV2 *v2Client.Fn
+	appName  string
+}
+
+func (d *diffcmd) flags() []cli.Flag {
+	return []cli.Flag{
+		cli.StringFlag{
+			Name:        "app",
+			Usage:       "App name the function is deployed to, defaults to the name in app.yaml",
+			Destination: &d.appName,
+		},
+		cli.StringFlag{
+			Name:  "working-dir,w",
+			Usage: "Specify the working directory of the function, must be the full path.",
+		},
+	}
+}
+
+// fnDrift is the difference between a local and a deployed function
+type fnDrift struct {
+	fields   []fieldChange
+	triggers []applyChange
+}
+
+func (d *fnDrift) empty() bool {
+	return len(d.fields) == 0 && len(d.triggers) == 0
+}
+
+func (d *diffcmd) diff(c *cli.Context) error {
+	dir := common.GetDir(c)
+	if path := c.Args().First(); path != "" {
+		dir = filepath.Join(dir, path)
+	}
+
+	_, ff, err := common.FindAndParseFuncFileV20180708(dir)
+	if err != nil {
+		return err
+	}
+	if ff.Name == "" {
+		ff.Name = filepath.Base(dir)
+	}
+
+	appName := d.appName
+	if appName == "" {
+		appName = diffAppName(dir)
+	}
+	if appName == "" {
+		return errors.New("App name must be provided, try `--app APP_NAME`")
+	}
+
+	local, err := localDiffFn(ff)
+	if err != nil {
+		return err
+	}
+
+	app, err := apps.GetAppByName(d.clientV2, appName)
+	if err != nil {
+		return err
+	}
+	fn, err := function.GetFnByName(d.clientV2, app.ID, ff.Name)
+	if err != nil {
+		return err
+	}
+	triggers, err := common.ListAllTriggersInFunc(d.clientV2, fn)
+	if err != nil {
+		return err
+	}
+
+	drift := computeFnDrift(local, &applyFn{fn: fn, triggers: triggers})
+	if drift.empty() {
+		fmt.Printf("Function %s matches the deployed function in app %s\n", ff.Name, appName)
+		return nil
+	}
+	fmt.Printf("Function %s differs from the deployed function in app %s (deployed -> local):\n", ff.Name, appName)
+	drift.print(os.Stdout)
+	return fmt.Errorf("function %s has drifted from its func.yaml", ff.Name)
+}
+
+// diffAppName looks for an app file in the function directory and then in its parent,
+// which is where `fn init` places functions of a multi-function app
+func diffAppName(dir string) string {
+	for _, d := range []string{dir, filepath.Dir(dir)} {
+		appf, err := common.LoadAppfile(d)
+		if err == nil && appf.Name != "" {
+			return appf.Name
+		}
+	}
+	return ""
+}
+
+// localDiffFn returns the function and triggers as they would be deployed from the func file
+func localDiffFn(ff *common.FuncFileV20180708) (*applyFn, error) {
+	fn := &models.Fn{Name: ff.Name}
+	if err := function.WithFuncFileV20180708(ff, fn); err != nil {
+		return nil, err
+	}
+	if ff.Version == "" {
+		// the image is only known once the function has been built
+		fn.Image = ""
+	}
+	local := &applyFn{fn: fn}
+	for _, t := range ff.Triggers {
+		local.triggers = append(local.triggers, &models.Trigger{
+			Name:   t.Name,
+			Type:   t.Type,
+			Source: t.Source,
+		})
+	}
+	return local, nil
+}
+
+// Defaults the functions server gives to the fields func.yaml leaves unset
+const (
+	defaultFnMemory      = 128
+	defaultFnTimeout     = 30
+	defaultFnIdleTimeout = 30
+)
+
+// computeFnDrift compares a local function with the deployed one, config keys and triggers
+// that only exist on the server are reported as drift too. Unlike apply, which leaves fields
+// that func.yaml doesn't set to the server, unset fields are compared with the server defaults.
+func computeFnDrift(local, remote *applyFn) *fnDrift {
+	name := local.fn.Name
+	withDefaults := *local
+	withDefaults.fn = fnWithServerDefaults(local.fn)
+	plan := computeApplyPlan(
+		&applyState{app: &models.App{}, fns: map[string]*applyFn{name: &withDefaults}},
+		&applyState{app: &models.App{}, fns: map[string]*applyFn{name: remote}},
+		true)
+
+	drift := &fnDrift{}
+	if local.fn.Image == "" && remote.fn.Image != "" {
+		drift.fields = append(drift.fields, fieldChange{field: "image", from: formatApplyString(remote.fn.Image), to: applyUnsetValue})
+	}
+	for _, ch := range plan.changes {
+		switch ch.kind {
+		case applyKindFunction:
+			drift.fields = append(drift.fields, ch.fields...)
+		case applyKindTrigger:
+			drift.triggers = append(drift.triggers, ch)
+		}
+	}
+	return drift
+}
+
+// fnWithServerDefaults returns the function with the server defaults for memory and timeouts it doesn't set
+func fnWithServerDefaults(fn *models.Fn) *models.Fn {
+	withDefaults := *fn
+	if withDefaults.Memory == 0 {
+		withDefaults.Memory = defaultFnMemory
+	}
+	if withDefaults.Timeout == nil {
+		timeout := int32(defaultFnTimeout)
+		withDefaults.Timeout = &timeout
+	}
+	if withDefaults.IdleTimeout == nil {
+		idleTimeout := int32(defaultFnIdleTimeout)
+		withDefaults.IdleTimeout = &idleTimeout
+	}
+	return &withDefaults
+}
+
+func (d *fnDrift) print(out io.Writer) {
+	for _, f := range d.fields {
+		fmt.Fprintf(out, "  %s: %s -> %s\n", f.field, f.from, f.to)
+	}
+	for _, t := range d.triggers {
+		switch t.action {
+		case applyCreate:
+			fmt.Fprintf(out, "  trigger %s: only declared locally\n", t.trigger.Name)
+		case applyDelete:
+			fmt.Fprintf(out, "  trigger %s: only deployed\n", t.trigger.Name)
+		case applyUpdate, applyReplace:
+			if t.action == applyReplace {
+				fmt.Fprintf(out, "  trigger %s: type changed, fn apply will replace it\n", t.trigger.Name)
+			}
+			for _, f := range t.fields {
+				fmt.Fprintf(out, "  trigger %s %s: %s -> %s\n", t.trigger.Name, f.field, f.from, f.to)
+			}
+		}
+	}
+}
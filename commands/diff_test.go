@@ -0,0 +1,105 @@
+package commands
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	models "github.com/fnproject/fn_go/modelsv2"
+)
+
+func TestComputeFnDrift(t *testing.T) {
+	local := &applyFn{
+		fn: &models.Fn{Name: "hello", Image: "hello:0.0.2", Memory: 256, Timeout: int32Ptr(30),
+			Config: map[string]string{"A": "1"}},
+		triggers: []*models.Trigger{
+			{Name: "hello", Type: "http", Source: "/hello"},
+			{Name: "new", Type: "http", Source: "/new"},
+		},
+	}
+	remote := &applyFn{
+		fn: &models.Fn{ID: "fn1", Name: "hello", Image: "hello:0.0.2", Memory: 512, Timeout: int32Ptr(30),
+			Config: map[string]string{"A": "1", "B": "2"}},
+		triggers: []*models.Trigger{
+			{ID: "t1", Name: "hello", Type: "http", Source: "/hi"},
+			{ID: "t2", Name: "old", Type: "http", Source: "/old"},
+		},
+	}
+
+	drift := computeFnDrift(local, remote)
+	if drift.empty() {
+		t.Fatal("expected drift")
+	}
+	var out bytes.Buffer
+	drift.print(&out)
+	expected := []string{
+		"memory: 512 -> 256",
+		`config.B: "2" -> <unset>`,
+		`trigger hello source: "/hi" -> "/hello"`,
+		"trigger new: only declared locally",
+		"trigger old: only deployed",
+	}
+	for _, e := range expected {
+		if !strings.Contains(out.String(), e) {
+			t.Fatalf("expected output to contain %q, got:\n%s", e, out.String())
+		}
+	}
+}
+
+func TestComputeFnDriftNoChanges(t *testing.T) {
+	local := &applyFn{fn: &models.Fn{Name: "hello", Image: "hello:0.0.1"}}
+	remote := &applyFn{fn: &models.Fn{ID: "fn1", Name: "hello", Image: "hello:0.0.1", Memory: 128, Timeout: int32Ptr(30), IdleTimeout: int32Ptr(30),
+		Annotations: map[string]interface{}{"fnproject.io/fn/invokeEndpoint": "http://example.com"}}}
+
+	if drift := computeFnDrift(local, remote); !drift.empty() {
+		t.Fatalf("expected no drift, got %+v", drift)
+	}
+}
+
+func TestComputeFnDriftComparesUnsetFieldsWithServerDefaults(t *testing.T) {
+	local := &applyFn{fn: &models.Fn{Name: "hello"}}
+	remote := &applyFn{fn: &models.Fn{ID: "fn1", Name: "hello", Image: "hello:0.0.1", Memory: 1024, Timeout: int32Ptr(120), IdleTimeout: int32Ptr(30)}}
+
+	var out bytes.Buffer
+	computeFnDrift(local, remote).print(&out)
+	expected := []string{
+		`image: "hello:0.0.1" -> <unset>`,
+		"memory: 1024 -> 128",
+		"timeout: 120 -> 30",
+	}
+	for _, e := range expected {
+		if !strings.Contains(out.String(), e) {
+			t.Fatalf("expected output to contain %q, got:\n%s", e, out.String())
+		}
+	}
+	if strings.Contains(out.String(), "idle_timeout") {
+		t.Fatalf("expected the default idle timeout to match, got:\n%s", out.String())
+	}
+}
+
+func TestComputeFnDriftPrintsReplacedTriggers(t *testing.T) {
+	local := &applyFn{
+		fn:       &models.Fn{Name: "hello", Image: "hello:0.0.1"},
+		triggers: []*models.Trigger{{Name: "hello", Type: "cron", Source: "/hello"}},
+	}
+	remote := &applyFn{
+		fn:       &models.Fn{ID: "fn1", Name: "hello", Image: "hello:0.0.1", Memory: 128, Timeout: int32Ptr(30), IdleTimeout: int32Ptr(30)},
+		triggers: []*models.Trigger{{ID: "t1", Name: "hello", Type: "http", Source: "/hello"}},
+	}
+
+	drift := computeFnDrift(local, remote)
+	if drift.empty() {
+		t.Fatal("expected drift")
+	}
+	var out bytes.Buffer
+	drift.print(&out)
+	expected := []string{
+		"trigger hello: type changed, fn apply will replace it",
+		`trigger hello type: "http" -> "cron"`,
+	}
+	for _, e := range expected {
+		if !strings.Contains(out.String(), e) {
+			t.Fatalf("expected output to contain %q, got:\n%s", e, out.String())
+		}
+	}
+}
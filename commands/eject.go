@@ -0,0 +1,62 @@
+/*
+ * Copyright (c) 2019, 2020 Oracle and/or its affiliates. All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package commands
+
+import (
+	"fmt"
+	"path/filepath"
+
+	common "github.com/fnproject/cli/common"
+	"github.com/urfave/cli"
+)
+
+// EjectCommand returns eject cli.command
+func EjectCommand() cli.Command {
+	return cli.Command{
+		Name:     "eject",
+		Usage:    "\tWrite the generated Dockerfile of a function and build it from that Dockerfile from now on",
+		Category: "DEVELOPMENT COMMANDS",
+		Description: "This command writes the Dockerfile that fn build generates for the runtime of a function to its " +
+			"directory and switches func.yaml to runtime docker, so the Dockerfile can be reviewed, pinned and edited. " +
+			"The build image, run image, entrypoint and cmd of func.yaml are removed as the Dockerfile sets them.",
+		ArgsUsage: "[function-subdirectory]",
+		Flags: []cli.Flag{
+			cli.StringFlag{
+				Name:  "working-dir, w",
+				Usage: "Specify the working directory of the function, must be the full path.",
+			},
+		},
+		Action: eject,
+	}
+}
+
+func eject(c *cli.Context) error {
+	dir := common.GetDir(c)
+	if path := c.Args().First(); path != "" {
+		dir = filepath.Join(dir, path)
+	}
+	fpath, ff, err := common.FindAndParseFuncFileV20180708(dir)
+	if err != nil {
+		return err
+	}
+	dockerfile, err := common.EjectDockerfileV20180708(fpath, ff)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Wrote %s, %s now uses runtime %s\n", dockerfile, filepath.Base(fpath), common.FuncfileDockerRuntime)
+	return nil
+}
@@ -176,3 +176,53 @@ func TestFindMissingValues(t *testing.T) {
 		}
 	}
 }
+
+func TestIsSignatureConfiguredCosign(t *testing.T) {
+	configured, err := isSignatureConfigured(common.SigningDetails{Key: "cosign.key"})
+	if !configured || err != nil {
+		t.Fatalf("expected a cosign key to configure signing, got %v %v", configured, err)
+	}
+	if _, err := isSignatureConfigured(common.SigningDetails{Provider: common.SigningProviderCosign}); err == nil {
+		t.Fatal("expected an error for the cosign provider without a key")
+	}
+	if _, err := isSignatureConfigured(common.SigningDetails{Provider: "notary"}); err == nil {
+		t.Fatal("expected an error for an unknown provider")
+	}
+	if d := signingKeyDescription(common.SigningDetails{Key: "cosign.key"}); d != "cosign key cosign.key" {
+		t.Fatalf("unexpected description %s", d)
+	}
+}
+
+func TestSignedMessageMatches(t *testing.T) {
+	message, err := createImageSignatureMessage("us-ashburn-1", "sha256:digestvalue", "test/reponame", common.SigningDetails{KmsKeyId: "ocid1.key.test"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := signedMessageMatches(message, "sha256:digestvalue"); err != nil {
+		t.Fatalf("expected the message to match, got %s", err)
+	}
+	if err := signedMessageMatches(message, "sha256:other"); err == nil {
+		t.Fatal("expected a message of another digest not to match")
+	}
+}
+
+func TestSignatureVerifiersTrustSigningKeyOnlyWithoutContextKeys(t *testing.T) {
+	signingDetails := common.SigningDetails{Key: "/keys/deploy.key"}
+	policy := common.SignaturePolicy{Enforce: true, TrustedKeys: []string{"/keys/release.pub"}, TransparencyLog: true}
+	verifiers, err := signatureVerifiers(policy, signingDetails, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(verifiers) != 1 || verifiers[0].(common.CosignVerifier).PublicKey != "/keys/release.pub" {
+		t.Fatalf("expected only the trusted key of the context, got %+v", verifiers)
+	}
+
+	policy.TrustedKeys = nil
+	verifiers, err = signatureVerifiers(policy, signingDetails, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v, ok := verifiers[0].(common.CosignVerifier); len(verifiers) != 1 || !ok || v.PublicKey != "/keys/deploy.pub" || !v.TransparencyLog {
+		t.Fatalf("expected the signing key with the transparency log of the context, got %+v", verifiers)
+	}
+}
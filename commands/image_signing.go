@@ -0,0 +1,293 @@
+/*
+ * Copyright (c) 2019, 2020 Oracle and/or its affiliates. All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package commands
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	common "github.com/fnproject/cli/common"
+	"github.com/fnproject/fn_go/provider/oracle"
+	"github.com/oracle/oci-go-sdk/v65/artifacts"
+	ociCommon "github.com/oracle/oci-go-sdk/v65/common"
+	"github.com/oracle/oci-go-sdk/v65/keymanagement"
+)
+
+// imageSigner signs pushed function images and verifies their signatures with the key of signing_details
+type imageSigner interface {
+	// sign signs the image with the given digest and attaches the signature to it
+	sign(image, digest string) error
+	common.ImageVerifier
+}
+
+// newImageSigner returns the signer of the signing provider, or nil when images are not signed.
+// OCI KMS signatures are only made with the oracle providers.
+func newImageSigner(signingDetails common.SigningDetails, out, errOut io.Writer) (imageSigner, error) {
+	configured, err := isSignatureConfigured(signingDetails)
+	if err != nil || !configured {
+		return nil, err
+	}
+	if signingDetails.SigningProvider() == common.SigningProviderCosign {
+		return &cosignSigner{signingDetails: signingDetails, out: out, errOut: errOut}, nil
+	}
+	oracleProvider, _ := getOracleProvider()
+	if oracleProvider == nil {
+		return nil, nil
+	}
+	return &ociKMSSigner{provider: oracleProvider, signingDetails: signingDetails, out: out}, nil
+}
+
+// signingKeyDescription names the key of signing_details in messages
+func signingKeyDescription(signingDetails common.SigningDetails) string {
+	if signingDetails.SigningProvider() == common.SigningProviderCosign {
+		return "cosign key " + signingDetails.Key
+	}
+	return "KmsKey " + signingDetails.KmsKeyId
+}
+
+// enforceSignature refuses to deploy an image that is not signed by a trusted key when the signature
+// policy of the context or of func.yaml is enforced. It returns the image to deploy, which is pinned to
+// the digest of the signed image when the policy is enforced, so the tag can't move after the check.
+func (p *deploycmd) enforceSignature(funcfilePath string, ff *common.FuncFileV20180708, image string) (string, error) {
+	policy := ff.SignaturePolicyV20180708()
+	if !policy.Enforce || image == "" {
+		return image, nil
+	}
+	verifiers, err := signatureVerifiers(policy, ff.SigningDetails.WithKeysRelativeTo(filepath.Dir(funcfilePath)), p.out, p.errOut)
+	if err != nil {
+		return "", err
+	}
+	ref := image
+	if containerEngineType, err := common.GetContainerEngineType(); err == nil {
+		if digest, err := lookupImageDigest(containerEngineType, image); err == nil {
+			ref = common.ImageDigestRef(image, digest)
+		}
+	}
+	fmt.Fprintf(p.out, "Verifying the signature of image %s...\n", ref)
+	digest, err := common.EnforceImageSignature(ref, verifiers)
+	if err != nil {
+		return "", err
+	}
+	pinned := common.ImageDigestRef(image, digest)
+	fmt.Fprintf(p.out, "Image %s is signed by a trusted key, deploying %s\n", image, pinned)
+	return pinned, nil
+}
+
+// signatureVerifiers returns the verifiers of an enforced policy. Deploys sign the image with the key of
+// signing_details, so it is only trusted when the context trusts no key, and its signatures must be in the
+// transparency log unless the context turns it off.
+func signatureVerifiers(policy common.SignaturePolicy, signingDetails common.SigningDetails, out, errOut io.Writer) ([]common.ImageVerifier, error) {
+	if len(policy.TrustedKeys) > 0 {
+		return policy.Verifiers(), nil
+	}
+	signer, err := newImageSigner(signingDetails, out, errOut)
+	if err != nil || signer == nil {
+		return nil, err
+	}
+	if signingDetails.SigningProvider() == common.SigningProviderCosign {
+		return []common.ImageVerifier{common.CosignVerifier{PublicKey: signingDetails.CosignPublicKey(), TransparencyLog: policy.TransparencyLog}}, nil
+	}
+	return []common.ImageVerifier{signer}, nil
+}
+
+// cosignSigner signs images with a cosign key and stores the signatures next to the image in its registry
+type cosignSigner struct {
+	signingDetails common.SigningDetails
+	out            io.Writer
+	errOut         io.Writer
+}
+
+func (s *cosignSigner) sign(image, digest string) error {
+	if err := common.CosignSign(common.ImageDigestRef(image, digest), s.signingDetails.Key, s.signingDetails.TransparencyLog, s.out, s.errOut); err != nil {
+		return err
+	}
+	fmt.Fprintf(s.out, "Successfully signed image %s\n", image)
+	return nil
+}
+
+// VerifyImage implements common.ImageVerifier
+func (s *cosignSigner) VerifyImage(image string) (string, error) {
+	return common.CosignVerifier{PublicKey: s.signingDetails.CosignPublicKey(), TransparencyLog: s.signingDetails.TransparencyLog}.VerifyImage(image)
+}
+
+// ociKMSSigner signs images with an OCI Vault key and uploads the signatures to OCI Registry
+type ociKMSSigner struct {
+	provider       *oracle.OracleProvider
+	signingDetails common.SigningDetails
+	out            io.Writer
+}
+
+func (s *ociKMSSigner) sign(image, imageDigest string) error {
+	repositoryName, err := repositoryNameOfImage(image)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(s.out, "Image belongs to repository %s\n", repositoryName)
+	artifactsClient, region, err := s.artifactsClient()
+	if err != nil {
+		return err
+	}
+	imageId, compartmentId, err := getImageId(artifactsClient, "", s.signingDetails.ImageCompartmentId, repositoryName, imageDigest)
+	if err != nil {
+		return err
+	}
+	signatureRequired, err := isSignatureRequired(artifactsClient, imageId, s.signingDetails)
+	if err != nil {
+		return err
+	}
+	if !signatureRequired {
+		fmt.Fprintf(s.out, "Image %s is already signed by %s\n", image, s.signingDetails.KmsKeyId)
+		return nil
+	}
+	message, signature, err := createImageSignature(s.provider, region, imageDigest, repositoryName, s.signingDetails)
+	if err != nil {
+		return err
+	}
+	if err = uploadImageSignature(artifactsClient, compartmentId, imageId, message, signature, s.signingDetails); err == nil {
+		fmt.Fprintf(s.out, "Successfully signed and uploaded image signature for %s\n", image)
+	}
+	return err
+}
+
+// VerifyImage implements common.ImageVerifier, for images referenced by tag or by digest
+func (s *ociKMSSigner) VerifyImage(image string) (string, error) {
+	artifactsClient, region, err := s.artifactsClient()
+	if err != nil {
+		return "", err
+	}
+	imageId, imageDigest, err := s.lookupImage(artifactsClient, image)
+	if err != nil {
+		return "", err
+	}
+
+	signatures, err := artifactsClient.ListContainerImageSignatures(context.Background(), artifacts.ListContainerImageSignaturesRequest{
+		CompartmentId:          ociCommon.String(s.signingDetails.ImageCompartmentId),
+		CompartmentIdInSubtree: ociCommon.Bool(true),
+		ImageId:                ociCommon.String(imageId),
+		KmsKeyId:               ociCommon.String(s.signingDetails.KmsKeyId),
+		KmsKeyVersionId:        ociCommon.String(s.signingDetails.KmsKeyVersionId),
+		SigningAlgorithm:       artifacts.ListContainerImageSignaturesSigningAlgorithmEnum(s.signingDetails.SigningAlgorithm),
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to list the signatures of %s due to %s", image, err)
+	}
+	if len(signatures.Items) == 0 {
+		return "", fmt.Errorf("no signature of %s by KmsKey %s", image, s.signingDetails.KmsKeyId)
+	}
+	kmsClient, err := s.cryptoClient(region)
+	if err != nil {
+		return "", err
+	}
+	var reasons []string
+	for _, signature := range signatures.Items {
+		if err := signedMessageMatches(*signature.Message, imageDigest); err != nil {
+			reasons = append(reasons, err.Error())
+			continue
+		}
+		verified, err := kmsClient.Verify(context.Background(), keymanagement.VerifyRequest{
+			VerifyDataDetails: keymanagement.VerifyDataDetails{
+				KeyId:            ociCommon.String(s.signingDetails.KmsKeyId),
+				KeyVersionId:     ociCommon.String(s.signingDetails.KmsKeyVersionId),
+				Message:          signature.Message,
+				Signature:        signature.Signature,
+				SigningAlgorithm: keymanagement.VerifyDataDetailsSigningAlgorithmEnum(s.signingDetails.SigningAlgorithm),
+				MessageType:      keymanagement.VerifyDataDetailsMessageTypeRaw,
+			},
+		})
+		if err != nil {
+			reasons = append(reasons, fmt.Sprintf("failed to verify signature %s due to %s", *signature.Id, err))
+			continue
+		}
+		if verified.IsSignatureValid != nil && *verified.IsSignatureValid {
+			return imageDigest, nil
+		}
+		reasons = append(reasons, fmt.Sprintf("signature %s is not valid", *signature.Id))
+	}
+	return "", fmt.Errorf("no valid signature of %s by KmsKey %s: %s", image, s.signingDetails.KmsKeyId, strings.Join(reasons, "; "))
+}
+
+// lookupImage returns the OCI Registry id and the digest of an image
+func (s *ociKMSSigner) lookupImage(artifactsClient artifacts.ArtifactsClient, image string) (string, string, error) {
+	if i := strings.Index(image, "@"); i >= 0 {
+		repositoryName, err := repositoryNameOfImage(image[:i] + ":latest")
+		if err != nil {
+			return "", "", err
+		}
+		imageId, _, err := getImageId(artifactsClient, "", s.signingDetails.ImageCompartmentId, repositoryName, image[i+1:])
+		return imageId, image[i+1:], err
+	}
+	repositoryName, err := repositoryNameOfImage(image)
+	if err != nil {
+		return "", "", err
+	}
+	images, err := artifactsClient.ListContainerImages(context.Background(), artifacts.ListContainerImagesRequest{
+		CompartmentId:          ociCommon.String(s.signingDetails.ImageCompartmentId),
+		CompartmentIdInSubtree: ociCommon.Bool(true),
+		RepositoryName:         ociCommon.String(repositoryName),
+		Version:                ociCommon.String(image[strings.LastIndex(image, ":")+1:]),
+	})
+	if err != nil {
+		return "", "", fmt.Errorf("failed to lookup image in OCI Registry due to %s", err)
+	}
+	if len(images.Items) == 0 || images.Items[0].Digest == nil {
+		return "", "", fmt.Errorf("failed to fetch image details for %s from OCI Container Registry", image)
+	}
+	return *images.Items[0].Id, *images.Items[0].Digest, nil
+}
+
+func (s *ociKMSSigner) artifactsClient() (artifacts.ArtifactsClient, string, error) {
+	artifactsClient, err := artifacts.NewArtifactsClientWithConfigurationProvider(s.provider.ConfigurationProvider)
+	if err != nil {
+		return artifactsClient, "", err
+	}
+	region := getRegion(s.provider)
+	artifactsClient.SetRegion(region)
+	return artifactsClient, region, nil
+}
+
+func (s *ociKMSSigner) cryptoClient(region string) (keymanagement.KmsCryptoClient, error) {
+	cryptoEndpoint, err := buildCryptoEndpoint(region, s.signingDetails.KmsKeyId)
+	if err != nil {
+		return keymanagement.KmsCryptoClient{}, fmt.Errorf("failed to build crypto endpoint due to %s", err)
+	}
+	kmsClient, err := keymanagement.NewKmsCryptoClientWithConfigurationProvider(s.provider.ConfigurationProvider, cryptoEndpoint)
+	if err != nil {
+		return kmsClient, fmt.Errorf("failed to create crypto client due to %s", err)
+	}
+	return kmsClient, nil
+}
+
+// signedMessageMatches checks that a signature message made by createImageSignatureMessage signs the digest
+func signedMessageMatches(encoded, imageDigest string) error {
+	decoded, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return fmt.Errorf("cannot decode signature message: %s", err)
+	}
+	var message Message
+	if err := json.Unmarshal(decoded, &message); err != nil {
+		return fmt.Errorf("cannot parse signature message: %s", err)
+	}
+	if message.ImageDigest != imageDigest {
+		return fmt.Errorf("signature is for digest %s, not %s", message.ImageDigest, imageDigest)
+	}
+	return nil
+}
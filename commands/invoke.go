@@ -21,6 +21,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"net/http"
 	"os"
 	"strings"
@@ -31,6 +32,7 @@ import (
 	"github.com/fnproject/cli/common"
 	"github.com/fnproject/cli/objects/app"
 	"github.com/fnproject/cli/objects/fn"
+	"github.com/fnproject/cli/objects/trigger"
 	"github.com/fnproject/fn_go/clientv2"
 	"github.com/fnproject/fn_go/provider"
 	"github.com/urfave/cli"
@@ -84,6 +86,23 @@ var InvokeFnFlags = []cli.Flag{
 		Name:  "fn-invoke-type",
 		Usage: "Invoke type for Oracle Functions: sync or detached",
 	},
+	cli.StringFlag{
+		Name:  "method",
+		Usage: "HTTP method of the invocation",
+		Value: http.MethodPost,
+	},
+	cli.StringSliceFlag{
+		Name:  "header,H",
+		Usage: "Request header as name=value or 'name: value', can be repeated",
+	},
+	cli.StringFlag{
+		Name:  "header-file",
+		Usage: "File of request headers, one 'name: value' per line",
+	},
+	cli.StringSliceFlag{
+		Name:  "env,e",
+		Usage: "Send an environment variable as a header, as NAME for its current value or NAME=value, can be repeated",
+	},
 	cli.BoolFlag{
 		Name:  common.NoInvokeEndpointCacheFlag,
 		Usage: "Do not use the local function invoke endpoint cache",
@@ -94,6 +113,44 @@ var InvokeFnFlags = []cli.Flag{
 		Value:  common.DefaultInvokeEndpointCacheTTL,
 		EnvVar: common.InvokeEndpointCacheTTLEnvVar,
 	},
+	cli.IntFlag{
+		Name:  "requests",
+		Usage: "Number of invocations to send for a load test, which reports throughput, errors and latency percentiles instead of the responses",
+		Value: 1,
+	},
+	cli.IntFlag{
+		Name:  "concurrency",
+		Usage: "Number of invocations of a load test in flight at the same time",
+		Value: 1,
+	},
+	cli.StringFlag{
+		Name:  "payload-file",
+		Usage: "File with the payload of every invocation of a load test, instead of STDIN",
+	},
+	cli.StringFlag{
+		Name:  "data,d",
+		Usage: "Payload of the invocation, or @file to read it from a file, instead of STDIN",
+	},
+	cli.BoolFlag{
+		Name:  "stream",
+		Usage: "Stream the payload instead of buffering it, so it can be larger than 10MB. Providers that sign requests, such as oracle, do not support it",
+	},
+	cli.StringFlag{
+		Name:  "output-file",
+		Usage: "Write the response body to a file as it is received",
+	},
+	cli.StringFlag{
+		Name:  "batch",
+		Usage: "JSON lines file, or directory of files, to send each line or file of as an invocation, --concurrency at a time",
+	},
+	cli.StringFlag{
+		Name:  "batch-output",
+		Usage: "JSON lines file of the results of a batch, in the order of its input, defaults to <batch>.out.jsonl",
+	},
+	cli.BoolFlag{
+		Name:  "resume",
+		Usage: "Retry the invocations of a batch that failed or have no result in its output file, keeping the others",
+	},
 }
 
 var InvokeDetachedFnFlags = []cli.Flag{
@@ -121,6 +178,18 @@ var InvokeDetachedFnFlags = []cli.Flag{
 		Name:  "is-dry-run",
 		Usage: "Send the invocation as a dry run without executing the function when supported by the server",
 	},
+	cli.StringSliceFlag{
+		Name:  "header,H",
+		Usage: "Request header as name=value or 'name: value', can be repeated",
+	},
+	cli.StringFlag{
+		Name:  "header-file",
+		Usage: "File of request headers, one 'name: value' per line",
+	},
+	cli.StringSliceFlag{
+		Name:  "env,e",
+		Usage: "Send an environment variable as a header, as NAME for its current value or NAME=value, can be repeated",
+	},
 	cli.BoolFlag{
 		Name:  common.NoInvokeEndpointCacheFlag,
 		Usage: "Do not use the local function invoke endpoint cache",
@@ -167,6 +236,24 @@ func InvokeCommand() cli.Command {
 					}
 				},
 			},
+			{
+				Name:        "trigger",
+				Usage:       "\tInvoke the http trigger of a remote function",
+				ArgsUsage:   "<app-name> <function-name> <trigger-name>",
+				Description: "This command sends a request to the endpoint of an http trigger and prints the status, headers and body of the response. Users may send a body by passing it to this command via STDIN.",
+				Flags:       InvokeTriggerFlags,
+				Action:      cl.InvokeTrigger,
+				BashComplete: func(c *cli.Context) {
+					switch len(c.Args()) {
+					case 0:
+						app.BashCompleteApps(c)
+					case 1:
+						fn.BashCompleteFns(c)
+					case 2:
+						trigger.BashCompleteTriggers(c)
+					}
+				},
+			},
 		},
 		Category:    "DEVELOPMENT COMMANDS",
 		Description: `This command invokes a function. Users may send input to their function by passing input to this command via STDIN.`,
@@ -209,7 +296,20 @@ func (cl *invokeCmd) invoke(c *cli.Context, forcedInvokeType string) error {
 			return err
 		}
 	}
-	content := stdin()
+	stream := c.Bool("stream")
+	if stream && common.IsOracleProvider(cl.provider) {
+		return errors.New("--stream cannot be used with an oracle provider, which signs the request body")
+	}
+	content, size, err := openInvokePayload(c.String("data"), os.Stderr)
+	if err != nil {
+		return err
+	}
+	if content != nil {
+		defer content.Close()
+	}
+	if err := checkInvokePayloadSize(size, stream); err != nil {
+		return err
+	}
 	wd := common.GetWd()
 	invokeType := strings.ToLower(strings.TrimSpace(forcedInvokeType))
 	if invokeType == "" {
@@ -233,23 +333,59 @@ func (cl *invokeCmd) invoke(c *cli.Context, forcedInvokeType string) error {
 		}
 	}
 
-	resp, err := invokeFunction(cl.provider,
-		client.InvokeRequest{
-			URL:          invokeURL,
-			Content:      content,
-			Env:          c.StringSlice("e"),
-			ContentType:  contentType,
-			FnIntent:     fnIntent,
-			IsDryRun:     c.Bool("is-dry-run"),
-			FnInvokeType: invokeType,
-		},
-	)
+	headers, err := invokeHeaders(c.StringSlice("header"), c.String("header-file"))
 	if err != nil {
 		return err
 	}
-	defer resp.Body.Close()
 
+	ireq := client.InvokeRequest{
+		URL:           invokeURL,
+		Method:        c.String("method"),
+		Headers:       headers,
+		Env:           c.StringSlice("env"),
+		ContentType:   contentType,
+		FnIntent:      fnIntent,
+		IsDryRun:      c.Bool("is-dry-run"),
+		FnInvokeType:  invokeType,
+		Stream:        stream,
+		ContentLength: size,
+	}
+	if content != nil {
+		ireq.Content = content
+	}
 	outputFormat := strings.ToLower(c.String("output"))
+	outputFile := c.String("output-file")
+	if c.String("batch") != "" {
+		if stream || outputFile != "" || c.String("data") != "" || c.String("payload-file") != "" || c.Int("requests") > 1 {
+			return errors.New("--batch cannot be used with --data, --payload-file, --requests, --stream or --output-file")
+		}
+		return cl.batch(c, ireq)
+	}
+	if c.Int("requests") > 1 || c.Int("concurrency") > 1 || c.String("payload-file") != "" {
+		if stream || outputFile != "" {
+			return errors.New("--stream and --output-file cannot be used in a load test")
+		}
+		if c.String("data") != "" && c.String("payload-file") != "" {
+			return errors.New("--data and --payload-file cannot be used together")
+		}
+		return cl.bench(c, ireq, outputFormat)
+	}
+	if outputFile != "" && outputFormat == "json" {
+		return errors.New("--output-file and --output json cannot be used together")
+	}
+
+	resp, err := invokeFunction(cl.provider, ireq)
+	if err == client.ErrRequestBodyTooLarge {
+		return payloadTooLargeError(size)
+	}
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if outputFile != "" {
+		return writeResponseToFile(outputFile, resp, c.Bool("display-call-id"), os.Stderr)
+	}
 	if outputFormat == "json" {
 		outputJSON(os.Stdout, resp)
 	} else {
@@ -260,6 +396,94 @@ func (cl *invokeCmd) invoke(c *cli.Context, forcedInvokeType string) error {
 	return nil
 }
 
+// bench load tests the function with --requests invocations of the same payload, --concurrency at a time
+func (cl *invokeCmd) bench(c *cli.Context, ireq client.InvokeRequest, outputFormat string) error {
+	requests, concurrency := c.Int("requests"), c.Int("concurrency")
+	if requests < 1 {
+		return fmt.Errorf("--requests must be at least 1")
+	}
+	if concurrency < 1 {
+		return fmt.Errorf("--concurrency must be at least 1")
+	}
+	if concurrency > requests {
+		concurrency = requests
+	}
+
+	var payload []byte
+	var err error
+	if path := c.String("payload-file"); path != "" {
+		payload, err = ioutil.ReadFile(path)
+	} else if ireq.Content != nil {
+		payload, err = ioutil.ReadAll(ireq.Content)
+	}
+	if err != nil {
+		return fmt.Errorf("could not read the payload: %v", err)
+	}
+
+	if outputFormat != "json" {
+		fmt.Fprintf(os.Stderr, "Sending %d invocations to %s, %d at a time\n", requests, ireq.URL, concurrency)
+	}
+	results, elapsed := runInvokeBench(cl.provider, ireq, payload, requests, concurrency)
+	return printInvokeBenchReport(os.Stdout, summarizeInvokeBench(results, elapsed, concurrency), outputFormat)
+}
+
+// batch invokes the function with each line or file of --batch and writes the results to --batch-output
+func (cl *invokeCmd) batch(c *cli.Context, ireq client.InvokeRequest) error {
+	input := c.String("batch")
+	concurrency := c.Int("concurrency")
+	if concurrency < 1 {
+		return fmt.Errorf("--concurrency must be at least 1")
+	}
+	output := c.String("batch-output")
+	if output == "" {
+		output = invokeBatchOutput(input)
+	}
+	items, err := readInvokeBatch(input)
+	if err != nil {
+		return fmt.Errorf("could not read the batch: %v", err)
+	}
+
+	remaining := items
+	flags := os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	resume := c.Bool("resume")
+	if resume {
+		var done int
+		remaining, done, err = resumeInvokeBatch(output, items)
+		if err != nil {
+			return fmt.Errorf("could not resume the batch: %v", err)
+		}
+		if done > 0 {
+			fmt.Fprintf(os.Stderr, "Resuming the batch, %d of %d invocations already succeeded\n", done, len(items))
+		}
+		// the results of the retried invocations are appended, and the output is put back in input order at the end
+		flags = os.O_WRONLY | os.O_CREATE | os.O_APPEND
+	}
+	out, err := os.OpenFile(output, flags, 0644)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	fmt.Fprintf(os.Stderr, "Invoking %s with %d payloads from %s, %d at a time\n", ireq.URL, len(remaining), input, concurrency)
+	failed, err := runInvokeBatch(cl.provider, ireq, remaining, concurrency, out)
+	if err != nil {
+		return fmt.Errorf("could not write the results to %s: %v", output, err)
+	}
+	if resume {
+		if err := out.Close(); err != nil {
+			return fmt.Errorf("could not write the results to %s: %v", output, err)
+		}
+		if err := rewriteInvokeBatch(output, items); err != nil {
+			return fmt.Errorf("could not write the results to %s: %v", output, err)
+		}
+	}
+	fmt.Fprintf(os.Stderr, "Wrote the results of %d invocations to %s\n", len(remaining), output)
+	if failed > 0 {
+		return fmt.Errorf("%d of %d invocations failed, run again with --resume to retry the failed invocations", failed, len(remaining))
+	}
+	return nil
+}
+
 func (cl *invokeCmd) resolveInvokeEndpoint(c *cli.Context, appName, fnName string) (string, error) {
 	cacheEnabled := !c.Bool(common.NoInvokeEndpointCacheFlag)
 	cacheTTL := c.Duration(common.InvokeEndpointCacheTTLFlag)
@@ -296,6 +520,45 @@ func (cl *invokeCmd) resolveInvokeEndpoint(c *cli.Context, appName, fnName strin
 	return invokeURL, nil
 }
 
+// invokeHeaders returns the headers of the --header-file, followed by the --header flags
+func invokeHeaders(flags []string, file string) (http.Header, error) {
+	headers := http.Header{}
+	if file != "" {
+		b, err := ioutil.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("could not read the header file: %v", err)
+		}
+		for n, line := range strings.Split(string(b), "\n") {
+			line = strings.TrimSpace(line)
+			if line == "" || strings.HasPrefix(line, "#") {
+				continue
+			}
+			name, value, err := parseInvokeHeader(line)
+			if err != nil {
+				return nil, fmt.Errorf("%s line %d: %v", file, n+1, err)
+			}
+			headers.Add(name, value)
+		}
+	}
+	for _, h := range flags {
+		name, value, err := parseInvokeHeader(h)
+		if err != nil {
+			return nil, err
+		}
+		headers.Add(name, value)
+	}
+	return headers, nil
+}
+
+// parseInvokeHeader splits a header given as name=value or name: value, header names contain neither separator
+func parseInvokeHeader(h string) (string, string, error) {
+	i := strings.IndexAny(h, "=:")
+	if i <= 0 || strings.TrimSpace(h[:i]) == "" {
+		return "", "", fmt.Errorf("invalid header %q, use name=value or 'name: value'", h)
+	}
+	return strings.TrimSpace(h[:i]), strings.TrimSpace(h[i+1:]), nil
+}
+
 func outputJSON(output io.Writer, resp *http.Response) {
 	var b bytes.Buffer
 	// TODO this is lame
@@ -0,0 +1,270 @@
+/*
+ * Copyright (c) 2019, 2020 Oracle and/or its affiliates. All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package commands
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fnproject/cli/client"
+	"github.com/fnproject/fn_go/provider"
+)
+
+// invokeBatchItem is one invocation of a batch, a line of a JSON lines file or a file of a directory
+type invokeBatchItem struct {
+	input   string
+	payload []byte
+}
+
+// invokeBatchRecord is the line of the output file with the result of an invocation
+type invokeBatchRecord struct {
+	Input    string      `json:"input"`
+	CallID   string      `json:"call_id,omitempty"`
+	Status   int         `json:"status,omitempty"`
+	Latency  float64     `json:"latency_ms"`
+	Response interface{} `json:"response,omitempty"`
+	Error    string      `json:"error,omitempty"`
+}
+
+func (r invokeBatchRecord) succeeded() bool {
+	return r.Error == "" && r.Status > 0 && r.Status < 400
+}
+
+// invokeBatchOutput is the default output file of a batch, next to its input
+func invokeBatchOutput(input string) string {
+	return strings.TrimSuffix(filepath.Clean(input), ".jsonl") + ".out.jsonl"
+}
+
+// readInvokeBatch returns the non empty lines of a JSON lines file, or the files of a directory in name order
+func readInvokeBatch(input string) ([]invokeBatchItem, error) {
+	stat, err := os.Stat(input)
+	if err != nil {
+		return nil, err
+	}
+	var items []invokeBatchItem
+	if stat.IsDir() {
+		entries, err := ioutil.ReadDir(input)
+		if err != nil {
+			return nil, err
+		}
+		sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
+		for _, e := range entries {
+			if e.IsDir() || strings.HasPrefix(e.Name(), ".") {
+				continue
+			}
+			b, err := ioutil.ReadFile(filepath.Join(input, e.Name()))
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, invokeBatchItem{input: e.Name(), payload: b})
+		}
+		return items, nil
+	}
+
+	f, err := os.Open(input)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	r := bufio.NewReader(f)
+	for n := 1; ; n++ {
+		line, err := r.ReadBytes('\n')
+		if line = bytes.TrimSpace(line); len(line) > 0 {
+			items = append(items, invokeBatchItem{input: fmt.Sprintf("%s:%d", filepath.Base(input), n), payload: line})
+		}
+		if err == io.EOF {
+			return items, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+// writtenInvokeBatchRecord is a record read back from the output file, with the line it was written as
+type writtenInvokeBatchRecord struct {
+	invokeBatchRecord
+	line []byte
+}
+
+// readInvokeBatchRecords returns the record of each input of the output file. A successful record wins over
+// the failed ones, and a later record over an earlier one. Lines cut short by an interrupted batch are skipped.
+func readInvokeBatchRecords(output string, items []invokeBatchItem) (map[string]writtenInvokeBatchRecord, error) {
+	records := map[string]writtenInvokeBatchRecord{}
+	b, err := ioutil.ReadFile(output)
+	if os.IsNotExist(err) {
+		return records, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	inputs := map[string]bool{}
+	for _, item := range items {
+		inputs[item.input] = true
+	}
+	for _, line := range bytes.Split(b, []byte("\n")) {
+		var record invokeBatchRecord
+		if err := json.Unmarshal(line, &record); err != nil {
+			continue
+		}
+		if !inputs[record.Input] {
+			return nil, fmt.Errorf("%s has results of %s, it is not the output of this batch", output, record.Input)
+		}
+		if previous, ok := records[record.Input]; ok && previous.succeeded() && !record.succeeded() {
+			continue
+		}
+		records[record.Input] = writtenInvokeBatchRecord{record, line}
+	}
+	return records, nil
+}
+
+// resumeInvokeBatch rewrites the output file in input order, without the lines cut short by an interrupted
+// batch, and returns the items of the batch that have no successful record in it, and how many do
+func resumeInvokeBatch(output string, items []invokeBatchItem) ([]invokeBatchItem, int, error) {
+	records, err := readInvokeBatchRecords(output, items)
+	if err != nil {
+		return nil, 0, err
+	}
+	if err := writeInvokeBatchRecords(output, items, records); err != nil {
+		return nil, 0, err
+	}
+	var remaining []invokeBatchItem
+	for _, item := range items {
+		if record, ok := records[item.input]; !ok || !record.succeeded() {
+			remaining = append(remaining, item)
+		}
+	}
+	return remaining, len(items) - len(remaining), nil
+}
+
+// rewriteInvokeBatch rewrites the output file of a resumed batch with a record per input, in the order of the items
+func rewriteInvokeBatch(output string, items []invokeBatchItem) error {
+	records, err := readInvokeBatchRecords(output, items)
+	if err != nil {
+		return err
+	}
+	return writeInvokeBatchRecords(output, items, records)
+}
+
+// writeInvokeBatchRecords replaces the output file with the records of the items, in their order
+func writeInvokeBatchRecords(output string, items []invokeBatchItem, records map[string]writtenInvokeBatchRecord) error {
+	var buf bytes.Buffer
+	for _, item := range items {
+		if record, ok := records[item.input]; ok {
+			buf.Write(record.line)
+			buf.WriteByte('\n')
+		}
+	}
+	tmp := output + ".tmp"
+	if err := ioutil.WriteFile(tmp, buf.Bytes(), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, output)
+}
+
+// runInvokeBatch invokes the items, at most concurrency at a time, and writes their records to out in
+// the order of the items as soon as the records before them are written
+func runInvokeBatch(p provider.Provider, ireq client.InvokeRequest, items []invokeBatchItem, concurrency int, out io.Writer) (int, error) {
+	type indexed struct {
+		index  int
+		record invokeBatchRecord
+	}
+	jobs := make(chan int)
+	results := make(chan indexed)
+	var wg sync.WaitGroup
+	for w := 0; w < concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				results <- indexed{i, invokeBatchItemOnce(p, ireq, items[i])}
+			}
+		}()
+	}
+	go func() {
+		for i := range items {
+			jobs <- i
+		}
+		close(jobs)
+		wg.Wait()
+		close(results)
+	}()
+
+	failed, next := 0, 0
+	pending := map[int]invokeBatchRecord{}
+	var writeErr error
+	for r := range results {
+		pending[r.index] = r.record
+		for {
+			record, ok := pending[next]
+			if !ok {
+				break
+			}
+			delete(pending, next)
+			next++
+			if !record.succeeded() {
+				failed++
+			}
+			if writeErr != nil {
+				continue
+			}
+			b, err := json.Marshal(record)
+			if err == nil {
+				_, err = out.Write(append(b, '\n'))
+			}
+			writeErr = err
+		}
+	}
+	return failed, writeErr
+}
+
+// invokeBatchItemOnce invokes the function with the payload of an item and records the response
+func invokeBatchItemOnce(p provider.Provider, ireq client.InvokeRequest, item invokeBatchItem) invokeBatchRecord {
+	record := invokeBatchRecord{Input: item.input}
+	ireq.Content = bytes.NewReader(item.payload)
+	start := time.Now()
+	resp, err := invokeFunction(p, ireq)
+	if err != nil {
+		record.Latency = milliseconds(time.Since(start))
+		record.Error = err.Error()
+		return record
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	record.Latency = milliseconds(time.Since(start))
+	record.Status = resp.StatusCode
+	record.CallID = resp.Header.Get(CallIDHeader)
+	if err != nil {
+		record.Error = err.Error()
+	}
+	if json.Valid(body) {
+		record.Response = json.RawMessage(body)
+	} else if len(body) > 0 {
+		record.Response = string(body)
+	}
+	return record
+}
@@ -0,0 +1,250 @@
+/*
+ * Copyright (c) 2019, 2020 Oracle and/or its affiliates. All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package commands
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"sort"
+	"strconv"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/fnproject/cli/client"
+	"github.com/fnproject/fn_go/provider"
+)
+
+// coldStartFactor is how many times slower than the median an invocation has to be to count as a cold start
+const coldStartFactor = 3
+
+// maxColdStartsShown limits the cold start outliers listed in the report
+const maxColdStartsShown = 10
+
+// invokeBenchResult is the outcome of one invocation of a load test
+type invokeBenchResult struct {
+	index   int
+	status  int
+	callID  string
+	latency time.Duration
+	err     error
+}
+
+// invokeBenchReport summarizes a load test
+type invokeBenchReport struct {
+	Requests    int                    `json:"requests"`
+	Concurrency int                    `json:"concurrency"`
+	Duration    float64                `json:"duration_seconds"`
+	Throughput  float64                `json:"requests_per_second"`
+	Succeeded   int                    `json:"succeeded"`
+	Failed      int                    `json:"failed"`
+	Errors      map[string]int         `json:"errors,omitempty"`
+	Latency     invokeBenchLatency     `json:"latency_ms"`
+	ColdStarts  []invokeBenchColdStart `json:"cold_starts,omitempty"`
+}
+
+// invokeBenchLatency are latency percentiles in milliseconds, of the invocations that got a response
+type invokeBenchLatency struct {
+	Min  float64 `json:"min"`
+	P50  float64 `json:"p50"`
+	P90  float64 `json:"p90"`
+	P99  float64 `json:"p99"`
+	Max  float64 `json:"max"`
+	Mean float64 `json:"mean"`
+}
+
+// invokeBenchColdStart is an invocation much slower than the median, which usually started a new container
+type invokeBenchColdStart struct {
+	Request int     `json:"request"`
+	CallID  string  `json:"call_id,omitempty"`
+	Latency float64 `json:"latency_ms"`
+}
+
+// runInvokeBench sends requests invocations with the same payload, at most concurrency at a time, and
+// returns their results in request order
+func runInvokeBench(p provider.Provider, ireq client.InvokeRequest, payload []byte, requests, concurrency int) ([]invokeBenchResult, time.Duration) {
+	results := make([]invokeBenchResult, requests)
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	start := time.Now()
+	for w := 0; w < concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				req := ireq
+				if payload != nil {
+					req.Content = bytes.NewReader(payload)
+				}
+				results[i] = invokeOnce(p, req, i)
+			}
+		}()
+	}
+	for i := 0; i < requests; i++ {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+	return results, time.Since(start)
+}
+
+// invokeOnce times one invocation until its response body is read
+func invokeOnce(p provider.Provider, req client.InvokeRequest, index int) invokeBenchResult {
+	start := time.Now()
+	resp, err := invokeFunction(p, req)
+	if err != nil {
+		return invokeBenchResult{index: index, latency: time.Since(start), err: err}
+	}
+	_, err = io.Copy(ioutil.Discard, resp.Body)
+	resp.Body.Close()
+	return invokeBenchResult{
+		index:   index,
+		status:  resp.StatusCode,
+		callID:  resp.Header.Get(CallIDHeader),
+		latency: time.Since(start),
+		err:     err,
+	}
+}
+
+// summarizeInvokeBench computes the throughput, errors by status and latency percentiles of a load test
+func summarizeInvokeBench(results []invokeBenchResult, elapsed time.Duration, concurrency int) invokeBenchReport {
+	report := invokeBenchReport{
+		Requests:    len(results),
+		Concurrency: concurrency,
+		Duration:    elapsed.Seconds(),
+		Errors:      map[string]int{},
+	}
+	if elapsed > 0 {
+		report.Throughput = float64(len(results)) / elapsed.Seconds()
+	}
+
+	var responded []invokeBenchResult
+	for _, r := range results {
+		switch {
+		case r.err != nil && r.status == 0:
+			report.Failed++
+			report.Errors["error"]++
+			continue
+		case r.err != nil || r.status >= 400:
+			report.Failed++
+			report.Errors[strconv.Itoa(r.status)]++
+		default:
+			report.Succeeded++
+		}
+		responded = append(responded, r)
+	}
+	if len(responded) == 0 {
+		return report
+	}
+
+	latencies := make([]time.Duration, len(responded))
+	var total time.Duration
+	for i, r := range responded {
+		latencies[i] = r.latency
+		total += r.latency
+	}
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+	report.Latency = invokeBenchLatency{
+		Min:  milliseconds(latencies[0]),
+		P50:  milliseconds(percentile(latencies, 50)),
+		P90:  milliseconds(percentile(latencies, 90)),
+		P99:  milliseconds(percentile(latencies, 99)),
+		Max:  milliseconds(latencies[len(latencies)-1]),
+		Mean: milliseconds(total / time.Duration(len(latencies))),
+	}
+
+	median := percentile(latencies, 50)
+	for _, r := range responded {
+		if r.latency > coldStartFactor*median {
+			report.ColdStarts = append(report.ColdStarts, invokeBenchColdStart{Request: r.index + 1, CallID: r.callID, Latency: milliseconds(r.latency)})
+		}
+	}
+	sort.SliceStable(report.ColdStarts, func(i, j int) bool { return report.ColdStarts[i].Latency > report.ColdStarts[j].Latency })
+	return report
+}
+
+// percentile returns the nearest-rank percentile of sorted latencies
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
+
+func milliseconds(d time.Duration) float64 {
+	return math.Round(float64(d)/float64(time.Millisecond)*100) / 100
+}
+
+// printInvokeBenchReport prints the report as text, or as JSON, and returns an error if any invocation failed
+func printInvokeBenchReport(out io.Writer, report invokeBenchReport, outputFormat string) error {
+	if outputFormat == "json" {
+		enc := json.NewEncoder(out)
+		enc.SetIndent("", "    ")
+		if err := enc.Encode(report); err != nil {
+			return err
+		}
+	} else {
+		fmt.Fprintf(out, "Requests:\t%d (%d succeeded, %d failed)\n", report.Requests, report.Succeeded, report.Failed)
+		fmt.Fprintf(out, "Concurrency:\t%d\n", report.Concurrency)
+		fmt.Fprintf(out, "Duration:\t%.2fs\n", report.Duration)
+		fmt.Fprintf(out, "Throughput:\t%.2f requests/s\n", report.Throughput)
+		l := report.Latency
+		fmt.Fprintf(out, "Latency (ms):\tmin %.2f, p50 %.2f, p90 %.2f, p99 %.2f, max %.2f, mean %.2f\n", l.Min, l.P50, l.P90, l.P99, l.Max, l.Mean)
+
+		if len(report.Errors) > 0 {
+			fmt.Fprintln(out, "\nErrors:")
+			w := tabwriter.NewWriter(out, 0, 8, 1, '\t', 0)
+			fmt.Fprint(w, "STATUS", "\t", "COUNT", "\n")
+			var statuses []string
+			for s := range report.Errors {
+				statuses = append(statuses, s)
+			}
+			sort.Strings(statuses)
+			for _, s := range statuses {
+				fmt.Fprint(w, s, "\t", report.Errors[s], "\n")
+			}
+			if err := w.Flush(); err != nil {
+				return err
+			}
+		}
+
+		if len(report.ColdStarts) > 0 {
+			fmt.Fprintf(out, "\nCold starts (over %dx the median latency): %d\n", coldStartFactor, len(report.ColdStarts))
+			w := tabwriter.NewWriter(out, 0, 8, 1, '\t', 0)
+			fmt.Fprint(w, "REQUEST", "\t", "LATENCY (ms)", "\t", "CALL ID", "\n")
+			for i, cs := range report.ColdStarts {
+				if i == maxColdStartsShown {
+					fmt.Fprintf(w, "... %d more\n", len(report.ColdStarts)-maxColdStartsShown)
+					break
+				}
+				fmt.Fprint(w, cs.Request, "\t", fmt.Sprintf("%.2f", cs.Latency), "\t", cs.CallID, "\n")
+			}
+			if err := w.Flush(); err != nil {
+				return err
+			}
+		}
+	}
+	if report.Failed > 0 {
+		return fmt.Errorf("%d of %d invocations failed", report.Failed, report.Requests)
+	}
+	return nil
+}
@@ -0,0 +1,107 @@
+/*
+ * Copyright (c) 2019, 2020 Oracle and/or its affiliates. All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package commands
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/fnproject/cli/client"
+)
+
+// openInvokePayload returns the payload of --data, which is the payload itself, @file to read it from a
+// file or @- for STDIN. Without --data the payload comes from STDIN. The size is -1 when it is not known.
+func openInvokePayload(data string, errOut io.Writer) (io.ReadCloser, int64, error) {
+	switch {
+	case data == "" || data == "@-":
+		content := stdin()
+		if content == nil {
+			return nil, -1, nil
+		}
+		return ioutil.NopCloser(content), -1, nil
+	case strings.HasPrefix(data, "@"):
+		path := data[1:]
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, -1, fmt.Errorf("could not open the payload: %v", err)
+		}
+		stat, err := f.Stat()
+		if err != nil {
+			f.Close()
+			return nil, -1, fmt.Errorf("could not open the payload: %v", err)
+		}
+		fmt.Fprintf(errOut, "Sending %s (%s)\n", path, formatByteSize(stat.Size()))
+		return f, stat.Size(), nil
+	default:
+		return ioutil.NopCloser(strings.NewReader(data)), int64(len(data)), nil
+	}
+}
+
+// checkInvokePayloadSize refuses payloads that are too large to be buffered, unless they are streamed
+func checkInvokePayloadSize(size int64, stream bool) error {
+	if !stream && size > client.MaximumRequestBodySize {
+		return payloadTooLargeError(size)
+	}
+	return nil
+}
+
+func payloadTooLargeError(size int64) error {
+	described := "the payload"
+	if size >= 0 {
+		described = fmt.Sprintf("the payload of %s", formatByteSize(size))
+	}
+	return fmt.Errorf("%s is larger than the maximum of %s, use --stream to send it without buffering with a provider that does not sign requests",
+		described, formatByteSize(client.MaximumRequestBodySize))
+}
+
+// writeResponseToFile copies the response body straight to a file and reports its size
+func writeResponseToFile(path string, resp *http.Response, includeCallID bool, errOut io.Writer) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("could not create the output file: %v", err)
+	}
+	n, err := io.Copy(f, resp.Body)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return fmt.Errorf("could not write the response to %s: %v", path, err)
+	}
+	if cid := resp.Header.Get(CallIDHeader); cid != "" && includeCallID {
+		fmt.Fprintf(errOut, "Call ID: %v\n", cid)
+	}
+	fmt.Fprintf(errOut, "Wrote %s (%s, %s) to %s\n", formatByteSize(n), resp.Status, resp.Header.Get("Content-Type"), path)
+	return nil
+}
+
+// formatByteSize prints a size in bytes with a binary unit
+func formatByteSize(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
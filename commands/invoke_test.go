@@ -1,12 +1,18 @@
 package commands
 
 import (
+	"bytes"
+	"encoding/json"
+	"errors"
 	"flag"
 	"io"
 	"net/http"
 	"net/url"
+	"os"
 	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	cliClient "github.com/fnproject/cli/client"
 	"github.com/fnproject/cli/common"
@@ -221,3 +227,358 @@ func invokeResponse() *http.Response {
 		Body:       io.NopCloser(strings.NewReader("ok\n")),
 	}
 }
+
+func TestInvokeLoadTestSendsEveryRequestWithThePayload(t *testing.T) {
+	restore := stubInvokeCommandDependencies(t)
+	defer restore()
+
+	payloadFile := t.TempDir() + "/payload.json"
+	if err := os.WriteFile(payloadFile, []byte(`{"name":"bench"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	var mu sync.Mutex
+	var bodies []string
+	invokeFunction = func(_ provider.Provider, req cliClient.InvokeRequest) (*http.Response, error) {
+		b, _ := io.ReadAll(req.Content)
+		mu.Lock()
+		bodies = append(bodies, string(b))
+		mu.Unlock()
+		return invokeResponse(), nil
+	}
+
+	cl := invokeCmd{provider: testInvokeProvider(t)}
+	ctx := newInvokeCLIContext(t, "--endpoint", "https://explicit.example.com/invoke", "--requests", "7", "--concurrency", "3", "--payload-file", payloadFile, "--output", "json")
+	if err := cl.invoke(ctx, ""); err != nil {
+		t.Fatal(err)
+	}
+	if len(bodies) != 7 {
+		t.Fatalf("expected 7 invocations, got %d", len(bodies))
+	}
+	for _, b := range bodies {
+		if b != `{"name":"bench"}` {
+			t.Fatalf("expected every invocation to send the payload, got %q", b)
+		}
+	}
+}
+
+func TestSummarizeInvokeBench(t *testing.T) {
+	var results []invokeBenchResult
+	for i := 0; i < 10; i++ {
+		results = append(results, invokeBenchResult{index: i, status: http.StatusOK, latency: time.Duration(i+1) * time.Millisecond})
+	}
+	results[0].latency = 100 * time.Millisecond
+	results[0].callID = "cold"
+	results[3].status = http.StatusBadGateway
+	results[4].status = http.StatusBadGateway
+	results[5] = invokeBenchResult{index: 5, err: errors.New("connection refused")}
+
+	report := summarizeInvokeBench(results, 2*time.Second, 2)
+	if report.Succeeded != 7 || report.Failed != 3 {
+		t.Fatalf("expected 7 succeeded and 3 failed, got %+v", report)
+	}
+	if report.Errors["502"] != 2 || report.Errors["error"] != 1 {
+		t.Fatalf("expected errors by status, got %v", report.Errors)
+	}
+	if report.Throughput != 5 {
+		t.Fatalf("expected 5 requests/s, got %v", report.Throughput)
+	}
+	// latencies of the 9 responses: 2 3 4 5 7 8 9 10 100
+	if report.Latency.P50 != 7 || report.Latency.P90 != 100 || report.Latency.Min != 2 || report.Latency.Max != 100 {
+		t.Fatalf("unexpected latency percentiles %+v", report.Latency)
+	}
+	if len(report.ColdStarts) != 1 || report.ColdStarts[0].Request != 1 || report.ColdStarts[0].CallID != "cold" {
+		t.Fatalf("expected the first request to be a cold start, got %+v", report.ColdStarts)
+	}
+
+	var out bytes.Buffer
+	if err := printInvokeBenchReport(&out, report, ""); err == nil || err.Error() != "3 of 10 invocations failed" {
+		t.Fatalf("expected the failures to be reported, got %v", err)
+	}
+	if !strings.Contains(out.String(), "p50 7.00") || !strings.Contains(out.String(), "Cold starts") {
+		t.Fatalf("unexpected report:\n%s", out.String())
+	}
+}
+
+func TestInvokePassesMethodHeadersAndEnv(t *testing.T) {
+	restore := stubInvokeCommandDependencies(t)
+	defer restore()
+
+	headerFile := t.TempDir() + "/headers"
+	if err := os.WriteFile(headerFile, []byte("# tenant headers\nX-Tenant: a\n\nAuthorization: Bearer a:b=c\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	var got cliClient.InvokeRequest
+	invokeFunction = func(_ provider.Provider, req cliClient.InvokeRequest) (*http.Response, error) {
+		got = req
+		return invokeResponse(), nil
+	}
+
+	cl := invokeCmd{provider: testInvokeProvider(t)}
+	ctx := newInvokeCLIContext(t, "--endpoint", "https://explicit.example.com/invoke", "--method", "GET",
+		"--header-file", headerFile, "-H", "X-Tenant=b", "--header", "X-Trace: 1", "-e", "STAGE=test")
+	if err := cl.invoke(ctx, ""); err != nil {
+		t.Fatal(err)
+	}
+	if got.Method != "GET" {
+		t.Fatalf("expected GET, got %q", got.Method)
+	}
+	if v := got.Headers.Values("X-Tenant"); len(v) != 2 || v[0] != "a" || v[1] != "b" {
+		t.Fatalf("expected the header file and flag headers, got %v", got.Headers)
+	}
+	if got.Headers.Get("Authorization") != "Bearer a:b=c" || got.Headers.Get("X-Trace") != "1" {
+		t.Fatalf("unexpected headers %v", got.Headers)
+	}
+	if len(got.Env) != 1 || got.Env[0] != "STAGE=test" {
+		t.Fatalf("expected the env flag, got %v", got.Env)
+	}
+
+	if err := cl.invoke(newInvokeCLIContext(t, "--endpoint", "https://explicit.example.com/invoke", "-H", "novalue"), ""); err == nil {
+		t.Fatal("expected an invalid header to fail")
+	}
+}
+
+func TestInvokeTriggerSendsRequestToTriggerEndpoint(t *testing.T) {
+	restore := stubInvokeCommandDependencies(t)
+	defer restore()
+	oldGetInvokeTrigger := getInvokeTrigger
+	defer func() { getInvokeTrigger = oldGetInvokeTrigger }()
+
+	getInvokeTrigger = func(appName, fnName, triggerName string) (*modelsv2.Trigger, error) {
+		if appName != "app" || fnName != "fn" || triggerName != "orders" {
+			t.Fatalf("unexpected trigger lookup %s/%s/%s", appName, fnName, triggerName)
+		}
+		return &modelsv2.Trigger{
+			Name: triggerName,
+			Type: "http",
+			Annotations: map[string]interface{}{
+				TriggerHTTPEndpointAnnotation: "https://gateway.example.com/t/app/orders?tenant=a",
+			},
+		}, nil
+	}
+	var got cliClient.InvokeRequest
+	invokeFunction = func(_ provider.Provider, req cliClient.InvokeRequest) (*http.Response, error) {
+		got = req
+		return invokeResponse(), nil
+	}
+
+	cl := invokeCmd{provider: testInvokeProvider(t)}
+	ctx := newInvokeTriggerCLIContext(t, "--path", "/42", "-q", "expand=items", "--method", "GET", "-H", "Accept: application/json", "app", "fn", "orders")
+	if err := cl.InvokeTrigger(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if got.URL != "https://gateway.example.com/t/app/orders/42?expand=items&tenant=a" {
+		t.Fatalf("unexpected trigger URL %q", got.URL)
+	}
+	if got.Method != "GET" || got.Headers.Get("Accept") != "application/json" {
+		t.Fatalf("expected the method and headers, got %+v", got)
+	}
+
+	invokeFunction = func(_ provider.Provider, req cliClient.InvokeRequest) (*http.Response, error) {
+		return nil, cliClient.ErrRequestBodyTooLarge
+	}
+	if err := cl.InvokeTrigger(newInvokeTriggerCLIContext(t, "app", "fn", "orders")); err == nil || err.Error() != payloadTooLargeError(-1).Error() {
+		t.Fatalf("expected a payload over the maximum size to be reported, got %v", err)
+	}
+
+	getInvokeTrigger = func(appName, fnName, triggerName string) (*modelsv2.Trigger, error) {
+		return &modelsv2.Trigger{Name: triggerName, Type: "cron"}, nil
+	}
+	if err := cl.InvokeTrigger(newInvokeTriggerCLIContext(t, "app", "fn", "nightly")); err == nil {
+		t.Fatal("expected a trigger that is not http to fail")
+	}
+}
+
+func TestOutputResponse(t *testing.T) {
+	var out bytes.Buffer
+	outputResponse(&out, &http.Response{
+		Proto:  "HTTP/1.1",
+		Status: "201 Created",
+		Header: http.Header{"Location": {"/orders/42"}, "Fn-Call-Id": {"call"}},
+		Body:   io.NopCloser(strings.NewReader(`{"id":42}`)),
+	})
+	want := "HTTP/1.1 201 Created\nFn-Call-Id: call\nLocation: /orders/42\n\n{\"id\":42}\n"
+	if out.String() != want {
+		t.Fatalf("expected\n%q\ngot\n%q", want, out.String())
+	}
+}
+
+func newInvokeTriggerCLIContext(t *testing.T, args ...string) *cli.Context {
+	t.Helper()
+	fs := flag.NewFlagSet("invoke-trigger-test", flag.ContinueOnError)
+	for _, f := range InvokeTriggerFlags {
+		f.Apply(fs)
+	}
+	if err := fs.Parse(args); err != nil {
+		t.Fatal(err)
+	}
+	return cli.NewContext(cli.NewApp(), fs, nil)
+}
+
+func TestInvokeDataFileAndOutputFile(t *testing.T) {
+	restore := stubInvokeCommandDependencies(t)
+	defer restore()
+
+	dir := t.TempDir()
+	payload := dir + "/image.png"
+	if err := os.WriteFile(payload, []byte("\x89PNG payload"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	var got cliClient.InvokeRequest
+	var body []byte
+	invokeFunction = func(_ provider.Provider, req cliClient.InvokeRequest) (*http.Response, error) {
+		got = req
+		body, _ = io.ReadAll(req.Content)
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Status:     "200 OK",
+			Header:     http.Header{"Content-Type": {"image/png"}},
+			Body:       io.NopCloser(strings.NewReader("\x89PNG resized")),
+		}, nil
+	}
+
+	cl := invokeCmd{provider: testInvokeProvider(t)}
+	output := dir + "/out.png"
+	ctx := newInvokeCLIContext(t, "--endpoint", "https://explicit.example.com/invoke", "--data", "@"+payload, "--stream", "--output-file", output)
+	if err := cl.invoke(ctx, ""); err != nil {
+		t.Fatal(err)
+	}
+	if string(body) != "\x89PNG payload" || !got.Stream || got.ContentLength != int64(len(body)) {
+		t.Fatalf("expected the file to be streamed, got %q %+v", body, got)
+	}
+	written, err := os.ReadFile(output)
+	if err != nil || string(written) != "\x89PNG resized" {
+		t.Fatalf("expected the response in the output file, got %q %v", written, err)
+	}
+}
+
+func TestInvokeRefusesLargePayloadWithoutStream(t *testing.T) {
+	restore := stubInvokeCommandDependencies(t)
+	defer restore()
+
+	invokeFunction = func(_ provider.Provider, req cliClient.InvokeRequest) (*http.Response, error) {
+		t.Fatal("unexpected invocation with a payload over the maximum size")
+		return nil, nil
+	}
+	payload := t.TempDir() + "/large.bin"
+	f, err := os.Create(payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Truncate(cliClient.MaximumRequestBodySize + 1); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	cl := invokeCmd{provider: testInvokeProvider(t)}
+	err = cl.invoke(newInvokeCLIContext(t, "--endpoint", "https://explicit.example.com/invoke", "--data", "@"+payload), "")
+	if err == nil || !strings.Contains(err.Error(), "--stream") {
+		t.Fatalf("expected the payload to be refused with a hint to stream it, got %v", err)
+	}
+}
+
+func TestInvokeBatchWritesResultsInInputOrderAndResumes(t *testing.T) {
+	restore := stubInvokeCommandDependencies(t)
+	defer restore()
+
+	dir := t.TempDir()
+	input := dir + "/records.jsonl"
+	if err := os.WriteFile(input, []byte("{\"id\":1}\n{\"id\":2}\n\n{\"id\":3}\n{\"id\":4}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	var mu sync.Mutex
+	failing := map[string]bool{`{"id":3}`: true}
+	invoked := map[string]int{}
+	invokeFunction = func(_ provider.Provider, req cliClient.InvokeRequest) (*http.Response, error) {
+		b, _ := io.ReadAll(req.Content)
+		mu.Lock()
+		defer mu.Unlock()
+		invoked[string(b)]++
+		if b[6] == '1' {
+			// the first record is the slowest, its result must still be written first
+			time.Sleep(20 * time.Millisecond)
+		}
+		if failing[string(b)] {
+			return &http.Response{StatusCode: http.StatusBadGateway, Header: http.Header{}, Body: io.NopCloser(strings.NewReader("busy"))}, nil
+		}
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Header:     http.Header{CallIDHeader: {"call-" + string(b[6])}},
+			Body:       io.NopCloser(strings.NewReader(`{"ok":` + string(b[6]) + `}`)),
+		}, nil
+	}
+
+	cl := invokeCmd{provider: testInvokeProvider(t)}
+	err := cl.invoke(newInvokeCLIContext(t, "--endpoint", "https://explicit.example.com/invoke", "--batch", input, "--concurrency", "4"), "")
+	if err == nil || !strings.Contains(err.Error(), "1 of 4 invocations failed") {
+		t.Fatalf("expected the failed invocation to be reported, got %v", err)
+	}
+	records := readBatchRecords(t, dir+"/records.out.jsonl")
+	want := []string{"records.jsonl:1", "records.jsonl:2", "records.jsonl:4", "records.jsonl:5"}
+	if len(records) != len(want) {
+		t.Fatalf("expected %d records, got %+v", len(want), records)
+	}
+	for i, r := range records {
+		if r.Input != want[i] {
+			t.Fatalf("expected record %d for %s, got %s", i, want[i], r.Input)
+		}
+	}
+	if records[0].CallID != "call-1" || records[0].Status != http.StatusOK || string(records[0].Response) != `{"ok":1}` {
+		t.Fatalf("unexpected first record %+v", records[0])
+	}
+	if records[2].Status != http.StatusBadGateway || string(records[2].Response) != `"busy"` {
+		t.Fatalf("unexpected failed record %+v", records[2])
+	}
+
+	// drop the result of the second record, and leave a line cut short by an interruption
+	out, err := os.ReadFile(dir + "/records.out.jsonl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.SplitAfter(string(out), "\n")
+	if err := os.WriteFile(dir+"/records.out.jsonl", []byte(lines[0]+lines[2]+lines[3]+`{"input":"records.jsonl:2","sta`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	delete(failing, `{"id":3}`)
+	if err := cl.invoke(newInvokeCLIContext(t, "--endpoint", "https://explicit.example.com/invoke", "--batch", input, "--resume"), ""); err != nil {
+		t.Fatal(err)
+	}
+	if invoked[`{"id":1}`] != 1 || invoked[`{"id":2}`] != 2 || invoked[`{"id":3}`] != 2 || invoked[`{"id":4}`] != 1 {
+		t.Fatalf("expected only the failed and missing records to be retried, got %v", invoked)
+	}
+	records = readBatchRecords(t, dir+"/records.out.jsonl")
+	if len(records) != 4 {
+		t.Fatalf("expected a record per input, got %+v", records)
+	}
+	for i, r := range records {
+		if r.Input != want[i] || r.Status != http.StatusOK {
+			t.Fatalf("expected the successful result of %s as record %d, got %+v", want[i], i, r)
+		}
+	}
+	if string(records[3].Response) != `{"ok":4}` {
+		t.Fatalf("expected the kept result to be written as it was, got %s", records[3].Response)
+	}
+}
+
+// batchRecord keeps the response of an invokeBatchRecord as the JSON it was written as
+type batchRecord struct {
+	invokeBatchRecord
+	Response json.RawMessage `json:"response"`
+}
+
+func readBatchRecords(t *testing.T, path string) []batchRecord {
+	t.Helper()
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var records []batchRecord
+	for _, line := range strings.Split(strings.TrimSpace(string(b)), "\n") {
+		var r batchRecord
+		if err := json.Unmarshal([]byte(line), &r); err != nil {
+			t.Fatal(err)
+		}
+		records = append(records, r)
+	}
+	return records
+}
@@ -0,0 +1,173 @@
+/*
+ * Copyright (c) 2019, 2020 Oracle and/or its affiliates. All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/fnproject/cli/client"
+	"github.com/fnproject/cli/objects/trigger"
+	"github.com/urfave/cli"
+)
+
+// TriggerHTTPEndpointAnnotation is the annotation that exposes the endpoint of an http trigger
+const TriggerHTTPEndpointAnnotation = "fnproject.io/trigger/httpEndpoint"
+
+var getInvokeTrigger = trigger.GetTriggerByAppFnAndTriggerNames
+
+// InvokeTriggerFlags used to invoke an http trigger
+var InvokeTriggerFlags = []cli.Flag{
+	cli.StringFlag{
+		Name:  "path",
+		Usage: "Path to add to the trigger endpoint, e.g. /orders/42",
+	},
+	cli.StringSliceFlag{
+		Name:  "query,q",
+		Usage: "Query string parameter as name=value, can be repeated",
+	},
+	cli.StringFlag{
+		Name:  "method",
+		Usage: "HTTP method of the request",
+		Value: http.MethodPost,
+	},
+	cli.StringFlag{
+		Name:  "content-type",
+		Usage: "The payload Content-Type of the request.",
+	},
+	cli.StringSliceFlag{
+		Name:  "header,H",
+		Usage: "Request header as name=value or 'name: value', can be repeated",
+	},
+	cli.StringFlag{
+		Name:  "header-file",
+		Usage: "File of request headers, one 'name: value' per line",
+	},
+	cli.StringSliceFlag{
+		Name:  "env,e",
+		Usage: "Send an environment variable as a header, as NAME for its current value or NAME=value, can be repeated",
+	},
+	cli.StringFlag{
+		Name:  "output",
+		Usage: "Output format (json)",
+	},
+}
+
+// InvokeTrigger sends a request to the endpoint of an http trigger and prints the status, headers and body of the response
+func (cl *invokeCmd) InvokeTrigger(c *cli.Context) error {
+	appName := c.Args().Get(0)
+	fnName := c.Args().Get(1)
+	triggerName := c.Args().Get(2)
+	if appName == "" || fnName == "" || triggerName == "" {
+		return errors.New("missing app, function and trigger name")
+	}
+
+	t, err := getInvokeTrigger(appName, fnName, triggerName)
+	if err != nil {
+		return err
+	}
+	if t.Type != "http" {
+		return fmt.Errorf("trigger %s is a %s trigger, only http triggers can be invoked", triggerName, t.Type)
+	}
+	endpoint, ok := t.Annotations[TriggerHTTPEndpointAnnotation].(string)
+	if !ok {
+		return fmt.Errorf("Trigger endpoint annotation not present, %s", TriggerHTTPEndpointAnnotation)
+	}
+	triggerURL, err := triggerRequestURL(endpoint, c.String("path"), c.StringSlice("query"))
+	if err != nil {
+		return err
+	}
+	headers, err := invokeHeaders(c.StringSlice("header"), c.String("header-file"))
+	if err != nil {
+		return err
+	}
+
+	resp, err := invokeFunction(cl.provider, client.InvokeRequest{
+		URL:         triggerURL,
+		Method:      c.String("method"),
+		Headers:     headers,
+		Content:     stdin(),
+		Env:         c.StringSlice("env"),
+		ContentType: c.String("content-type"),
+	})
+	if err == client.ErrRequestBodyTooLarge {
+		return payloadTooLargeError(-1)
+	}
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if strings.ToLower(c.String("output")) == "json" {
+		outputJSON(os.Stdout, resp)
+	} else {
+		outputResponse(os.Stdout, resp)
+	}
+	return nil
+}
+
+// triggerRequestURL adds the path and the name=value query parameters to the endpoint of a trigger
+func triggerRequestURL(endpoint, path string, query []string) (string, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return "", fmt.Errorf("invalid trigger endpoint %s: %v", endpoint, err)
+	}
+	if path != "" {
+		u.Path = strings.TrimSuffix(u.Path, "/") + "/" + strings.TrimPrefix(path, "/")
+	}
+	values := u.Query()
+	for _, q := range query {
+		kv := strings.SplitN(q, "=", 2)
+		if kv[0] == "" {
+			return "", fmt.Errorf("invalid query parameter %q, use name=value", q)
+		}
+		if len(kv) == 1 {
+			kv = append(kv, "")
+		}
+		values.Add(kv[0], kv[1])
+	}
+	u.RawQuery = values.Encode()
+	return u.String(), nil
+}
+
+// outputResponse prints the status line, the headers and the body of a response
+func outputResponse(output io.Writer, resp *http.Response) {
+	fmt.Fprintf(output, "%s %s\n", resp.Proto, resp.Status)
+	names := make([]string, 0, len(resp.Header))
+	for name := range resp.Header {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		for _, v := range resp.Header[name] {
+			fmt.Fprintf(output, "%s: %s\n", name, v)
+		}
+	}
+	fmt.Fprintln(output)
+
+	lcc := lastCharChecker{reader: resp.Body}
+	io.Copy(output, &lcc)
+	if lcc.last != '\n' {
+		fmt.Fprintln(output)
+	}
+}
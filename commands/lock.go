@@ -0,0 +1,99 @@
+/*
+ * Copyright (c) 2019, 2020 Oracle and/or its affiliates. All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sort"
+
+	common "github.com/fnproject/cli/common"
+	"github.com/urfave/cli"
+)
+
+// LockCommand returns lock cli.command
+func LockCommand() cli.Command {
+	return cli.Command{
+		Name:     "lock",
+		Usage:    "\tPin the build and run images of a function by digest in func.lock",
+		Category: "DEVELOPMENT COMMANDS",
+		Description: "This command resolves the build and run images of a function to the digests their tags point at and " +
+			"records them in func.lock next to func.yaml. fn build and fn deploy build from the pinned images until fn lock " +
+			"is run again.",
+		ArgsUsage: "[function-subdirectory]",
+		Flags: []cli.Flag{
+			cli.BoolFlag{
+				Name:  "all",
+				Usage: "If in root directory containing `app.yaml`, this will lock all functions",
+			},
+			cli.StringFlag{
+				Name:  "working-dir, w",
+				Usage: "Specify the working directory of the function, must be the full path.",
+			},
+		},
+		Action: lock,
+	}
+}
+
+func lock(c *cli.Context) error {
+	dir := common.GetDir(c)
+	if path := c.Args().First(); path != "" {
+		dir = filepath.Join(dir, path)
+	}
+	if !c.Bool("all") {
+		fpath, ff, err := common.FindAndParseFuncFileV20180708(dir)
+		if err != nil {
+			return err
+		}
+		return lockFunc(fpath, ff)
+	}
+
+	if _, err := common.LoadAppfile(dir); err != nil {
+		return err
+	}
+	funcs, err := findAllFuncs(dir, dir)
+	if err != nil {
+		return err
+	}
+	if len(funcs) == 0 {
+		return errors.New("No functions found to lock")
+	}
+	for _, f := range funcs {
+		if err := lockFunc(f.path, f.ff); err != nil {
+			return fmt.Errorf("lock error on %s: %v", f.path, err)
+		}
+	}
+	return nil
+}
+
+func lockFunc(fpath string, ff *common.FuncFileV20180708) error {
+	l, err := common.LockFuncV20180708(fpath, ff)
+	if err != nil {
+		return err
+	}
+	var images []string
+	for image := range l.Images {
+		images = append(images, image)
+	}
+	sort.Strings(images)
+	for _, image := range images {
+		fmt.Printf("Locked %s to %s\n", image, l.Images[image])
+	}
+	fmt.Printf("Wrote %s\n", filepath.Join(filepath.Dir(fpath), common.FuncLockFile))
+	return nil
+}
@@ -0,0 +1,255 @@
+/*
+ * Copyright (c) 2019, 2020 Oracle and/or its affiliates. All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package commands
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	client "github.com/fnproject/cli/client"
+	common "github.com/fnproject/cli/common"
+	apps "github.com/fnproject/cli/objects/app"
+	function "github.com/fnproject/cli/objects/fn"
+	trigger "github.com/fnproject/cli/objects/trigger"
+	v2Client "github.com/fnproject/fn_go/clientv2"
+	models "github.com/fnproject/fn_go/modelsv2"
+	fnprovider "github.com/fnproject/fn_go/provider"
+	"github.com/urfave/cli"
+)
+
+// serverAnnotationPrefix marks annotations that are managed by the server and never restored
+const serverAnnotationPrefix = "fnproject.io/"
+
+// RollbackCommand returns rollback cli.command
+func RollbackCommand() cli.Command {
+	return cli.Command{
+		Name:         "rollback",
+		Usage:        "\tRoll back an object to a previously deployed version",
+		Category:     "DEVELOPMENT COMMANDS",
+		Hidden:       false,
+		ArgsUsage:    "<subcommand>",
+		Description:  "This command rolls back an object ('function') to a version recorded by a previous deploy.",
+		Subcommands:  GetCommands(RollbackCmds),
+		BashComplete: common.DefaultBashComplete,
+	}
+}
+
+// RollbackFunctionCommand returns the rollback function cli.command
+func RollbackFunctionCommand() cli.Command {
+	r := rollbackcmd{}
+	return cli.Command{
+		Name:      "function",
+		ShortName: "func",
+		Aliases:   []string{"f", "fn"},
+		Category:  "DEVELOPMENT COMMANDS",
+		Usage:     "Roll back a function to the image and settings it had before a deploy",
+		Description: "This command restores the image, memory, timeouts, config, annotations and triggers a function had before it was " +
+			"last deployed from this machine. Use --to to pick an older version or image from the deploy history.",
+		Before: func(c *cli.Context) error {
+			provider, err := client.CurrentProvider()
+			if err != nil {
+				return err
+			}
+			r.provider = provider
+			r.clientV2 = provider.APIClientv2()
+			return nil
+		},
+		ArgsUsage: "<app-name> <function-name>",
+		Action:    r.rollback,
+		Flags: []cli.Flag{
+			cli.StringFlag{
+				Name:        "to",
+				Usage:       "Version or image to roll back to, defaults to the one before the last deploy",
+				Destination: &r.to,
+			},
+		},
+		BashComplete: func(c *cli.Context) {
+			switch len(c.Args()) {
+			case 0:
+				apps.BashCompleteApps(c)
+			case 1:
+				function.BashCompleteFns(c)
+			}
+		},
+	}
+}
+
+type rollbackcmd struct {
+	clientV2 *v2Client.Fn
+	provider fnprovider.Provider
+	to       string
+}
+
+func (r *rollbackcmd) rollback(c *cli.Context) error {
+	appName := c.Args().Get(0)
+	fnName := function.WithoutSlash(c.Args().Get(1))
+	if appName == "" || fnName == "" {
+		return fmt.Errorf("app and function names are required, see `fn rollback function --help`")
+	}
+
+	app, err := apps.GetAppByName(r.clientV2, appName)
+	if err != nil {
+		return err
+	}
+	fn, err := function.GetFnByName(r.clientV2, app.ID, fnName)
+	if err != nil {
+		return err
+	}
+	entries, err := common.NewDefaultDeployHistory().FunctionEntries(common.NewDeployStateKey(r.provider, appName, fnName))
+	if err != nil {
+		return err
+	}
+	target, err := rollbackTarget(entries, fn.Image, r.to)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Rolling back function %s from image %s to %s...\n", fnName, fn.Image, target.Fn.Image)
+	if err := rollbackFunction(r.clientV2, r.provider, app, fn, target, os.Stderr); err != nil {
+		return err
+	}
+	fmt.Printf("Function %s rolled back to %s\n", fnName, target.Fn.Image)
+	return nil
+}
+
+// rollbackFunction restores a function to a snapshot and records the rollback in the deploy history
+func rollbackFunction(client *v2Client.Fn, provider fnprovider.Provider, app *models.App, fn *models.Fn, target *common.FnSnapshot, errOut io.Writer) error {
+	triggers, err := common.ListAllTriggersInFunc(client, fn)
+	if err != nil {
+		return err
+	}
+	if err := restoreFunction(client, app.ID, fn, triggers, target); err != nil {
+		return err
+	}
+
+	entry := common.NewDeployHistoryEntry(provider, common.DeployHistoryActionRollback, app.Name, fn.Name)
+	entry.OldImage = fn.Image
+	entry.NewImage = target.Fn.Image
+	entry.Previous = &common.FnSnapshot{Fn: fn, Triggers: triggers}
+	if err := common.NewDefaultDeployHistory().Append(entry); err != nil {
+		fmt.Fprintf(errOut, "Warning: unable to record deploy history: %v\n", err)
+	}
+	if err := common.InvalidateInvokeEndpointCacheForFunction(provider, app.Name, fn.Name); err != nil {
+		fmt.Fprintf(errOut, "Warning: unable to invalidate invoke endpoint cache: %v\n", err)
+	}
+	return nil
+}
+
+// rollbackTarget picks the snapshot to restore from the deploy history of a function, searching newest
+// first. Without a target it is the function as it was before the most recent deploy, skipping snapshots
+// of the current image and rollbacks, so rolling back twice goes further back instead of undoing the
+// first rollback. A target matches a full image or an image tag.
+func rollbackTarget(entries []common.DeployHistoryEntry, currentImage, to string) (*common.FnSnapshot, error) {
+	for i := len(entries) - 1; i >= 0; i-- {
+		prev := entries[i].Previous
+		if prev == nil || prev.Fn == nil {
+			continue
+		}
+		if to != "" {
+			if prev.Fn.Image == to || strings.HasSuffix(prev.Fn.Image, ":"+to) {
+				return prev, nil
+			}
+			continue
+		}
+		if entries[i].Action == common.DeployHistoryActionDeploy && prev.Fn.Image != currentImage {
+			return prev, nil
+		}
+	}
+	if to != "" {
+		return nil, fmt.Errorf("no deploy of %s was recorded for this function", to)
+	}
+	return nil, fmt.Errorf("no previous version was recorded for this function, only deploys made with this CLI in the current context can be rolled back")
+}
+
+// restoreFunction updates the function and its triggers to match the snapshot. Config keys,
+// annotations and triggers added since the snapshot are removed, server managed annotations are left alone.
+func restoreFunction(client *v2Client.Fn, appID string, current *models.Fn, currentTriggers []*models.Trigger, target *common.FnSnapshot) error {
+	fn := &models.Fn{
+		Image:       target.Fn.Image,
+		Memory:      target.Fn.Memory,
+		Timeout:     target.Fn.Timeout,
+		IdleTimeout: target.Fn.IdleTimeout,
+		Config:      withRemovedConfig(target.Fn.Config, current.Config),
+		Annotations: withRemovedAnnotations(target.Fn.Annotations, current.Annotations),
+	}
+	if err := function.PutFn(client, current.ID, fn); err != nil {
+		return err
+	}
+
+	existing := map[string]*models.Trigger{}
+	for _, t := range currentTriggers {
+		existing[t.Name] = t
+	}
+	keep := map[string]bool{}
+	for _, t := range target.Triggers {
+		keep[t.Name] = true
+		trig := &models.Trigger{
+			AppID:  appID,
+			FnID:   current.ID,
+			Name:   t.Name,
+			Type:   t.Type,
+			Source: t.Source,
+		}
+		cur, ok := existing[t.Name]
+		if !ok {
+			if err := trigger.CreateTrigger(client, trig); err != nil {
+				return err
+			}
+			continue
+		}
+		if cur.Type == t.Type && cur.Source == t.Source {
+			continue
+		}
+		trig.ID = cur.ID
+		if err := trigger.PutTrigger(client, trig); err != nil {
+			return err
+		}
+	}
+
+	var remove []*models.Trigger
+	for _, t := range currentTriggers {
+		if !keep[t.Name] {
+			remove = append(remove, t)
+		}
+	}
+	if len(remove) > 0 {
+		return common.DeleteTriggers(nil, client, remove)
+	}
+	return nil
+}
+
+// withRemovedAnnotations returns the snapshot annotations with an empty value for every annotation
+// added since, which removes it when the function is updated. Server managed annotations are skipped.
+func withRemovedAnnotations(snapshot, current map[string]interface{}) map[string]interface{} {
+	annotations := map[string]interface{}{}
+	for k, v := range snapshot {
+		if !strings.HasPrefix(k, serverAnnotationPrefix) {
+			annotations[k] = v
+		}
+	}
+	for k := range current {
+		if _, ok := snapshot[k]; !ok && !strings.HasPrefix(k, serverAnnotationPrefix) {
+			annotations[k] = ""
+		}
+	}
+	if len(annotations) == 0 {
+		return nil
+	}
+	return annotations
+}
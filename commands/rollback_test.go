@@ -0,0 +1,66 @@
+package commands
+
+import (
+	"testing"
+
+	"github.com/fnproject/cli/common"
+	models "github.com/fnproject/fn_go/modelsv2"
+)
+
+func snapshotOf(image string) *common.FnSnapshot {
+	return &common.FnSnapshot{Fn: &models.Fn{Image: image}}
+}
+
+func TestRollbackTarget(t *testing.T) {
+	entries := []common.DeployHistoryEntry{
+		{Action: common.DeployHistoryActionDeploy, NewImage: "hello:0.0.1"},
+		{Action: common.DeployHistoryActionDeploy, OldImage: "hello:0.0.1", NewImage: "hello:0.0.2", Previous: snapshotOf("hello:0.0.1")},
+		{Action: common.DeployHistoryActionDeploy, OldImage: "hello:0.0.2", NewImage: "hello:0.0.3", Previous: snapshotOf("hello:0.0.2")},
+	}
+
+	target, err := rollbackTarget(entries, "hello:0.0.3", "")
+	if err != nil || target.Fn.Image != "hello:0.0.2" {
+		t.Fatalf("expected rollback to hello:0.0.2, got %v %v", target, err)
+	}
+
+	// after rolling back, a second rollback goes further back
+	entries = append(entries, common.DeployHistoryEntry{
+		Action: common.DeployHistoryActionRollback, OldImage: "hello:0.0.3", NewImage: "hello:0.0.2", Previous: snapshotOf("hello:0.0.3"),
+	})
+	target, err = rollbackTarget(entries, "hello:0.0.2", "")
+	if err != nil || target.Fn.Image != "hello:0.0.1" {
+		t.Fatalf("expected rollback to hello:0.0.1, got %v %v", target, err)
+	}
+
+	target, err = rollbackTarget(entries, "hello:0.0.2", "0.0.3")
+	if err != nil || target.Fn.Image != "hello:0.0.3" {
+		t.Fatalf("expected rollback to version 0.0.3, got %v %v", target, err)
+	}
+	target, err = rollbackTarget(entries, "hello:0.0.2", "hello:0.0.1")
+	if err != nil || target.Fn.Image != "hello:0.0.1" {
+		t.Fatalf("expected rollback to image hello:0.0.1, got %v %v", target, err)
+	}
+
+	if _, err := rollbackTarget(entries, "hello:0.0.2", "0.0.9"); err == nil {
+		t.Fatal("expected an error for an unknown version")
+	}
+	if _, err := rollbackTarget(entries[:1], "hello:0.0.1", ""); err == nil {
+		t.Fatal("expected an error when no previous version was recorded")
+	}
+}
+
+func TestWithRemovedAnnotations(t *testing.T) {
+	snapshot := map[string]interface{}{"team": "a", "fnproject.io/fn/invokeEndpoint": "http://old"}
+	current := map[string]interface{}{"team": "b", "owner": "c", "fnproject.io/fn/invokeEndpoint": "http://new"}
+
+	got := withRemovedAnnotations(snapshot, current)
+	if len(got) != 2 || got["team"] != "a" || got["owner"] != "" {
+		t.Fatalf("expected team restored and owner removed, got %v", got)
+	}
+	if _, ok := got["fnproject.io/fn/invokeEndpoint"]; ok {
+		t.Fatalf("expected server managed annotations to be left alone, got %v", got)
+	}
+	if got := withRemovedAnnotations(nil, map[string]interface{}{"fnproject.io/fn/invokeEndpoint": "x"}); got != nil {
+		t.Fatalf("expected no annotations, got %v", got)
+	}
+}
@@ -0,0 +1,383 @@
+/*
+ * Copyright (c) 2019, 2020 Oracle and/or its affiliates. All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package commands
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/fnproject/cli/client"
+	"github.com/fnproject/cli/common"
+	"github.com/fnproject/cli/common/color"
+	"github.com/fnproject/cli/objects/app"
+	"github.com/fnproject/cli/objects/fn"
+	"github.com/urfave/cli"
+)
+
+const (
+	defaultFuncTestTimeout = 30 * time.Second
+	funcTestStartTimeout   = 60 * time.Second
+	funcTestListenerPath   = "/tmp/iofs/lsnr.sock"
+)
+
+// funcTestTarget is something a test case can be invoked against, either a
+// locally started function container or a function deployed to an Fn server.
+type funcTestTarget interface {
+	invoke(payload []byte, contentType string, timeout time.Duration) (int, []byte, error)
+	close()
+}
+
+// TestCommand returns test cli.command
+func TestCommand() cli.Command {
+	cmd := testcmd{}
+	return cli.Command{
+		Name:     "test",
+		Usage:    "\tRun the tests declared in func.yaml against a function",
+		Category: "DEVELOPMENT COMMANDS",
+		Description: "This command builds the function and runs each test case declared in the `tests` section of func.yaml " +
+			"against a locally started function container. With --app the tests are run against the function already deployed " +
+			"to that app (e.g. with `fn deploy --local`) instead.",
+		ArgsUsage: "[function-subdirectory]",
+		Flags:     cmd.flags(),
+		Action:    cmd.test,
+	}
+}
+
+type testcmd struct {
+	appName string
+	noCache bool
+	timeout time.Duration
+}
+
+func (t *testcmd) flags() []cli.Flag {
+	return []cli.Flag{
+		cli.StringFlag{
+			Name:        "app",
+			Usage:       "Run the tests against the function deployed to this app instead of a local container",
+			Destination: &t.appName,
+		},
+		cli.BoolFlag{
+			Name:        "verbose, v",
+			Usage:       "Verbose mode",
+			Destination: &common.CommandVerbose,
+		},
+		cli.BoolFlag{
+			Name:        "no-cache",
+			Usage:       "Don't use docker cache",
+			Destination: &t.noCache,
+		},
+		cli.StringSliceFlag{
+			Name:  "build-arg",
+			Usage: "Set build-time variables",
+		},
+		cli.DurationFlag{
+			Name:        "timeout",
+			Usage:       "Timeout for each test invocation, defaults to the function timeout or 30s",
+			Destination: &t.timeout,
+		},
+		cli.StringFlag{
+			Name:  "working-dir, w",
+			Usage: "Specify the working directory of the function to test, must be the full path.",
+		},
+	}
+}
+
+// test builds the function and runs its tests
+func (t *testcmd) test(c *cli.Context) error {
+	dir := common.GetDir(c)
+
+	path := c.Args().First()
+	if path != "" {
+		dir = filepath.Join(dir, path)
+	}
+
+	fpath, ff, err := common.FindAndParseFuncFileV20180708(dir)
+	if err != nil {
+		return err
+	}
+	if ff.Name == "" {
+		ff.Name = filepath.Base(dir)
+	}
+	if len(ff.Tests) == 0 {
+		return fmt.Errorf("no tests found in %s", fpath)
+	}
+
+	timeout := t.timeout
+	if timeout <= 0 {
+		timeout = defaultFuncTestTimeout
+		if ff.Timeout != nil && *ff.Timeout > 0 {
+			timeout = time.Duration(*ff.Timeout) * time.Second
+		}
+	}
+
+	var target funcTestTarget
+	if t.appName != "" {
+		target, err = newDeployedFuncTestTarget(t.appName, ff.Name)
+		if err != nil {
+			return err
+		}
+	} else {
+		ff, err = common.BuildFuncV20180708(common.IsVerbose(), fpath, ff, c.StringSlice("build-arg"), t.noCache, "", false)
+		if err != nil {
+			return err
+		}
+		target, err = startLocalFuncTestTarget(ff)
+		if err != nil {
+			return err
+		}
+	}
+	defer target.close()
+
+	return runFuncTests(os.Stdout, target, ff, timeout)
+}
+
+// runFuncTests runs every test in the func file against the target and writes a report,
+// returning an error when any of the tests failed.
+func runFuncTests(out io.Writer, target funcTestTarget, ff *common.FuncFileV20180708, timeout time.Duration) error {
+	fmt.Fprintf(out, "Running %d tests for function %s\n", len(ff.Tests), ff.Name)
+
+	contentType := ff.Content_type
+	failed := 0
+	for i, test := range ff.Tests {
+		start := time.Now()
+		err := runFuncTest(target, test, contentType, timeout)
+		elapsed := time.Since(start).Round(time.Millisecond)
+		if err != nil {
+			failed++
+			fmt.Fprintf(out, "  %s  %s (%v): %v\n", color.Red("FAIL"), test.TestName(i), elapsed, err)
+			continue
+		}
+		fmt.Fprintf(out, "  %s  %s (%v)\n", color.Cyan("PASS"), test.TestName(i), elapsed)
+	}
+
+	fmt.Fprintf(out, "%d passed, %d failed\n", len(ff.Tests)-failed, failed)
+	if failed > 0 {
+		return fmt.Errorf("%d of %d tests failed", failed, len(ff.Tests))
+	}
+	return nil
+}
+
+func runFuncTest(target funcTestTarget, test common.FFTest, contentType string, timeout time.Duration) error {
+	payload, err := test.InputBody()
+	if err != nil {
+		return err
+	}
+	status, body, err := target.invoke(payload, contentType, timeout)
+	if err != nil {
+		return err
+	}
+	return test.Check(status, body)
+}
+
+// localFuncTestTarget runs the function image in a container started by the
+// container engine and talks to the FDK over its unix socket, the same way the Fn server does.
+type localFuncTestTarget struct {
+	containerEngineType string
+	containerID         string
+	iofsDir             string
+	httpClient          *http.Client
+}
+
+func startLocalFuncTestTarget(ff *common.FuncFileV20180708) (*localFuncTestTarget, error) {
+	containerEngineType, err := common.GetRunnableContainerEngineType()
+	if err != nil {
+		return nil, err
+	}
+
+	iofsDir, err := ioutil.TempDir("", "fn-test-iofs")
+	if err != nil {
+		return nil, err
+	}
+	// the function may run as a non root user inside the container
+	if err := os.Chmod(iofsDir, 0777); err != nil {
+		os.RemoveAll(iofsDir)
+		return nil, err
+	}
+
+	args := []string{"run", "-d", "--rm",
+		"-v", fmt.Sprintf("%s:%s:z", iofsDir, filepath.Dir(funcTestListenerPath)),
+		"-e", "FN_LISTENER=unix:" + funcTestListenerPath,
+		"-e", "FN_FORMAT=http-stream",
+		"-e", "FN_APP_NAME=fn-test",
+		"-e", "FN_FN_NAME=" + ff.Name,
+		"-e", "FN_TYPE=sync",
+	}
+	if ff.Memory > 0 {
+		args = append(args, "-e", fmt.Sprintf("FN_MEMORY=%d", ff.Memory))
+	}
+	for k, v := range ff.Config {
+		args = append(args, "-e", k+"="+v)
+	}
+	args = append(args, ff.ImageNameV20180708())
+
+	fmt.Printf("Starting function container from image %s\n", ff.ImageNameV20180708())
+	var stderr bytes.Buffer
+	cmd := exec.Command(containerEngineType, args...)
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		os.RemoveAll(iofsDir)
+		return nil, fmt.Errorf("error starting function container: %v %s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	sock := filepath.Join(iofsDir, filepath.Base(funcTestListenerPath))
+	target := &localFuncTestTarget{
+		containerEngineType: containerEngineType,
+		containerID:         strings.TrimSpace(string(out)),
+		iofsDir:             iofsDir,
+		httpClient: &http.Client{
+			Transport: &http.Transport{
+				DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+					var d net.Dialer
+					return d.DialContext(ctx, "unix", sock)
+				},
+			},
+		},
+	}
+
+	deadline := time.Now().Add(funcTestStartTimeout)
+	for !common.Exists(sock) {
+		if time.Now().After(deadline) {
+			logs, _ := exec.Command(containerEngineType, "logs", target.containerID).CombinedOutput()
+			target.close()
+			return nil, fmt.Errorf("function container did not start listening within %v: %s", funcTestStartTimeout, strings.TrimSpace(string(logs)))
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	return target, nil
+}
+
+func (l *localFuncTestTarget) invoke(payload []byte, contentType string, timeout time.Duration) (int, []byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	req, err := http.NewRequest(http.MethodPost, "http://localhost/call", bytes.NewReader(payload))
+	if err != nil {
+		return 0, nil, err
+	}
+	req = req.WithContext(ctx)
+	if contentType == "" {
+		contentType = "text/plain"
+	}
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set("Fn-Call-Id", newFuncTestCallID())
+	req.Header.Set("Fn-Deadline", time.Now().Add(timeout).UTC().Format(time.RFC3339))
+
+	resp, err := l.httpClient.Do(req)
+	if err != nil {
+		if errors.Is(err, context.DeadlineExceeded) {
+			return 0, nil, fmt.Errorf("timed out after %v", timeout)
+		}
+		return 0, nil, fmt.Errorf("error invoking function container: %v", err)
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	return resp.StatusCode, body, err
+}
+
+func (l *localFuncTestTarget) close() {
+	exec.Command(l.containerEngineType, "rm", "-f", l.containerID).Run()
+	os.RemoveAll(l.iofsDir)
+}
+
+func newFuncTestCallID() string {
+	b := make([]byte, 13)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// deployedFuncTestTarget invokes a function deployed to the Fn server of the current context.
+type deployedFuncTestTarget struct {
+	invokeURL string
+	invokeFn  func(client.InvokeRequest) (*http.Response, error)
+}
+
+func newDeployedFuncTestTarget(appName, fnName string) (*deployedFuncTestTarget, error) {
+	provider, err := client.CurrentProvider()
+	if err != nil {
+		return nil, err
+	}
+	appObj, err := app.GetAppByName(provider.APIClientv2(), appName)
+	if err != nil {
+		return nil, err
+	}
+	fnObj, err := fn.GetFnByName(provider.APIClientv2(), appObj.ID, fnName)
+	if err != nil {
+		return nil, err
+	}
+	invokeURL, ok := fnObj.Annotations[FnInvokeEndpointAnnotation].(string)
+	if !ok {
+		return nil, fmt.Errorf("Fn invoke url annotation not present, %s", FnInvokeEndpointAnnotation)
+	}
+	fmt.Printf("Testing function %s deployed to app %s\n", fnName, appName)
+	return &deployedFuncTestTarget{
+		invokeURL: invokeURL,
+		invokeFn: func(req client.InvokeRequest) (*http.Response, error) {
+			return client.Invoke(provider, req)
+		},
+	}, nil
+}
+
+func (d *deployedFuncTestTarget) invoke(payload []byte, contentType string, timeout time.Duration) (int, []byte, error) {
+	return invokeWithTimeout(d.invokeFn, client.InvokeRequest{
+		URL:         d.invokeURL,
+		Content:     bytes.NewReader(payload),
+		ContentType: contentType,
+	}, timeout)
+}
+
+// invokeWithTimeout sends a request with invokeFn and reads the whole response, giving up after timeout
+func invokeWithTimeout(invokeFn func(client.InvokeRequest) (*http.Response, error), req client.InvokeRequest, timeout time.Duration) (int, []byte, error) {
+	type result struct {
+		status int
+		body   []byte
+		err    error
+	}
+	done := make(chan result, 1)
+	go func() {
+		resp, err := invokeFn(req)
+		if err != nil {
+			done <- result{err: err}
+			return
+		}
+		defer resp.Body.Close()
+		body, err := ioutil.ReadAll(resp.Body)
+		done <- result{status: resp.StatusCode, body: body, err: err}
+	}()
+
+	select {
+	case r := <-done:
+		return r.status, r.body, r.err
+	case <-time.After(timeout):
+		return 0, nil, fmt.Errorf("timed out after %v", timeout)
+	}
+}
+
+func (d *deployedFuncTestTarget) close() {}
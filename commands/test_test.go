@@ -0,0 +1,80 @@
+package commands
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fnproject/cli/common"
+)
+
+type fakeFuncTestTarget struct {
+	responses map[string]fakeFuncTestResponse
+	payloads  []string
+}
+
+type fakeFuncTestResponse struct {
+	status int
+	body   string
+	err    error
+}
+
+func (f *fakeFuncTestTarget) invoke(payload []byte, contentType string, timeout time.Duration) (int, []byte, error) {
+	f.payloads = append(f.payloads, string(payload))
+	r := f.responses[string(payload)]
+	return r.status, []byte(r.body), r.err
+}
+
+func (f *fakeFuncTestTarget) close() {}
+
+func TestRunFuncTestsReportsFailures(t *testing.T) {
+	boom := "boom"
+	ff := &common.FuncFileV20180708{
+		Name: "hello",
+		Tests: []common.FFTest{
+			{Name: "ok", Input: &common.InputMap{Body: "a"}, Output: &common.OutputMap{Body: "A"}},
+			{Name: "mismatch", Input: &common.InputMap{Body: "b"}, Output: &common.OutputMap{Body: "B"}},
+			{Name: "expected error", Input: &common.InputMap{Body: "c"}, Err: &boom},
+			{Name: "unreachable", Input: &common.InputMap{Body: "d"}},
+		},
+	}
+	target := &fakeFuncTestTarget{responses: map[string]fakeFuncTestResponse{
+		"a": {status: 200, body: "A"},
+		"b": {status: 200, body: "not B"},
+		"c": {status: 502, body: `{"message":"boom"}`},
+		"d": {err: errors.New("connection refused")},
+	}}
+
+	var out bytes.Buffer
+	err := runFuncTests(&out, target, ff, time.Second)
+	if err == nil || err.Error() != "2 of 4 tests failed" {
+		t.Fatalf("expected 2 failures, got %v", err)
+	}
+	if strings.Join(target.payloads, "") != "abcd" {
+		t.Fatalf("expected every test to be invoked in order, got %v", target.payloads)
+	}
+	report := out.String()
+	for _, want := range []string{"Running 4 tests for function hello", "mismatch", "connection refused", "2 passed, 2 failed"} {
+		if !strings.Contains(report, want) {
+			t.Fatalf("expected report to contain %q, got:\n%s", want, report)
+		}
+	}
+}
+
+func TestRunFuncTestsSucceeds(t *testing.T) {
+	ff := &common.FuncFileV20180708{
+		Name:  "hello",
+		Tests: []common.FFTest{{Input: &common.InputMap{Body: "a"}, Output: &common.OutputMap{Body: "A"}}},
+	}
+	target := &fakeFuncTestTarget{responses: map[string]fakeFuncTestResponse{"a": {status: 200, body: "A\n"}}}
+
+	var out bytes.Buffer
+	if err := runFuncTests(&out, target, ff, time.Second); err != nil {
+		t.Fatalf("runFuncTests() error = %v, output:\n%s", err, out.String())
+	}
+	if !strings.Contains(out.String(), "1 passed, 0 failed") {
+		t.Fatalf("unexpected report:\n%s", out.String())
+	}
+}
@@ -0,0 +1,148 @@
+/*
+ * Copyright (c) 2019, 2020 Oracle and/or its affiliates. All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package commands
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	client "github.com/fnproject/cli/client"
+	common "github.com/fnproject/cli/common"
+	apps "github.com/fnproject/cli/objects/app"
+	function "github.com/fnproject/cli/objects/fn"
+	v2Client "github.com/fnproject/fn_go/clientv2"
+	"github.com/urfave/cli"
+)
+
+// VerifyCommand returns verify cli.command
+func VerifyCommand() cli.Command {
+	return cli.Command{
+		Name:         "verify",
+		Usage:        "\tVerify the signature of an object",
+		Category:     "DEVELOPMENT COMMANDS",
+		Hidden:       false,
+		ArgsUsage:    "<subcommand>",
+		Description:  "This command verifies the signature of an object ('image').",
+		Subcommands:  GetCommands(VerifyCmds),
+		BashComplete: common.DefaultBashComplete,
+	}
+}
+
+// VerifyImageCommand returns the verify image cli.command
+func VerifyImageCommand() cli.Command {
+	v := verifyimagecmd{}
+	return cli.Command{
+		Name:     "image",
+		Category: "DEVELOPMENT COMMANDS",
+		Usage:    "Verify the signature of the image a function is deployed with",
+		Description: "This command checks that the image of a deployed function is signed by the key in signing_details of func.yaml, " +
+			"or by the cosign public key given with --key.",
+		Before: func(c *cli.Context) error {
+			provider, err := client.CurrentProvider()
+			if err != nil {
+				return err
+			}
+			v.clientV2 = provider.APIClientv2()
+			return nil
+		},
+		ArgsUsage: "<app-name> <function-name>",
+		Action:    v.verify,
+		Flags: []cli.Flag{
+			cli.StringFlag{
+				Name:        "key",
+				Usage:       "cosign public key, env://VAR or KMS URI that signs the image, instead of signing_details of func.yaml",
+				Destination: &v.key,
+			},
+			cli.BoolFlag{
+				Name:        "transparency-log",
+				Usage:       "Require the signature to be recorded in the Rekor transparency log, with --key",
+				Destination: &v.transparencyLog,
+			},
+			cli.StringFlag{
+				Name:        "working-dir,w",
+				Usage:       "Specify the working directory of the func.yaml with signing_details",
+				Destination: &v.workingDir,
+			},
+		},
+		BashComplete: func(c *cli.Context) {
+			switch len(c.Args()) {
+			case 0:
+				apps.BashCompleteApps(c)
+			case 1:
+				function.BashCompleteFns(c)
+			}
+		},
+	}
+}
+
+type verifyimagecmd struct {
+	clientV2        *v2Client.Fn
+	key             string
+	transparencyLog bool
+	workingDir      string
+}
+
+func (v *verifyimagecmd) verify(c *cli.Context) error {
+	appName := c.Args().Get(0)
+	fnName := function.WithoutSlash(c.Args().Get(1))
+	if appName == "" || fnName == "" {
+		return fmt.Errorf("app and function names are required, see `fn verify image --help`")
+	}
+	signingDetails, err := v.signingDetails()
+	if err != nil {
+		return err
+	}
+	signer, err := newImageSigner(signingDetails, os.Stdout, os.Stderr)
+	if err != nil {
+		return err
+	}
+	if signer == nil {
+		return fmt.Errorf("no signing key to verify with, pass --key or set signing_details in func.yaml, OCI KMS keys need an oracle context")
+	}
+
+	app, err := apps.GetAppByName(v.clientV2, appName)
+	if err != nil {
+		return err
+	}
+	fn, err := function.GetFnByName(v.clientV2, app.ID, fnName)
+	if err != nil {
+		return err
+	}
+	digest, err := signer.VerifyImage(fn.Image)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Image %s (%s) of function %s is signed by %s\n", fn.Image, digest, fnName, signingKeyDescription(signingDetails))
+	return nil
+}
+
+// signingDetails returns the key given by the flags, or signing_details of func.yaml
+func (v *verifyimagecmd) signingDetails() (common.SigningDetails, error) {
+	if v.key != "" {
+		return common.SigningDetails{Provider: common.SigningProviderCosign, Key: v.key, PublicKey: v.key, TransparencyLog: v.transparencyLog}, nil
+	}
+	dir := v.workingDir
+	if dir == "" {
+		dir = common.GetWd()
+	}
+	fpath, ff, err := common.FindAndParseFuncFileV20180708(dir)
+	if err != nil {
+		return common.SigningDetails{}, fmt.Errorf("no --key given and no func.yaml with signing_details in %s: %v", dir, err)
+	}
+	return ff.SigningDetails.WithKeysRelativeTo(filepath.Dir(fpath)), nil
+}
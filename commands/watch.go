@@ -17,7 +17,6 @@
 package commands
 
 import (
-	"bufio"
 	"context"
 	"errors"
 	"fmt"
@@ -39,7 +38,7 @@ import (
 
 const (
 	defaultWatchDebounce = 500 * time.Millisecond
-	fnIgnoreFileName     = ".fnignore"
+	fnIgnoreFileName     = common.FnIgnoreFileName
 )
 
 var watchFuncYamlVersionLine = regexp.MustCompile(`(?m)^\s*version\s*:\s*.*$`)
@@ -285,69 +284,18 @@ func loadWatchIgnore(root string, extra []string) (watchIgnore, error) {
 	// defaults requested by user
 	patterns := []string{".git", ".fn", "node_modules", "target", "dist", "vendor", "Dockerfile-fn-tmp*"}
 
-	fnIgnorePath := filepath.Join(root, fnIgnoreFileName)
-	if f, err := os.Open(fnIgnorePath); err == nil {
-		defer f.Close()
-		s := bufio.NewScanner(f)
-		for s.Scan() {
-			line := strings.TrimSpace(s.Text())
-			if line == "" || strings.HasPrefix(line, "#") {
-				continue
-			}
-			patterns = append(patterns, line)
-		}
-		if err := s.Err(); err != nil {
-			return watchIgnore{}, err
-		}
-		return watchIgnore{root: root, patterns: append(patterns, extra...), hasFnIgnore: true}, nil
-	} else if !os.IsNotExist(err) {
+	fnIgnorePatterns, hasFnIgnore, err := common.LoadFnIgnore(root)
+	if err != nil {
 		return watchIgnore{}, err
 	}
+	patterns = append(patterns, fnIgnorePatterns...)
 
-	return watchIgnore{root: root, patterns: append(patterns, extra...), hasFnIgnore: false}, nil
+	return watchIgnore{root: root, patterns: append(patterns, extra...), hasFnIgnore: hasFnIgnore}, nil
 }
 
 func (w watchIgnore) shouldIgnore(root string, path string, isDir bool) bool {
-	// ignore if it matches any pattern against:
-	// - any path segment
-	// - or the relative path
-	rel, err := filepath.Rel(root, path)
-	if err != nil {
-		rel = path
-	}
-	rel = filepath.ToSlash(rel)
-	segs := strings.Split(rel, "/")
-
-	for _, p := range w.patterns {
-		p = strings.TrimSpace(p)
-		if p == "" {
-			continue
-		}
-
-		// segment match (simple, fast)
-		for _, s := range segs {
-			if s == p {
-				return true
-			}
-			if !strings.Contains(p, "/") {
-				if ok, _ := filepath.Match(p, s); ok {
-					return true
-				}
-			}
-		}
-
-		// glob against rel path
-		if ok, _ := filepath.Match(p, rel); ok {
-			return true
-		}
-		// Also try matching with OS separator style
-		if ok, _ := filepath.Match(p, filepath.FromSlash(rel)); ok {
-			return true
-		}
-	}
-
 	_ = isDir
-	return false
+	return common.MatchesIgnorePattern(w.patterns, root, path)
 }
 
 func addRecursiveWatches(watcher *fsnotify.Watcher, root string, ignore watchIgnore) error {
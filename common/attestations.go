@@ -0,0 +1,379 @@
+/*
+ * Copyright (c) 2019, 2020 Oracle and/or its affiliates. All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package common
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/fnproject/cli/config"
+)
+
+// SBOM formats
+const (
+	SBOMFormatSPDX      = "spdx"
+	SBOMFormatCycloneDX = "cyclonedx"
+)
+
+const (
+	attestationsDirName = "attestations"
+
+	provenanceBuildType     = "https://fnproject.io/fn-cli/build/v1"
+	provenancePredicateType = "https://slsa.dev/provenance/v1"
+	inTotoStatementType     = "https://in-toto.io/Statement/v1"
+
+	spdxMediaType       = "application/spdx+json"
+	cycloneDXMediaType  = "application/vnd.cyclonedx+json"
+	provenanceMediaType = "application/vnd.in-toto+json"
+)
+
+// Attestations are the documents generated for the image of a function after it is built
+type Attestations struct {
+	// SBOM is the format of the software bill of materials of the image, spdx or cyclonedx
+	SBOM string `yaml:"sbom,omitempty" json:"sbom,omitempty"`
+	// Provenance records how the image was built
+	Provenance bool `yaml:"provenance,omitempty" json:"provenance,omitempty"`
+	// Push attaches the documents to the pushed image as OCI artifacts
+	Push bool `yaml:"push,omitempty" json:"push,omitempty"`
+}
+
+// Validate checks the SBOM format
+func (a *Attestations) Validate() error {
+	switch a.SBOM {
+	case "", SBOMFormatSPDX, SBOMFormatCycloneDX:
+		return nil
+	}
+	return fmt.Errorf("unsupported SBOM format %q, use %s or %s", a.SBOM, SBOMFormatSPDX, SBOMFormatCycloneDX)
+}
+
+// IsEmpty reports whether no document is generated
+func (a Attestations) IsEmpty() bool {
+	return a.SBOM == "" && !a.Provenance
+}
+
+// AttestationsDir returns the directory the attestations of a function are written to
+func AttestationsDir(funcDir string) string {
+	return filepath.Join(funcDir, ".fn", attestationsDirName)
+}
+
+// attestationBuild describes a finished build of a function image
+type attestationBuild struct {
+	fpath               string
+	ff                  *FuncFileV20180708
+	attestations        Attestations
+	buildArgs           []string
+	localDebug          bool
+	shape               string
+	containerEngineType string
+	pushed              bool
+	startedOn           time.Time
+	finishedOn          time.Time
+	// archive is where a local build wrote the image, when the container engine doesn't keep it
+	archive string
+}
+
+// writeAttestationsV20180708 writes the SBOM and provenance of a built function image next to the function,
+// and attaches them to the image when it was pushed and it is asked to.
+func writeAttestationsV20180708(b attestationBuild, out, errOut io.Writer) error {
+	a := b.attestations
+	if err := a.Validate(); err != nil {
+		return err
+	}
+	if a.IsEmpty() {
+		return nil
+	}
+	dir := AttestationsDir(filepath.Dir(b.fpath))
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	image := b.ff.ImageNameV20180708()
+	base := filepath.Join(dir, fmt.Sprintf("%s-%s", invalidBuildLogChars.ReplaceAllString(b.ff.Name, "_"), b.ff.Version))
+
+	type artifact struct{ path, mediaType string }
+	var artifacts []artifact
+	if a.SBOM != "" {
+		path, mediaType := base+".spdx.json", spdxMediaType
+		if a.SBOM == SBOMFormatCycloneDX {
+			path, mediaType = base+".cdx.json", cycloneDXMediaType
+		}
+		source, err := sbomSource(b.containerEngineType, image, b.pushed, b.archive)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "Generating %s SBOM of %s...\n", a.SBOM, image)
+		if err := generateSBOM(source, a.SBOM, path, errOut); err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "SBOM written to %s\n", path)
+		artifacts = append(artifacts, artifact{path, mediaType})
+	}
+	if a.Provenance {
+		provenance, err := buildProvenance(b)
+		if err != nil {
+			return err
+		}
+		content, err := json.MarshalIndent(provenance, "", "  ")
+		if err != nil {
+			return err
+		}
+		path := base + ".provenance.json"
+		if err := ioutil.WriteFile(path, content, 0644); err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "Provenance written to %s\n", path)
+		artifacts = append(artifacts, artifact{path, provenanceMediaType})
+	}
+
+	if !a.Push {
+		return nil
+	}
+	if !b.pushed {
+		fmt.Fprintf(errOut, "Warning: %s was not pushed, its attestations are only written locally\n", image)
+		return nil
+	}
+	for _, art := range artifacts {
+		if err := attachArtifact(image, art.path, art.mediaType, out, errOut); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sbomSource returns the syft source of an image: the registry for pushed images, the archive of local builds
+// that wrote one, and the local image store otherwise
+func sbomSource(containerEngineType, image string, pushed bool, archive string) (string, error) {
+	if pushed {
+		return "registry:" + image, nil
+	}
+	if archive != "" {
+		// kaniko writes a docker archive, the other builders an OCI archive
+		if containerEngineType == config.ContainerEngineKaniko {
+			return "docker-archive:" + archive, nil
+		}
+		return "oci-archive:" + archive, nil
+	}
+	if config.IsDaemonlessContainerEngine(containerEngineType) {
+		return "", fmt.Errorf("%s keeps the image where syft can't read it, an SBOM can only be generated for pushed images", containerEngineType)
+	}
+	return containerEngineType + ":" + image, nil
+}
+
+// generateSBOM catalogs the OS packages and the language dependencies installed in an image with syft
+func generateSBOM(source, format, path string, errOut io.Writer) error {
+	if _, err := exec.LookPath("syft"); err != nil {
+		return fmt.Errorf("generating an SBOM needs syft on your PATH: %v", err)
+	}
+	output := "spdx-json"
+	if format == SBOMFormatCycloneDX {
+		output = "cyclonedx-json"
+	}
+	cmd := exec.Command("syft", "scan", source, "-o", output+"="+path)
+	cmd.Stderr = errOut
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("error running syft: %v", err)
+	}
+	return nil
+}
+
+// attachArtifact pushes a document to the registry of an image as an OCI artifact referring to it
+func attachArtifact(image, path, mediaType string, out, errOut io.Writer) error {
+	if _, err := exec.LookPath("oras"); err != nil {
+		return fmt.Errorf("pushing attestations needs oras on your PATH: %v", err)
+	}
+	// oras takes the file relative to its working directory, and stores its base name as the title
+	cmd := exec.Command("oras", "attach", "--artifact-type", mediaType, image, filepath.Base(path)+":"+mediaType)
+	cmd.Dir = filepath.Dir(path)
+	cmd.Stdout = out
+	cmd.Stderr = errOut
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("error attaching %s to %s: %v", filepath.Base(path), image, err)
+	}
+	return nil
+}
+
+// provenance is an in-toto statement with a SLSA provenance predicate
+type provenance struct {
+	Type          string              `json:"_type"`
+	Subject       []provenanceSubject `json:"subject"`
+	PredicateType string              `json:"predicateType"`
+	Predicate     provenancePredicate `json:"predicate"`
+}
+
+type provenanceSubject struct {
+	Name   string            `json:"name"`
+	Digest map[string]string `json:"digest,omitempty"`
+}
+
+type provenancePredicate struct {
+	BuildDefinition struct {
+		BuildType            string                 `json:"buildType"`
+		ExternalParameters   map[string]interface{} `json:"externalParameters"`
+		InternalParameters   map[string]interface{} `json:"internalParameters,omitempty"`
+		ResolvedDependencies []provenanceDependency `json:"resolvedDependencies,omitempty"`
+	} `json:"buildDefinition"`
+	RunDetails struct {
+		Builder struct {
+			ID      string            `json:"id"`
+			Version map[string]string `json:"version,omitempty"`
+		} `json:"builder"`
+		Metadata struct {
+			StartedOn  time.Time `json:"startedOn"`
+			FinishedOn time.Time `json:"finishedOn"`
+		} `json:"metadata"`
+	} `json:"runDetails"`
+}
+
+type provenanceDependency struct {
+	Name   string            `json:"name,omitempty"`
+	URI    string            `json:"uri,omitempty"`
+	Digest map[string]string `json:"digest,omitempty"`
+}
+
+// buildProvenance records the func file, base images and build args of a build
+func buildProvenance(b attestationBuild) (*provenance, error) {
+	funcfile, err := ioutil.ReadFile(b.fpath)
+	if err != nil {
+		return nil, err
+	}
+	dir := filepath.Dir(b.fpath)
+	image := b.ff.ImageNameV20180708()
+
+	p := &provenance{Type: inTotoStatementType, PredicateType: provenancePredicateType}
+	subject := provenanceSubject{Name: image}
+	if digest, ok := PushedImageDigest(image); ok {
+		subject.Digest = digestMap(digest)
+	}
+	p.Subject = []provenanceSubject{subject}
+
+	def := &p.Predicate.BuildDefinition
+	def.BuildType = provenanceBuildType
+	def.ExternalParameters = map[string]interface{}{
+		"funcfile":   string(funcfile),
+		"buildArgs":  b.buildArgs,
+		"localDebug": b.localDebug,
+	}
+	if b.shape != "" {
+		def.ExternalParameters["shape"] = b.shape
+	}
+	def.InternalParameters = map[string]interface{}{"containerEngine": b.containerEngineType}
+
+	def.ResolvedDependencies = append(def.ResolvedDependencies, provenanceDependency{
+		Name:   filepath.Base(b.fpath),
+		Digest: map[string]string{"sha256": sha256Hex(funcfile)},
+	})
+	if commit := GitCommitOf(dir); commit != "" {
+		def.ResolvedDependencies = append(def.ResolvedDependencies, provenanceDependency{Name: "source", Digest: map[string]string{"gitCommit": commit}})
+	}
+	baseImages, err := baseImagesV20180708(dir, b.ff)
+	if err != nil {
+		return nil, err
+	}
+	for _, i := range baseImages {
+		dependency := provenanceDependency{URI: "docker://" + i}
+		// images pinned by func.lock record their digest
+		if at := strings.Index(i, "@"); at >= 0 {
+			dependency = provenanceDependency{URI: "docker://" + i[:at], Digest: digestMap(i[at+1:])}
+		}
+		def.ResolvedDependencies = append(def.ResolvedDependencies, dependency)
+	}
+
+	run := &p.Predicate.RunDetails
+	run.Builder.ID = "https://github.com/fnproject/cli"
+	run.Builder.Version = map[string]string{"fn": config.Version}
+	run.Metadata.StartedOn = b.startedOn.UTC()
+	run.Metadata.FinishedOn = b.finishedOn.UTC()
+	return p, nil
+}
+
+// baseImagesV20180708 returns the images a function image is built from: the FROM images of its
+// Dockerfile, or the build and run images of its runtime as pinned by func.lock.
+func baseImagesV20180708(dir string, ff *FuncFileV20180708) ([]string, error) {
+	dockerfile := filepath.Join(dir, "Dockerfile")
+	if !Exists(dockerfile) {
+		lock, err := LoadFuncLock(dir)
+		if err != nil {
+			return nil, err
+		}
+		var images []string
+		for _, i := range []string{ff.Build_image, ff.Run_image} {
+			if i, err = lock.Pin(i); err != nil {
+				return nil, err
+			}
+			if i != "" && (len(images) == 0 || images[0] != i) {
+				images = append(images, i)
+			}
+		}
+		return images, nil
+	}
+
+	f, err := os.Open(dockerfile)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	stages := map[string]bool{}
+	seen := map[string]bool{}
+	var images []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 || !strings.EqualFold(fields[0], "FROM") {
+			continue
+		}
+		// skip flags such as --platform
+		args := fields[1:]
+		for len(args) > 0 && strings.HasPrefix(args[0], "--") {
+			args = args[1:]
+		}
+		if len(args) == 0 {
+			continue
+		}
+		image := args[0]
+		// FROM an earlier stage is not a base image
+		if !stages[strings.ToLower(image)] && !seen[image] && image != "scratch" {
+			seen[image] = true
+			images = append(images, image)
+		}
+		if len(args) >= 3 && strings.EqualFold(args[1], "AS") {
+			stages[strings.ToLower(args[2])] = true
+		}
+	}
+	return images, scanner.Err()
+}
+
+func digestMap(digest string) map[string]string {
+	parts := strings.SplitN(digest, ":", 2)
+	if len(parts) != 2 {
+		return nil
+	}
+	return map[string]string{parts[0]: parts[1]}
+}
+
+func sha256Hex(b []byte) string {
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
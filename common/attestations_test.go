@@ -0,0 +1,130 @@
+package common
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/fnproject/cli/config"
+)
+
+func TestBuildOptionsAttestations(t *testing.T) {
+	ff := &FuncFileV20180708{Attestations: &Attestations{SBOM: SBOMFormatSPDX, Push: true}}
+
+	opts := BuildOptions{Attestations: Attestations{SBOM: SBOMFormatCycloneDX, Provenance: true}}
+	expected := Attestations{SBOM: SBOMFormatCycloneDX, Provenance: true, Push: true}
+	if a := opts.ForFuncFileV20180708(ff).Attestations; a != expected {
+		t.Fatalf("expected %+v, got %+v", expected, a)
+	}
+	if a := opts.ForFuncFileV20180708(&FuncFileV20180708{}).Attestations; a.IsEmpty() {
+		t.Fatal("expected the flags to ask for attestations without an attestations section")
+	}
+	if err := (&Attestations{SBOM: "syft"}).Validate(); err == nil {
+		t.Fatal("expected an unknown SBOM format to be rejected")
+	}
+}
+
+func TestSBOMSource(t *testing.T) {
+	if s, _ := sbomSource("docker", "hello:0.0.1", false, ""); s != "docker:hello:0.0.1" {
+		t.Fatalf("expected the docker image store, got %s", s)
+	}
+	if s, _ := sbomSource("podman", "reg/hello:0.0.1", true, ""); s != "registry:reg/hello:0.0.1" {
+		t.Fatalf("expected the registry for a pushed image, got %s", s)
+	}
+	if _, err := sbomSource(config.ContainerEngineKaniko, "hello:0.0.1", false, ""); err == nil {
+		t.Fatal("expected an error for an image kaniko did not push")
+	}
+	if _, err := sbomSource(config.ContainerEngineBuildah, "hello:0.0.1", false, ""); err == nil {
+		t.Fatal("expected an error for an image buildah did not push")
+	}
+
+	// local builds that write an archive are scanned from it, not from an image store that may not have them
+	if s, _ := sbomSource("docker", "hello:0.0.1", false, "/home/me/.fn/images/hello.tar"); s != "oci-archive:/home/me/.fn/images/hello.tar" {
+		t.Fatalf("expected the OCI archive of a build of several platforms, got %s", s)
+	}
+	if s, _ := sbomSource(config.ContainerEngineKaniko, "hello:0.0.1", false, "/tmp/hello.tar"); s != "docker-archive:/tmp/hello.tar" {
+		t.Fatalf("expected the tarball of a local kaniko build, got %s", s)
+	}
+}
+
+func TestBaseImagesV20180708(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fn-base-images")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	ff := &FuncFileV20180708{Build_image: "fnproject/go:dev", Run_image: "fnproject/go"}
+	images, err := baseImagesV20180708(dir, ff)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(images, []string{"fnproject/go:dev", "fnproject/go"}) {
+		t.Fatalf("unexpected base images of a runtime %v", images)
+	}
+
+	ioutil.WriteFile(filepath.Join(dir, "Dockerfile"), []byte(`FROM --platform=linux/amd64 golang:1.22 AS build
+RUN go build
+from build as test
+FROM gcr.io/distroless/base
+COPY --from=build /func /func
+FROM scratch
+`), 0644)
+	images, err = baseImagesV20180708(dir, ff)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(images, []string{"golang:1.22", "gcr.io/distroless/base"}) {
+		t.Fatalf("unexpected base images of a Dockerfile %v", images)
+	}
+}
+
+func TestBuildProvenance(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fn-provenance")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	ff := &FuncFileV20180708{Schema_version: LatestYamlVersion, Name: "hello", Version: "0.0.2", Runtime: "go", Build_image: "fnproject/go:dev", Run_image: "fnproject/go"}
+	fpath := filepath.Join(dir, "func.yaml")
+	if err := EncodeFuncFileV20180708YAML(fpath, ff); err != nil {
+		t.Fatal(err)
+	}
+	funcfile, _ := ioutil.ReadFile(fpath)
+
+	started := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	p, err := buildProvenance(attestationBuild{
+		fpath: fpath, ff: ff, buildArgs: []string{"A=1"}, shape: "GENERIC_X86",
+		containerEngineType: "docker", startedOn: started, finishedOn: started.Add(time.Minute),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := json.Marshal(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var doc map[string]interface{}
+	json.Unmarshal(b, &doc)
+	if doc["_type"] != inTotoStatementType || doc["predicateType"] != provenancePredicateType {
+		t.Fatalf("unexpected statement %s", b)
+	}
+	def := p.Predicate.BuildDefinition
+	if def.ExternalParameters["funcfile"] != string(funcfile) || !reflect.DeepEqual(def.ExternalParameters["buildArgs"], []string{"A=1"}) {
+		t.Fatalf("expected func.yaml and the build args in the parameters, got %v", def.ExternalParameters)
+	}
+	if def.ResolvedDependencies[0].Digest["sha256"] != sha256Hex(funcfile) {
+		t.Fatalf("expected the digest of func.yaml, got %+v", def.ResolvedDependencies[0])
+	}
+	last := def.ResolvedDependencies[len(def.ResolvedDependencies)-1]
+	if last.URI != "docker://fnproject/go" {
+		t.Fatalf("expected the run image as a dependency, got %+v", def.ResolvedDependencies)
+	}
+	if p.Subject[0].Name != ff.ImageNameV20180708() {
+		t.Fatalf("unexpected subject %+v", p.Subject)
+	}
+}
@@ -0,0 +1,168 @@
+/*
+ * Copyright (c) 2019, 2020 Oracle and/or its affiliates. All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package common
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/fnproject/cli/config"
+)
+
+// BuildCache are the caches a build imports layers from and exports them to, in the format of the
+// buildx --cache-from and --cache-to flags, e.g. type=registry,ref=registry.example.com/team/hello:cache
+// or type=local,dest=/tmp/cache. An image reference alone is a registry cache.
+type BuildCache struct {
+	From []string
+	To   []string
+}
+
+// IsEmpty reports whether the build uses no external cache
+func (c BuildCache) IsEmpty() bool {
+	return len(c.From) == 0 && len(c.To) == 0
+}
+
+// parseCacheSpec splits a buildx cache spec into its attributes, an image reference alone becomes
+// type=registry,ref=<image>
+func parseCacheSpec(spec string) (map[string]string, error) {
+	attrs := map[string]string{}
+	if !strings.Contains(spec, "=") {
+		if spec == "" {
+			return nil, fmt.Errorf("empty build cache")
+		}
+		attrs["type"] = "registry"
+		attrs["ref"] = spec
+		return attrs, nil
+	}
+	for _, field := range strings.Split(spec, ",") {
+		kv := strings.SplitN(field, "=", 2)
+		if len(kv) != 2 {
+			return nil, fmt.Errorf("invalid build cache %q: %q is not key=value", spec, field)
+		}
+		attrs[strings.TrimSpace(kv[0])] = strings.TrimSpace(kv[1])
+	}
+	if attrs["type"] == "" {
+		return nil, fmt.Errorf("invalid build cache %q: type is missing", spec)
+	}
+	return attrs, nil
+}
+
+// registryCacheRef returns the image of a registry cache, for the builders that only cache to registries
+func registryCacheRef(containerEngineType, spec string) (string, error) {
+	attrs, err := parseCacheSpec(spec)
+	if err != nil {
+		return "", err
+	}
+	if attrs["type"] != "registry" || attrs["ref"] == "" {
+		return "", fmt.Errorf("%s only supports registry build caches, %q is not one", containerEngineType, spec)
+	}
+	return attrs["ref"], nil
+}
+
+// buildCacheArgs returns the build flags of docker, podman and buildah for the caches. docker passes
+// them to buildx as they are, podman and buildah take the image of registry caches.
+func buildCacheArgs(containerEngineType string, cache BuildCache) ([]string, error) {
+	var args []string
+	for _, c := range cache.From {
+		from := c
+		if containerEngineType != containerEngineTypeDocker {
+			ref, err := registryCacheRef(containerEngineType, c)
+			if err != nil {
+				return nil, err
+			}
+			from = ref
+		} else if _, err := parseCacheSpec(c); err != nil {
+			return nil, err
+		}
+		args = append(args, "--cache-from", from)
+	}
+	for _, c := range cache.To {
+		to := c
+		if containerEngineType != containerEngineTypeDocker {
+			ref, err := registryCacheRef(containerEngineType, c)
+			if err != nil {
+				return nil, err
+			}
+			to = ref
+		} else if _, err := parseCacheSpec(c); err != nil {
+			return nil, err
+		}
+		args = append(args, "--cache-to", to)
+	}
+	return args, nil
+}
+
+// buildKitCacheArgs returns the buildctl flags for the caches
+func buildKitCacheArgs(cache BuildCache) ([]string, error) {
+	var args []string
+	for _, c := range cache.From {
+		attrs, err := parseCacheSpec(c)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, "--import-cache", cacheSpecString(attrs))
+	}
+	for _, c := range cache.To {
+		attrs, err := parseCacheSpec(c)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, "--export-cache", cacheSpecString(attrs))
+	}
+	return args, nil
+}
+
+// kanikoCacheArgs returns the kaniko flags for the caches. kaniko reads and writes its layer cache in a
+// single repository, so cache_from and cache_to must name the same one.
+func kanikoCacheArgs(cache BuildCache) ([]string, error) {
+	if cache.IsEmpty() {
+		return nil, nil
+	}
+	repo := ""
+	for _, c := range append(append([]string{}, cache.From...), cache.To...) {
+		ref, err := registryCacheRef(config.ContainerEngineKaniko, c)
+		if err != nil {
+			return nil, err
+		}
+		// kaniko tags the cached layers itself, so it takes a repository
+		if i := strings.LastIndex(ref, ":"); i > strings.LastIndex(ref, "/") {
+			ref = ref[:i]
+		}
+		if repo != "" && ref != repo {
+			return nil, fmt.Errorf("kaniko uses a single cache repository, got %s and %s", repo, ref)
+		}
+		repo = ref
+	}
+	return []string{"--cache=true", "--cache-repo", repo}, nil
+}
+
+// cacheSpecString formats cache attributes with type first and the others sorted, so the flags are stable
+func cacheSpecString(attrs map[string]string) string {
+	fields := []string{"type=" + attrs["type"]}
+	var keys []string
+	for k := range attrs {
+		if k != "type" {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		fields = append(fields, k+"="+attrs[k])
+	}
+	return strings.Join(fields, ",")
+}
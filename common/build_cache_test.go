@@ -0,0 +1,114 @@
+package common
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/fnproject/cli/config"
+)
+
+func TestBuildOptionsCache(t *testing.T) {
+	ff := &FuncFileV20180708{CacheFrom: []string{"reg/hello:cache"}, CacheTo: []string{"type=local,dest=.cache"}}
+
+	if cache := (BuildOptions{}).ForFuncFileV20180708(ff).Cache; !reflect.DeepEqual(cache, BuildCache{From: ff.CacheFrom, To: ff.CacheTo}) {
+		t.Fatalf("expected the caches of func.yaml, got %+v", cache)
+	}
+	opts := BuildOptions{Cache: BuildCache{To: []string{"reg/hello:ci"}}}
+	cache := opts.ForFuncFileV20180708(ff).Cache
+	if !reflect.DeepEqual(cache.From, ff.CacheFrom) || !reflect.DeepEqual(cache.To, []string{"reg/hello:ci"}) {
+		t.Fatalf("expected --cache-to to replace cache_to only, got %+v", cache)
+	}
+}
+
+func TestBuildCacheArgs(t *testing.T) {
+	cache := BuildCache{
+		From: []string{"reg/hello:cache", "type=local,src=.cache"},
+		To:   []string{"type=registry,ref=reg/hello:cache,mode=max"},
+	}
+	args, err := buildCacheArgs(containerEngineTypeDocker, cache)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{"--cache-from", "reg/hello:cache", "--cache-from", "type=local,src=.cache", "--cache-to", "type=registry,ref=reg/hello:cache,mode=max"}
+	if !reflect.DeepEqual(args, expected) {
+		t.Fatalf("expected %v, got %v", expected, args)
+	}
+
+	if _, err := buildCacheArgs("podman", cache); err == nil {
+		t.Fatal("expected podman to refuse a local cache")
+	}
+	args, err = buildCacheArgs("podman", BuildCache{From: []string{"reg/hello:cache"}, To: []string{"type=registry,ref=reg/hello:cache"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected = []string{"--cache-from", "reg/hello:cache", "--cache-to", "reg/hello:cache"}
+	if !reflect.DeepEqual(args, expected) {
+		t.Fatalf("expected %v, got %v", expected, args)
+	}
+
+	if _, err := buildCacheArgs(containerEngineTypeDocker, BuildCache{From: []string{"ref=reg/hello:cache"}}); err == nil {
+		t.Fatal("expected a cache without type to be rejected")
+	}
+}
+
+func TestSinglePlatformBuildCommand(t *testing.T) {
+	cacheArgs := []string{"--cache-from", "reg/hello:cache", "--cache-to", "type=registry,ref=reg/hello:cache"}
+	tail := []string{"-t", "hello:0.0.2", "-f", "Dockerfile", "--cache-from", "reg/hello:cache", "--cache-to", "type=registry,ref=reg/hello:cache",
+		"--build-arg", "HTTP_PROXY", "--build-arg", "HTTPS_PROXY", "."}
+
+	// the image is loaded into the docker image store, builds for a shape push it afterwards
+	args := singlePlatformBuildCommand(containerEngineTypeDocker, "hello:0.0.2", "Dockerfile", nil, false, cacheArgs, nil)
+	expected := append([]string{"buildx", "build", "--load"}, tail...)
+	if !reflect.DeepEqual(args, expected) {
+		t.Fatalf("expected %v, got %v", expected, args)
+	}
+
+	// without caches, and with podman, the plain build is kept
+	args = singlePlatformBuildCommand(containerEngineTypeDocker, "hello:0.0.2", "Dockerfile", nil, false, nil, nil)
+	if args[0] != "build" {
+		t.Fatalf("expected docker build without caches, got %v", args)
+	}
+	args = singlePlatformBuildCommand("podman", "hello:0.0.2", "Dockerfile", nil, false, cacheArgs, nil)
+	if args[0] != "build" {
+		t.Fatalf("expected podman build with caches, got %v", args)
+	}
+}
+
+func TestBuildKitCacheArgs(t *testing.T) {
+	args, err := buildKitCacheArgs(BuildCache{From: []string{"reg/hello:cache"}, To: []string{"type=local,mode=max,dest=/tmp/cache"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{"--import-cache", "type=registry,ref=reg/hello:cache", "--export-cache", "type=local,dest=/tmp/cache,mode=max"}
+	if !reflect.DeepEqual(args, expected) {
+		t.Fatalf("expected %v, got %v", expected, args)
+	}
+}
+
+func TestKanikoCacheArgs(t *testing.T) {
+	args, err := kanikoCacheArgs(BuildCache{From: []string{"reg/hello:cache"}, To: []string{"type=registry,ref=reg/hello"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{"--cache=true", "--cache-repo", "reg/hello"}
+	if !reflect.DeepEqual(args, expected) {
+		t.Fatalf("expected %v, got %v", expected, args)
+	}
+
+	if _, err := kanikoCacheArgs(BuildCache{From: []string{"reg/hello"}, To: []string{"reg/other"}}); err == nil {
+		t.Fatal("expected kaniko to refuse two cache repositories")
+	}
+	if _, err := kanikoCacheArgs(BuildCache{From: []string{"type=local,src=.cache"}}); err == nil {
+		t.Fatal("expected kaniko to refuse a local cache")
+	}
+
+	cmds, err := daemonlessBuildCommands(config.ContainerEngineKaniko, daemonlessBuild{binary: "executor", noCache: true, cache: BuildCache{From: []string{"reg/hello"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, a := range cmds[0] {
+		if a == "--cache=true" {
+			t.Fatalf("expected --no-cache to drop the kaniko cache, got %v", cmds[0])
+		}
+	}
+}
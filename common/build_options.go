@@ -0,0 +1,126 @@
+/*
+ * Copyright (c) 2019, 2020 Oracle and/or its affiliates. All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package common
+
+import (
+	"io"
+	"os"
+)
+
+// BuildOptions are the settings of a build that are given by the command rather than by func.yaml
+type BuildOptions struct {
+	Verbose   bool
+	BuildArgs []string
+	NoCache   bool
+	// Shape is the shape of the app the image is built for. Images built for a shape are pushed, the others stay local.
+	Shape      string
+	LocalDebug bool
+	// Platforms of a local build, they replace the platforms of func.yaml
+	Platforms []string
+	// LoadHostPlatform loads the image of the host platform into the container engine after a local
+	// build of several platforms, which docker keeps in an OCI archive
+	LoadHostPlatform bool
+	// Cache replaces the cache_from and cache_to of func.yaml
+	Cache BuildCache
+	// Attestations are generated on top of those of the attestations section of func.yaml
+	Attestations Attestations
+	// Progress is the progress mode of the build, plain with Verbose and tty otherwise when it is empty
+	Progress string
+	// Out and ErrOut receive the progress of the build, they default to stdout and stderr
+	Out    io.Writer
+	ErrOut io.Writer
+	// Prefixed is set when every line of ErrOut is prefixed, as with --parallel. The dots of the tty progress
+	// never end a line, so they are not printed.
+	Prefixed bool
+	// Function is the name of the function in the build events
+	Function string
+}
+
+// Validate checks the progress mode, platforms and attestations
+func (o BuildOptions) Validate() error {
+	if err := ValidateBuildProgress(o.Progress); err != nil {
+		return err
+	}
+	if err := ValidatePlatforms(o.Platforms); err != nil {
+		return err
+	}
+	return o.Attestations.Validate()
+}
+
+// ForFuncFileV20180708 returns the options of a build of the function. The platforms and caches of the
+// options replace those of func.yaml, and the attestations of func.yaml are generated as well.
+func (o BuildOptions) ForFuncFileV20180708(ff *FuncFileV20180708) BuildOptions {
+	if len(o.Platforms) == 0 {
+		o.Platforms = ff.Platforms
+	}
+	o.Function = ff.Name
+
+	cache := BuildCache{From: ff.CacheFrom, To: ff.CacheTo}
+	if len(o.Cache.From) > 0 {
+		cache.From = o.Cache.From
+	}
+	if len(o.Cache.To) > 0 {
+		cache.To = o.Cache.To
+	}
+	o.Cache = cache
+
+	var a Attestations
+	if ff.Attestations != nil {
+		a = *ff.Attestations
+	}
+	if o.Attestations.SBOM != "" {
+		a.SBOM = o.Attestations.SBOM
+	}
+	a.Provenance = a.Provenance || o.Attestations.Provenance
+	a.Push = a.Push || o.Attestations.Push
+	o.Attestations = a
+	return o
+}
+
+// InfoOutput returns where the progress messages around a build go. With --progress json stdout only
+// carries build events, so they go to ErrOut.
+func (o BuildOptions) InfoOutput() io.Writer {
+	if o.Progress == BuildProgressJSON {
+		return o.errOut()
+	}
+	return o.out()
+}
+
+// progressMode returns the progress mode of the build, --verbose streams the output unless a mode was chosen
+func (o BuildOptions) progressMode() string {
+	if o.Progress != "" {
+		return o.Progress
+	}
+	if o.Verbose {
+		return BuildProgressPlain
+	}
+	return BuildProgressTTY
+}
+
+func (o BuildOptions) out() io.Writer {
+	if o.Out == nil {
+		return os.Stdout
+	}
+	return o.Out
+}
+
+func (o BuildOptions) errOut() io.Writer {
+	if o.ErrOut == nil {
+		return os.Stderr
+	}
+	return o.ErrOut
+}
@@ -0,0 +1,312 @@
+/*
+ * Copyright (c) 2019, 2020 Oracle and/or its affiliates. All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package common
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fnproject/cli/config"
+)
+
+// Build progress modes
+const (
+	// BuildProgressTTY prints a dot per second while building, the default
+	BuildProgressTTY = "tty"
+	// BuildProgressPlain streams the container engine output, like --verbose
+	BuildProgressPlain = "plain"
+	// BuildProgressJSON prints a JSON event per build step
+	BuildProgressJSON = "json"
+)
+
+const (
+	buildLogsDirName = "build-logs"
+	// buildLogsKept is how many build logs are kept in ~/.fn/build-logs
+	buildLogsKept = 50
+	// BuildLogTailLines is how many lines of the build log are printed when a build fails
+	BuildLogTailLines = 30
+)
+
+// ValidateBuildProgress checks a --progress value
+func ValidateBuildProgress(progress string) error {
+	switch progress {
+	case "", BuildProgressTTY, BuildProgressPlain, BuildProgressJSON:
+		return nil
+	}
+	return fmt.Errorf("invalid --progress %q, must be one of %s, %s or %s", progress, BuildProgressPlain, BuildProgressTTY, BuildProgressJSON)
+}
+
+// BuildEvent is a line of the output of --progress json
+type BuildEvent struct {
+	Time  time.Time `json:"time"`
+	Type  string    `json:"type"`
+	Image string    `json:"image"`
+	// Function is the name of the function the image is built for, which tells apart the events of parallel builds
+	Function string `json:"function,omitempty"`
+	// Stage is the name of the Dockerfile stage of a step, stage-N for unnamed stages of multi-stage builds
+	Stage string `json:"stage,omitempty"`
+	Step  int    `json:"step,omitempty"`
+	Steps int    `json:"steps,omitempty"`
+	// Name is the Dockerfile instruction of a step
+	Name            string  `json:"name,omitempty"`
+	DurationSeconds float64 `json:"duration_seconds,omitempty"`
+	Cached          bool    `json:"cached,omitempty"`
+	Error           string  `json:"error,omitempty"`
+	// Log is the path of the build log
+	Log string `json:"log,omitempty"`
+}
+
+// Build event types
+const (
+	BuildEventStart = "build_start"
+	BuildEventStep  = "step"
+	BuildEventEnd   = "build_end"
+)
+
+// openBuildLog creates the file that receives the container engine output of a build. Only the most
+// recent buildLogsKept logs are kept.
+func openBuildLog(imageName string) (*os.File, error) {
+	dir := filepath.Join(config.GetHomeDir(), ".fn", buildLogsDirName)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	pruneBuildLogs(dir, buildLogsKept-1)
+	name := invalidBuildLogChars.ReplaceAllString(imageName, "_")
+	f, err := os.OpenFile(filepath.Join(dir, fmt.Sprintf("%s-%s.log", time.Now().Format("20060102-150405.000"), name)), os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+var invalidBuildLogChars = regexp.MustCompile(`[^a-zA-Z0-9_.-]`)
+
+// pruneBuildLogs removes the oldest build logs of dir until keep are left. Log names start with their
+// creation time, so they sort oldest first.
+func pruneBuildLogs(dir string, keep int) {
+	matches, err := filepath.Glob(filepath.Join(dir, "*.log"))
+	if err != nil || len(matches) <= keep {
+		return
+	}
+	sort.Strings(matches)
+	for _, m := range matches[:len(matches)-keep] {
+		os.Remove(m)
+	}
+}
+
+// tailFile returns the last n lines of a file
+func tailFile(path string, n int) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var lines []string
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+		if len(lines) > n {
+			lines = lines[1:]
+		}
+	}
+	return lines, scanner.Err()
+}
+
+// printBuildLogTail prints the end of the log of a failed build
+func printBuildLogTail(w io.Writer, logPath string) {
+	lines, err := tailFile(logPath, BuildLogTailLines)
+	if err != nil {
+		return
+	}
+	fmt.Fprintf(w, "Last %d lines of the build log:\n", len(lines))
+	for _, l := range lines {
+		fmt.Fprintf(w, "  %s\n", l)
+	}
+	fmt.Fprintf(w, "Full build log: %s\n", logPath)
+}
+
+// lockedWriter serializes the writes of the stdout and stderr copiers of a command
+type lockedWriter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func (l *lockedWriter) Write(p []byte) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.w.Write(p)
+}
+
+var (
+	// BuildKit plain progress, e.g. "#8 [build 2/5] RUN go build", "#8 CACHED", "#8 DONE 1.2s"
+	buildKitStepLine  = regexp.MustCompile(`^#(\d+) \[(?:(\S+) )?(\d+)/(\d+)\] (.*)$`)
+	buildKitCached    = regexp.MustCompile(`^#(\d+) CACHED$`)
+	buildKitDone      = regexp.MustCompile(`^#(\d+) DONE ([0-9.]+)s$`)
+	buildKitError     = regexp.MustCompile(`^#(\d+) ERROR:? ?(.*)$`)
+	legacyStepLine    = regexp.MustCompile(`^(?:Step|STEP) (\d+)/(\d+) ?: ?(.*)$`)
+	legacyCachedLine  = regexp.MustCompile(`^\s*-+> Using cache`)
+	fromStageInstruct = regexp.MustCompile(`(?i)^FROM\s+\S+(?:\s+AS\s+(\S+))?`)
+)
+
+// buildEventWriter turns the output of docker, podman and the BuildKit plain progress into step events.
+// BuildKit reports the duration and cache hits of its steps, the steps of the classic builders last
+// until the next one starts.
+type buildEventWriter struct {
+	image string
+	emit  func(BuildEvent)
+	now   func() time.Time
+
+	buf bytes.Buffer
+
+	// buildKitSteps are the BuildKit steps that were started, by vertex number
+	buildKitSteps map[string]*BuildEvent
+	// current is the classic builder step that is running
+	current      *BuildEvent
+	currentStart time.Time
+	stages       int
+	stage        string
+}
+
+func newBuildEventWriter(image string, emit func(BuildEvent)) *buildEventWriter {
+	return &buildEventWriter{image: image, emit: emit, now: time.Now, buildKitSteps: map[string]*BuildEvent{}}
+}
+
+func (w *buildEventWriter) Write(p []byte) (int, error) {
+	w.buf.Write(p)
+	for {
+		line, err := w.buf.ReadString('\n')
+		if err != nil {
+			// keep the incomplete line for the next write
+			w.buf.Reset()
+			w.buf.WriteString(line)
+			return len(p), nil
+		}
+		w.line(strings.TrimRight(line, "\r\n"))
+	}
+}
+
+func (w *buildEventWriter) line(l string) {
+	if m := buildKitStepLine.FindStringSubmatch(l); m != nil {
+		if _, ok := w.buildKitSteps[m[1]]; !ok {
+			step, _ := strconv.Atoi(m[3])
+			steps, _ := strconv.Atoi(m[4])
+			w.buildKitSteps[m[1]] = &BuildEvent{Stage: m[2], Step: step, Steps: steps, Name: m[5]}
+		}
+		return
+	}
+	if m := buildKitCached.FindStringSubmatch(l); m != nil {
+		if e, ok := w.buildKitSteps[m[1]]; ok {
+			e.Cached = true
+			w.finishBuildKitStep(m[1], 0)
+		}
+		return
+	}
+	if m := buildKitDone.FindStringSubmatch(l); m != nil {
+		d, _ := strconv.ParseFloat(m[2], 64)
+		w.finishBuildKitStep(m[1], d)
+		return
+	}
+	if m := buildKitError.FindStringSubmatch(l); m != nil {
+		if e, ok := w.buildKitSteps[m[1]]; ok {
+			e.Error = m[2]
+			w.finishBuildKitStep(m[1], 0)
+		}
+		return
+	}
+	if m := legacyStepLine.FindStringSubmatch(l); m != nil {
+		w.finishCurrent()
+		step, _ := strconv.Atoi(m[1])
+		steps, _ := strconv.Atoi(m[2])
+		if f := fromStageInstruct.FindStringSubmatch(m[3]); f != nil {
+			w.stages++
+			w.stage = f[1]
+			if w.stage == "" && w.stages > 1 {
+				w.stage = fmt.Sprintf("stage-%d", w.stages-1)
+			}
+		}
+		w.current = &BuildEvent{Stage: w.stage, Step: step, Steps: steps, Name: m[3]}
+		w.currentStart = w.now()
+		return
+	}
+	if w.current != nil && legacyCachedLine.MatchString(l) {
+		w.current.Cached = true
+	}
+}
+
+func (w *buildEventWriter) finishBuildKitStep(vertex string, seconds float64) {
+	e, ok := w.buildKitSteps[vertex]
+	if !ok || e.Type != "" {
+		return
+	}
+	e.Type = BuildEventStep
+	e.DurationSeconds = seconds
+	w.send(*e)
+}
+
+func (w *buildEventWriter) finishCurrent() {
+	if w.current == nil {
+		return
+	}
+	e := *w.current
+	e.Type = BuildEventStep
+	e.DurationSeconds = w.now().Sub(w.currentStart).Seconds()
+	w.current = nil
+	w.send(e)
+}
+
+// Close reports the step that was running when the build ended
+func (w *buildEventWriter) Close() error {
+	if w.buf.Len() > 0 {
+		w.line(strings.TrimRight(w.buf.String(), "\r\n"))
+		w.buf.Reset()
+	}
+	w.finishCurrent()
+	return nil
+}
+
+func (w *buildEventWriter) send(e BuildEvent) {
+	e.Time = w.now()
+	e.Image = w.image
+	w.emit(e)
+}
+
+// jsonBuildEvents returns a func that prints the build events of a function to out, one JSON document per line
+func jsonBuildEvents(out io.Writer, function string) func(BuildEvent) {
+	var mu sync.Mutex
+	return func(e BuildEvent) {
+		e.Function = function
+		b, err := json.Marshal(e)
+		if err != nil {
+			return
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		fmt.Fprintln(out, string(b))
+	}
+}
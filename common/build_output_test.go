@@ -0,0 +1,171 @@
+package common
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func collectBuildEvents(t *testing.T, output string) []BuildEvent {
+	var events []BuildEvent
+	w := newBuildEventWriter("hello:0.0.1", func(e BuildEvent) { events = append(events, e) })
+	clock := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	w.now = func() time.Time {
+		clock = clock.Add(time.Second)
+		return clock
+	}
+	// write in chunks that split lines, as the container engine copiers do
+	for len(output) > 0 {
+		n := 7
+		if n > len(output) {
+			n = len(output)
+		}
+		if _, err := w.Write([]byte(output[:n])); err != nil {
+			t.Fatal(err)
+		}
+		output = output[n:]
+	}
+	w.Close()
+	return events
+}
+
+func TestBuildEventWriterBuildKit(t *testing.T) {
+	events := collectBuildEvents(t, `#1 [internal] load build definition from Dockerfile
+#1 DONE 0.0s
+#5 [build 1/3] FROM docker.io/fnproject/go:dev
+#5 CACHED
+#6 [build 2/3] RUN go build -o func
+#6 0.512 downloading modules
+#6 DONE 2.5s
+#7 [stage-1 3/3] COPY --from=build /function/func /function/
+#7 ERROR: failed to copy files
+`)
+	if len(events) != 3 {
+		t.Fatalf("expected 3 step events, got %+v", events)
+	}
+	if e := events[0]; e.Stage != "build" || e.Step != 1 || e.Steps != 3 || !e.Cached || e.Name != "FROM docker.io/fnproject/go:dev" {
+		t.Fatalf("unexpected cached step %+v", e)
+	}
+	if e := events[1]; e.Cached || e.DurationSeconds != 2.5 || e.Type != BuildEventStep || e.Image != "hello:0.0.1" {
+		t.Fatalf("unexpected step %+v", e)
+	}
+	if e := events[2]; e.Stage != "stage-1" || e.Error != "failed to copy files" {
+		t.Fatalf("unexpected failed step %+v", e)
+	}
+}
+
+func TestBuildEventWriterClassicBuilders(t *testing.T) {
+	for _, output := range []string{
+		// docker without BuildKit
+		`Step 1/3 : FROM fnproject/go:dev as build-stage
+ ---> 2f3a1c
+Step 2/3 : RUN go build
+ ---> Using cache
+ ---> 9b1e2d
+Step 3/3 : FROM fnproject/go
+ ---> 3c4d5e
+Successfully built 3c4d5e
+`,
+		// podman
+		`STEP 1/3: FROM fnproject/go:dev AS build-stage
+STEP 2/3: RUN go build
+--> Using cache 9b1e2d
+STEP 3/3: FROM fnproject/go
+COMMIT hello:0.0.1
+`,
+	} {
+		events := collectBuildEvents(t, output)
+		var got []string
+		for _, e := range events {
+			got = append(got, fmt.Sprintf("%s %d/%d %t %v", e.Stage, e.Step, e.Steps, e.Cached, e.DurationSeconds))
+		}
+		expected := []string{"build-stage 1/3 false 1", "build-stage 2/3 true 1", "stage-1 3/3 false 1"}
+		if !reflect.DeepEqual(got, expected) {
+			t.Fatalf("expected %v, got %v", expected, got)
+		}
+	}
+}
+
+func TestTailFile(t *testing.T) {
+	f, err := ioutil.TempFile("", "fn-build-log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	for i := 1; i <= 50; i++ {
+		fmt.Fprintf(f, "line %d\n", i)
+	}
+	f.Close()
+
+	lines, err := tailFile(f.Name(), 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(lines, ",") != "line 48,line 49,line 50" {
+		t.Fatalf("unexpected tail %v", lines)
+	}
+}
+
+func TestPruneBuildLogs(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fn-build-logs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	for _, name := range []string{"20200101-000000.000-a.log", "20200102-000000.000-b.log", "20200103-000000.000-c.log"} {
+		ioutil.WriteFile(filepath.Join(dir, name), nil, 0600)
+	}
+
+	pruneBuildLogs(dir, 2)
+	matches, _ := filepath.Glob(filepath.Join(dir, "*.log"))
+	if len(matches) != 2 || filepath.Base(matches[0]) != "20200102-000000.000-b.log" {
+		t.Fatalf("expected the oldest log to be removed, got %v", matches)
+	}
+}
+
+func TestValidateBuildProgress(t *testing.T) {
+	for _, p := range []string{"", BuildProgressTTY, BuildProgressPlain, BuildProgressJSON} {
+		if err := ValidateBuildProgress(p); err != nil {
+			t.Fatalf("expected %q to be valid: %v", p, err)
+		}
+	}
+	if err := ValidateBuildProgress("fancy"); err == nil {
+		t.Fatal("expected an unknown progress mode to be rejected")
+	}
+}
+
+func TestBuildOptionsInfoOutput(t *testing.T) {
+	out, errOut := &strings.Builder{}, &strings.Builder{}
+
+	if (BuildOptions{Progress: BuildProgressJSON, Out: out, ErrOut: errOut}).InfoOutput() != errOut {
+		t.Fatal("expected progress messages on stderr with --progress json")
+	}
+	if (BuildOptions{Progress: BuildProgressPlain, Out: out, ErrOut: errOut}).InfoOutput() != out {
+		t.Fatal("expected progress messages on stdout")
+	}
+}
+
+func TestBuildOptionsProgressMode(t *testing.T) {
+	if m := (BuildOptions{}).progressMode(); m != BuildProgressTTY {
+		t.Fatalf("expected %s by default, got %s", BuildProgressTTY, m)
+	}
+	if m := (BuildOptions{Verbose: true}).progressMode(); m != BuildProgressPlain {
+		t.Fatalf("expected --verbose to stream the output, got %s", m)
+	}
+	if m := (BuildOptions{Verbose: true, Progress: BuildProgressJSON}).progressMode(); m != BuildProgressJSON {
+		t.Fatalf("expected --progress to win over --verbose, got %s", m)
+	}
+}
+
+func TestJSONBuildEventsNameTheFunction(t *testing.T) {
+	var out strings.Builder
+	jsonBuildEvents(&out, "hello")(BuildEvent{Type: "start", Image: "hello:0.0.1"})
+	if !strings.Contains(out.String(), `"function":"hello"`) || !strings.HasSuffix(out.String(), "}\n") {
+		t.Fatalf("expected a JSON line naming the function, got %q", out.String())
+	}
+}
@@ -0,0 +1,136 @@
+/*
+ * Copyright (c) 2019, 2020 Oracle and/or its affiliates. All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package common
+
+import (
+	"fmt"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/fnproject/cli/config"
+)
+
+// imageArchivesDirName is the directory of ~/.fn the archives of local builds are written to
+const imageArchivesDirName = "images"
+
+// ParsePlatforms splits platforms given as repeated or comma separated flags
+func ParsePlatforms(values []string) []string {
+	var platforms []string
+	for _, v := range values {
+		for _, p := range strings.Split(v, ",") {
+			if p = strings.TrimSpace(p); p != "" {
+				platforms = append(platforms, p)
+			}
+		}
+	}
+	return platforms
+}
+
+// ValidatePlatforms checks that platforms are os/arch or os/arch/variant
+func ValidatePlatforms(platforms []string) error {
+	for _, p := range platforms {
+		parts := strings.Split(p, "/")
+		if len(parts) < 2 || len(parts) > 3 {
+			return fmt.Errorf("invalid platform %q, use os/arch such as linux/amd64 or linux/arm64", p)
+		}
+		for _, part := range parts {
+			if part == "" {
+				return fmt.Errorf("invalid platform %q, use os/arch such as linux/amd64 or linux/arm64", p)
+			}
+		}
+	}
+	return nil
+}
+
+// hostPlatform is the platform of images the local container engine runs natively
+func hostPlatform() string {
+	return "linux/" + runtime.GOARCH
+}
+
+// ImageArchive is the archive a local build writes the image to when the container engine can't keep it, such
+// as the manifest list of a docker build of several platforms. It is kept in ~/.fn/images rather than next to
+// the function, so it is not sent with the build context of the next build.
+func ImageArchive(imageName string) string {
+	name := strings.NewReplacer("/", "_", ":", "_", "@", "_").Replace(imageName)
+	return filepath.Join(config.GetHomeDir(), ".fn", imageArchivesDirName, name+".tar")
+}
+
+// localImageArchive returns the archive a build of the image writes it to, empty when the image is pushed
+// or kept by the container engine
+func localImageArchive(containerEngineType, shape, imageName string, platforms []string) string {
+	if _, pushed := ShapeMap[shape]; pushed {
+		return ""
+	}
+	if config.IsDaemonlessContainerEngine(containerEngineType) {
+		if daemonlessBuildArchives(containerEngineType) {
+			return ImageArchive(imageName)
+		}
+		return ""
+	}
+	if len(platforms) > 1 && containerEngineType == containerEngineTypeDocker {
+		return ImageArchive(imageName)
+	}
+	return ""
+}
+
+// localPlatformsCommands returns the commands of a local build of several platforms. The docker image
+// store only holds single platform images, so docker writes the manifest list to an OCI archive and
+// loads the image of the host platform when asked to. podman keeps the manifest list in its local storage.
+func localPlatformsCommands(imageName, dockerfile string, buildArgs []string, noCache bool, cacheArgs []string, platforms []string, containerEngineType, archive string, load bool) ([][]string, error) {
+	if containerEngineType != containerEngineTypeDocker {
+		return [][]string{buildXDockerCommand(imageName, dockerfile, buildArgs, noCache, cacheArgs, platforms, containerEngineType)}, nil
+	}
+	cmds := [][]string{buildXLocalDockerCommand(imageName, dockerfile, buildArgs, noCache, cacheArgs, platforms, "--output", "type=oci,dest="+archive+",name="+imageName)}
+	if load {
+		host := hostPlatform()
+		found := false
+		for _, p := range platforms {
+			found = found || p == host
+		}
+		if !found {
+			return nil, fmt.Errorf("cannot load the image of the host platform %s, it is not one of %s", host, strings.Join(platforms, ", "))
+		}
+		// without --no-cache, the layers come from the builder cache of the first build
+		cmds = append(cmds, buildXLocalDockerCommand(imageName, dockerfile, buildArgs, false, nil, []string{host}, "--load"))
+	}
+	return cmds, nil
+}
+
+// buildXLocalDockerCommand builds an image for the platforms with buildx without pushing it, output tells where the image goes
+func buildXLocalDockerCommand(imageName, dockerfile string, buildArgs []string, noCache bool, cacheArgs []string, platforms []string, output ...string) []string {
+	args := []string{
+		"buildx",
+		"build",
+		"-f", dockerfile,
+		"--platform", strings.Join(platforms, ","),
+		"-t", imageName,
+	}
+	if noCache {
+		args = append(args, "--no-cache")
+	}
+	args = append(args, cacheArgs...)
+	for _, buildArg := range buildArgs {
+		args = append(args, "--build-arg", buildArg)
+	}
+	args = append(args, "--label", "imageName="+imageName)
+	args = append(args, output...)
+	return append(args,
+		"--build-arg", "HTTP_PROXY",
+		"--build-arg", "HTTPS_PROXY",
+		".")
+}
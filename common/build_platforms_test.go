@@ -0,0 +1,80 @@
+package common
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/fnproject/cli/config"
+)
+
+func TestBuildOptionsPlatforms(t *testing.T) {
+	ff := &FuncFileV20180708{Platforms: []string{"linux/amd64"}}
+	if p := (BuildOptions{}).ForFuncFileV20180708(ff).Platforms; !reflect.DeepEqual(p, []string{"linux/amd64"}) {
+		t.Fatalf("expected the platforms of func.yaml, got %v", p)
+	}
+	opts := BuildOptions{Platforms: ParsePlatforms([]string{"linux/amd64,linux/arm64", " linux/arm/v7 "})}
+	if p := opts.ForFuncFileV20180708(ff).Platforms; !reflect.DeepEqual(p, []string{"linux/amd64", "linux/arm64", "linux/arm/v7"}) {
+		t.Fatalf("expected --platform to replace platforms, got %v", p)
+	}
+	if err := opts.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range []string{"amd64", "linux/", "linux/arm/v7/x"} {
+		if err := ValidatePlatforms([]string{p}); err == nil {
+			t.Fatalf("expected %s to be rejected", p)
+		}
+	}
+}
+
+func TestLocalPlatformsCommands(t *testing.T) {
+	platforms := []string{"linux/amd64", "linux/arm64"}
+	cmds, err := localPlatformsCommands("hello:0.0.1", "Dockerfile", nil, true, nil, platforms, containerEngineTypeDocker, "/f/.fn/images/hello_0.0.1.tar", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cmds) != 2 {
+		t.Fatalf("expected a build of the manifest list and a load, got %v", cmds)
+	}
+	build := strings.Join(cmds[0], " ")
+	if !strings.Contains(build, "--platform linux/amd64,linux/arm64") || !strings.Contains(build, "--output type=oci,dest=/f/.fn/images/hello_0.0.1.tar,name=hello:0.0.1") || !strings.Contains(build, "--no-cache") {
+		t.Fatalf("unexpected build of the manifest list %v", cmds[0])
+	}
+	load := strings.Join(cmds[1], " ")
+	if !strings.Contains(load, "--platform "+hostPlatform()+" ") || !strings.Contains(load, "--load") || strings.Contains(load, "--no-cache") {
+		t.Fatalf("unexpected load of the host platform %v", cmds[1])
+	}
+
+	if _, err := localPlatformsCommands("hello:0.0.1", "Dockerfile", nil, false, nil, []string{"linux/s390x"}, containerEngineTypeDocker, "a.tar", true); err == nil {
+		t.Fatal("expected an error loading a platform that was not built")
+	}
+
+	cmds, err = localPlatformsCommands("hello:0.0.1", "Dockerfile", nil, false, nil, platforms, "podman", "", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cmds) != 1 || !strings.Contains(strings.Join(cmds[0], " "), "--manifest hello:0.0.1") {
+		t.Fatalf("expected podman to build a local manifest list, got %v", cmds)
+	}
+}
+
+func TestImageArchive(t *testing.T) {
+	a := ImageArchive("reg:5000/team/hello:0.0.1")
+	if a != filepath.Join(config.GetHomeDir(), ".fn", "images", "reg_5000_team_hello_0.0.1.tar") {
+		t.Fatalf("unexpected archive %s", a)
+	}
+
+	if localImageArchive("docker", "", "hello:0.0.1", []string{"linux/amd64", "linux/arm64"}) == "" {
+		t.Fatal("expected a docker build of several platforms to write an archive")
+	}
+	if localImageArchive("docker", "", "hello:0.0.1", []string{"linux/amd64"}) != "" || localImageArchive("podman", "", "hello:0.0.1", []string{"linux/amd64", "linux/arm64"}) != "" {
+		t.Fatal("expected images the container engine keeps not to be archived")
+	}
+	if localImageArchive(config.ContainerEngineBuildKit, "", "hello:0.0.1", nil) == "" || localImageArchive(config.ContainerEngineBuildah, "", "hello:0.0.1", nil) != "" {
+		t.Fatal("expected local buildkit builds, and not buildah builds, to write an archive")
+	}
+	if localImageArchive(config.ContainerEngineBuildKit, DefaultAppShape, "hello:0.0.1", nil) != "" {
+		t.Fatal("expected pushed builds not to write an archive")
+	}
+}
@@ -238,3 +238,12 @@ func bumpVersionV20180708(funcfile *FuncFileV20180708, t VType) (*FuncFileV20180
 	funcfile.Version = newver.String()
 	return funcfile, nil
 }
+
+// NextVersionV20180708 returns the version BumpItV20180708 would store, without changing the func file
+func NextVersionV20180708(funcfile *FuncFileV20180708, vtype VType) (string, error) {
+	bumped := *funcfile
+	if _, err := bumpVersionV20180708(&bumped, vtype); err != nil {
+		return "", err
+	}
+	return bumped.Version, nil
+}
@@ -38,3 +38,25 @@ func TestCleanImageName(t *testing.T) {
 		})
 	}
 }
+
+func TestNextVersionV20180708(t *testing.T) {
+	ff := &FuncFileV20180708{Name: "hello", Version: "0.0.3"}
+	version, err := NextVersionV20180708(ff, Patch)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if version != "0.0.4" {
+		t.Fatalf("Expected '0.0.4' but got '%s'", version)
+	}
+	if ff.Version != "0.0.3" {
+		t.Fatalf("Expected the func file to keep version '0.0.3' but got '%s'", ff.Version)
+	}
+
+	version, err = NextVersionV20180708(&FuncFileV20180708{Name: "hello"}, Patch)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if version != InitialVersion {
+		t.Fatalf("Expected '%s' but got '%s'", InitialVersion, version)
+	}
+}
@@ -34,6 +34,7 @@ import (
 	"path/filepath"
 	"runtime"
 	"strings"
+	"sync"
 	"syscall"
 	"time"
 	"unicode"
@@ -118,6 +119,11 @@ func GetDir(c *cli.Context) string {
 
 // BuildFunc bumps version and builds function.
 func BuildFunc(verbose bool, fpath string, funcfile *FuncFile, buildArg []string, noCache bool) (*FuncFile, error) {
+	return BuildFuncWithOptions(fpath, funcfile, BuildOptions{Verbose: verbose, BuildArgs: buildArg, NoCache: noCache})
+}
+
+// BuildFuncWithOptions bumps version and builds function with the options of the build command.
+func BuildFuncWithOptions(fpath string, funcfile *FuncFile, opts BuildOptions) (*FuncFile, error) {
 	var err error
 	if funcfile.Version == "" {
 		funcfile, err = BumpIt(fpath, Patch)
@@ -134,7 +140,7 @@ func BuildFunc(verbose bool, fpath string, funcfile *FuncFile, buildArg []string
 		return nil, err
 	}
 
-	if err := containerEngineBuild(verbose, fpath, funcfile, buildArg, noCache); err != nil {
+	if err := containerEngineBuild(fpath, funcfile, opts); err != nil {
 		return nil, err
 	}
 
@@ -143,6 +149,13 @@ func BuildFunc(verbose bool, fpath string, funcfile *FuncFile, buildArg []string
 
 // BuildFunc bumps version and builds function.
 func BuildFuncV20180708(verbose bool, fpath string, funcfile *FuncFileV20180708, buildArg []string, noCache bool, shape string, localDebug bool) (*FuncFileV20180708, error) {
+	return BuildFuncV20180708WithOptions(fpath, funcfile, BuildOptions{Verbose: verbose, BuildArgs: buildArg, NoCache: noCache, Shape: shape, LocalDebug: localDebug})
+}
+
+// BuildFuncV20180708WithOptions bumps version and builds function, writing build progress and
+// container engine output to opts.Out and opts.ErrOut. It only depends on the path of the func file,
+// not on the working directory, so functions can be built concurrently.
+func BuildFuncV20180708WithOptions(fpath string, funcfile *FuncFileV20180708, opts BuildOptions) (*FuncFileV20180708, error) {
 	var err error
 
 	if funcfile.Version == "" {
@@ -157,13 +170,42 @@ func BuildFuncV20180708(verbose bool, fpath string, funcfile *FuncFileV20180708,
 		return nil, err
 	}
 
+	opts = opts.ForFuncFileV20180708(funcfile)
+	if err := opts.Attestations.Validate(); err != nil {
+		return nil, err
+	}
+
 	if err := localBuild(fpath, funcfile.Build); err != nil {
 		return nil, err
 	}
-	if err := containerEngineBuildV20180708(verbose, fpath, funcfile, buildArg, noCache, shape, localDebug); err != nil {
+	started := time.Now()
+	if err := containerEngineBuildV20180708(fpath, funcfile, opts); err != nil {
 		return nil, err
 	}
 
+	if !opts.Attestations.IsEmpty() {
+		containerEngineType, err := GetContainerEngineType()
+		if err != nil {
+			return nil, err
+		}
+		_, pushed := ShapeMap[opts.Shape]
+		err = writeAttestationsV20180708(attestationBuild{
+			fpath:               fpath,
+			ff:                  funcfile,
+			attestations:        opts.Attestations,
+			buildArgs:           opts.BuildArgs,
+			localDebug:          opts.LocalDebug,
+			shape:               opts.Shape,
+			containerEngineType: containerEngineType,
+			pushed:              pushed,
+			archive:             localImageArchive(containerEngineType, opts.Shape, funcfile.ImageNameV20180708(), opts.Platforms),
+			startedOn:           started,
+			finishedOn:          time.Now(),
+		}, opts.out(), opts.errOut())
+		if err != nil {
+			return nil, err
+		}
+	}
 	return funcfile, nil
 }
 
@@ -315,7 +357,7 @@ func GetContainerEngineType() (string, error) {
 	return containerEngineType, nil
 }
 
-func containerEngineBuild(verbose bool, fpath string, ff *FuncFile, buildArgs []string, noCache bool) error {
+func containerEngineBuild(fpath string, ff *FuncFile, opts BuildOptions) error {
 	containerEngineType, err := GetContainerEngineType()
 	if err != nil {
 		return err
@@ -346,13 +388,13 @@ func containerEngineBuild(verbose bool, fpath string, ff *FuncFile, buildArgs []
 		}
 		defer os.Remove(dockerfile)
 		if helper.HasPreBuild() {
-			err := helper.PreBuild()
+			err := helper.PreBuild(dir)
 			if err != nil {
 				return err
 			}
 		}
 	}
-	err = RunBuild(verbose, dir, ff.ImageName(), dockerfile, buildArgs, noCache, containerEngineType, "")
+	err = RunBuildWithOptions(dir, ff.ImageName(), dockerfile, containerEngineType, opts)
 	if err != nil {
 		return err
 	}
@@ -366,13 +408,13 @@ func containerEngineBuild(verbose bool, fpath string, ff *FuncFile, buildArgs []
 	return nil
 }
 
-func containerEngineBuildV20180708(verbose bool, fpath string, ff *FuncFileV20180708, buildArgs []string, noCache bool, shape string, localDebug bool) error {
+func containerEngineBuildV20180708(fpath string, ff *FuncFileV20180708, opts BuildOptions) error {
 	containerEngineType, err := GetContainerEngineType()
 	if err != nil {
 		return err
 	}
 
-	fmt.Println("Using Container engine", containerEngineType)
+	fmt.Fprintln(opts.out(), "Using Container engine", containerEngineType)
 	err = containerEngineVersionCheck(containerEngineType)
 	if err != nil {
 		return err
@@ -391,20 +433,20 @@ func containerEngineBuildV20180708(verbose bool, fpath string, ff *FuncFileV2018
 		if helper == nil {
 			return fmt.Errorf("Cannot build, no language helper found for %v", ff.Runtime)
 		}
-		dockerfile, err = writeTmpDockerfileV20180708(helper, dir, ff, localDebug)
+		dockerfile, err = writeTmpDockerfileV20180708(helper, dir, ff, opts.LocalDebug)
 		if err != nil {
 			return err
 		}
 		defer os.Remove(dockerfile)
 		if helper.HasPreBuild() {
-			err := helper.PreBuild()
+			err := helper.PreBuild(dir)
 			if err != nil {
 				return err
 			}
 		}
 	}
 
-	err = RunBuild(verbose, dir, ff.ImageNameV20180708(), dockerfile, buildArgs, noCache, containerEngineType, shape)
+	err = RunBuildWithOptions(dir, ff.ImageNameV20180708(), dockerfile, containerEngineType, opts)
 	if err != nil {
 		return err
 	}
@@ -418,7 +460,7 @@ func containerEngineBuildV20180708(verbose bool, fpath string, ff *FuncFileV2018
 	return nil
 }
 
-func buildXDockerCommand(imageName, dockerfile string, buildArgs []string, noCache bool, architectures []string, containerEngineType string) []string {
+func buildXDockerCommand(imageName, dockerfile string, buildArgs []string, noCache bool, cacheArgs []string, architectures []string, containerEngineType string) []string {
 	var buildCommand = "buildx"
 	var name = imageName
 
@@ -432,6 +474,7 @@ func buildXDockerCommand(imageName, dockerfile string, buildArgs []string, noCac
 	if noCache {
 		args = append(args, "--no-cache")
 	}
+	args = append(args, cacheArgs...)
 
 	if len(buildArgs) > 0 {
 		for _, buildArg := range buildArgs {
@@ -466,7 +509,7 @@ func buildXDockerCommand(imageName, dockerfile string, buildArgs []string, noCac
 	return args
 }
 
-func buildDockerCommand(imageName, dockerfile string, buildArgs []string, noCache bool, architectures []string) []string {
+func buildDockerCommand(imageName, dockerfile string, buildArgs []string, noCache bool, cacheArgs []string, architectures []string) []string {
 	var name = imageName
 
 	args := []string{
@@ -482,6 +525,7 @@ func buildDockerCommand(imageName, dockerfile string, buildArgs []string, noCach
 	if noCache {
 		args = append(args, "--no-cache")
 	}
+	args = append(args, cacheArgs...)
 
 	if len(buildArgs) > 0 {
 		for _, buildArg := range buildArgs {
@@ -498,35 +542,100 @@ func buildDockerCommand(imageName, dockerfile string, buildArgs []string, noCach
 	return args
 }
 
+// singlePlatformBuildCommand returns the build command of an image for a single platform. docker build only
+// takes cache flags through buildx, which keeps the image in the builder unless it is loaded into the image
+// store. The image is loaded rather than pushed by buildx, so docker push records its digest as for other builds.
+func singlePlatformBuildCommand(containerEngineType, imageName, dockerfile string, buildArgs []string, noCache bool, cacheArgs []string, platforms []string) []string {
+	args := buildDockerCommand(imageName, dockerfile, buildArgs, noCache, cacheArgs, platforms)
+	if containerEngineType != containerEngineTypeDocker || len(cacheArgs) == 0 {
+		return args
+	}
+	return append([]string{"buildx", "build", "--load"}, args[1:]...)
+}
+
 // RunBuild runs function from func.yaml/json/yml.
 func RunBuild(verbose bool, dir, imageName, dockerfile string, buildArgs []string, noCache bool, containerEngineType string, shape string) error {
+	return RunBuildWithOptions(dir, imageName, dockerfile, containerEngineType, BuildOptions{Verbose: verbose, BuildArgs: buildArgs, NoCache: noCache, Shape: shape})
+}
+
+// RunBuildWithOptions runs function from func.yaml/json/yml, writing build progress to opts.Out and
+// opts.ErrOut in the mode of opts.Progress. The container engine output is also written to a build log,
+// whose last lines are printed when the build fails. Layers are imported from and exported to opts.Cache.
+// Builds for a shape target its platforms and are pushed, other builds stay local and target opts.Platforms,
+// producing a manifest list when there are several.
+func RunBuildWithOptions(dir, imageName, dockerfile, containerEngineType string, opts BuildOptions) error {
 	var issuePush bool
 	var isLocal bool
+	buildArgs, noCache, cache, platforms, shape := opts.BuildArgs, opts.NoCache, opts.Cache, opts.Platforms, opts.Shape
+	out, errOut := opts.out(), opts.errOut()
+	if err := ValidatePlatforms(platforms); err != nil {
+		return err
+	}
+	if shapePlatforms, ok := ShapeMap[shape]; ok && len(platforms) > 0 && strings.Join(platforms, ",") != strings.Join(shapePlatforms, ",") {
+		fmt.Fprintf(errOut, "Warning: images of shape %s are built for %s, ignoring the platforms %s\n", shape, strings.Join(shapePlatforms, ","), strings.Join(platforms, ","))
+	}
+	daemonless := config.IsDaemonlessContainerEngine(containerEngineType)
+	_, pushed := ShapeMap[shape]
+	archive := localImageArchive(containerEngineType, shape, imageName, platforms)
+	if archive != "" {
+		if err := os.MkdirAll(filepath.Dir(archive), 0755); err != nil {
+			return err
+		}
+	}
 	cancel := make(chan os.Signal, 3)
 	signal.Notify(cancel, os.Interrupt) // and others perhaps
 	defer signal.Stop(cancel)
 
 	result := make(chan error, 1)
 
-	buildOut := ioutil.Discard
-	buildErr := ioutil.Discard
+	// the container engine output always goes to a build log, so a failed build can be looked into
+	var logWriter io.Writer = ioutil.Discard
+	logPath := ""
+	if logFile, err := openBuildLog(imageName); err != nil {
+		fmt.Fprintf(errOut, "Warning: unable to create build log: %v\n", err)
+	} else {
+		defer logFile.Close()
+		logWriter = logFile
+		logPath = logFile.Name()
+	}
 
+	mode := opts.progressMode()
+	prefixed := opts.Prefixed
+	var buildOut, buildErr io.Writer
+	var events *buildEventWriter
+	var emit func(BuildEvent)
 	quit := make(chan struct{})
-	fmt.Fprintf(os.Stderr, "Building image %v ", imageName)
-	if verbose {
-		fmt.Println()
-		buildOut = os.Stdout
-		buildErr = os.Stderr
+	switch mode {
+	case BuildProgressPlain:
+		fmt.Fprintf(errOut, "Building image %v ", imageName)
+		fmt.Fprintln(out)
+		buildOut = io.MultiWriter(logWriter, out)
+		buildErr = io.MultiWriter(logWriter, errOut)
 		PrintDockerfileContent(dockerfile, buildOut)
 		PrintContextualInfo()
-	} else {
+	case BuildProgressJSON:
+		emit = jsonBuildEvents(out, opts.Function)
+		emit(BuildEvent{Time: time.Now(), Type: BuildEventStart, Image: imageName, Log: logPath})
+		events = newBuildEventWriter(imageName, emit)
+		// stdout and stderr share a writer, so the copiers don't interleave partial lines
+		buildOut = &lockedWriter{w: io.MultiWriter(logWriter, events)}
+		buildErr = buildOut
+	case BuildProgressTTY:
+		buildOut = &lockedWriter{w: logWriter}
+		buildErr = buildOut
+		if prefixed {
+			// dots never end a line, so a prefixed writer would hold them until the build is done
+			fmt.Fprintf(errOut, "Building image %v\n", imageName)
+			break
+		}
+		fmt.Fprintf(errOut, "Building image %v ", imageName)
 		// print dots. quit channel explanation: https://stackoverflow.com/a/16466581/105562
 		ticker := time.NewTicker(1 * time.Second)
 		go func() {
 			for {
 				select {
 				case <-ticker.C:
-					fmt.Fprintf(os.Stderr, ".")
+					fmt.Fprintf(errOut, ".")
 				case <-quit:
 					ticker.Stop()
 					return
@@ -534,9 +643,25 @@ func RunBuild(verbose bool, dir, imageName, dockerfile string, buildArgs []strin
 			}
 		}()
 	}
+	// with --progress json stdout only carries events, the rest of the progress goes to stderr
+	infoOut := opts.InfoOutput()
+	started := time.Now()
 
 	go func(done chan<- error) {
+		if daemonless {
+			// daemonless builders push while building, local builds write the image to an archive
+			if pushed {
+				platforms = ShapeMap[shape]
+			}
+			done <- runDaemonlessBuild(dir, imageName, dockerfile, buildArgs, noCache, cache, containerEngineType, platforms, pushed, archive, buildOut, buildErr)
+			return
+		}
 		var dockerBuildCmdArgs []string
+		cacheArgs, err := buildCacheArgs(containerEngineType, cache)
+		if err != nil {
+			done <- err
+			return
+		}
 
 		// Depending whether architecture list is passed or not trigger docker buildx or docker build accordingly
 		var mappedArchitectures []string
@@ -546,70 +671,108 @@ func RunBuild(verbose bool, dir, imageName, dockerfile string, buildArgs []strin
 			if platform, ok := TargetPlatformMap[shape]; ok {
 				// create target platform string to compare with hosted platform
 				targetPlatform := strings.Join(platform, " ")
-				fmt.Println("TargetedPlatform: ", targetPlatform+"HostPlatform: ", hostedPlatform)
+				fmt.Fprintln(infoOut, "TargetedPlatform: ", targetPlatform+"HostPlatform: ", hostedPlatform)
 				if targetPlatform != hostedPlatform {
 					if config.EnvIsOL8CloudShell {
 						done <- fmt.Errorf("OL8 CloudShell does not support cross-compilation and multi-arch functions builds. Please ensure the architecture of your App matches the CloudShell architecture.")
 						return
 					}
-					err := initializeContainerBuilder(containerEngineType, mappedArchitectures)
+					err := acquireContainerBuilder(containerEngineType, mappedArchitectures)
 					if err != nil {
 						done <- err
 						return
 					}
-					dockerBuildCmdArgs = buildXDockerCommand(imageName, dockerfile, buildArgs, noCache, mappedArchitectures, containerEngineType)
+					dockerBuildCmdArgs = buildXDockerCommand(imageName, dockerfile, buildArgs, noCache, cacheArgs, mappedArchitectures, containerEngineType)
 					// perform cleanup
-					defer cleanupContainerBuilder(containerEngineType)
+					defer releaseContainerBuilder(containerEngineType)
 				} else {
-					dockerBuildCmdArgs = buildDockerCommand(imageName, dockerfile, buildArgs, noCache, mappedArchitectures)
+					dockerBuildCmdArgs = singlePlatformBuildCommand(containerEngineType, imageName, dockerfile, buildArgs, noCache, cacheArgs, mappedArchitectures)
 					issuePush = true
 				}
 			}
+		} else if len(platforms) > 1 {
+			// local builds of several platforms produce a manifest list, they are not pushed either
+			isLocal = true
+			if err := acquireContainerBuilder(containerEngineType, platforms); err != nil {
+				done <- err
+				return
+			}
+			defer releaseContainerBuilder(containerEngineType)
+			cmds, err := localPlatformsCommands(imageName, dockerfile, buildArgs, noCache, cacheArgs, platforms, containerEngineType, archive, opts.LoadHostPlatform)
+			if err != nil {
+				done <- err
+				return
+			}
+			for _, args := range cmds[:len(cmds)-1] {
+				if err := runBuildCommand(containerEngineType, args, dir, buildOut, buildErr); err != nil {
+					done <- err
+					return
+				}
+			}
+			dockerBuildCmdArgs = cmds[len(cmds)-1]
 		} else {
 			// In case of local we ignore the architectures parameter and push to registry should be skipped
-			dockerBuildCmdArgs = buildDockerCommand(imageName, dockerfile, buildArgs, noCache, mappedArchitectures)
+			dockerBuildCmdArgs = singlePlatformBuildCommand(containerEngineType, imageName, dockerfile, buildArgs, noCache, cacheArgs, platforms)
 			isLocal = true
 		}
-		cmd := exec.Command(containerEngineType, dockerBuildCmdArgs...)
-		cmd.Dir = dir
-		cmd.Stderr = buildErr // Doesn't look like there's any output to stderr on docker build, whether it's successful or not.
-		cmd.Stdout = buildOut
-		done <- cmd.Run()
-		//cmd.Env = v
+		done <- runBuildCommand(containerEngineType, dockerBuildCmdArgs, dir, buildOut, buildErr)
 	}(result)
 
 	select {
 	case err := <-result:
 		close(quit)
-		fmt.Fprintln(os.Stderr)
+		end := BuildEvent{Type: BuildEventEnd, Image: imageName, DurationSeconds: time.Since(started).Seconds(), Log: logPath}
+		if events != nil {
+			events.Close()
+		}
+		if mode == BuildProgressPlain || (mode == BuildProgressTTY && !prefixed) {
+			fmt.Fprintln(errOut)
+		}
 		if err != nil {
-			if verbose == false {
-				fmt.Printf("%v Run with `--verbose` flag to see what went wrong. eg: `fn --verbose CMD`\n", color.RedString("Error during build."))
+			if emit != nil {
+				end.Time, end.Error = time.Now(), err.Error()
+				emit(end)
+			}
+			if mode != BuildProgressPlain && logPath != "" {
+				fmt.Fprintf(errOut, "%v\n", color.RedString("Error during build."))
+				printBuildLogTail(errOut, logPath)
 			}
 			return fmt.Errorf("error running docker build: %v", err)
 		}
+		if emit != nil {
+			end.Time = time.Now()
+			emit(end)
+		}
+		switch {
+		case archive != "" && daemonless:
+			fmt.Fprintf(infoOut, "Image %s written to %s, %s keeps no local images\n", imageName, archive, containerEngineType)
+		case archive != "":
+			fmt.Fprintf(infoOut, "Manifest list of %s for %s written to %s\n", imageName, strings.Join(platforms, ", "), archive)
+		case daemonless && !pushed:
+			fmt.Fprintf(infoOut, "Image %s is in the local storage of %s\n", imageName, containerEngineType)
+		}
 	case signal := <-cancel:
 		close(quit)
-		fmt.Fprintln(os.Stderr)
+		fmt.Fprintln(errOut)
 		return fmt.Errorf("build cancelled on signal %v", signal)
 	}
-	if !isLocal && (containerEngineType != containerEngineTypeDocker || issuePush) {
+	if !isLocal && !daemonless && (containerEngineType != containerEngineTypeDocker || issuePush) {
 		// Push to docker registry
-		fmt.Println("Using Container engine ", containerEngineType, " to push")
-		fmt.Printf("Pushing %v to docker registry...", imageName)
+		fmt.Fprintln(infoOut, "Using Container engine ", containerEngineType, " to push")
+		fmt.Fprintf(infoOut, "Pushing %v to docker registry...", imageName)
 		if issuePush == true {
 			// build push for same targetedPlatform and hostPlatform
 			cmd := exec.Command(containerEngineType, "push", imageName)
-			cmd.Stderr = os.Stderr
-			cmd.Stdout = os.Stdout
+			cmd.Stderr = errOut
+			cmd.Stdout = infoOut
 			if err := cmd.Run(); err != nil {
 				return fmt.Errorf("error running %v push: %v", containerEngineType, err)
 			}
 		} else {
 			// push for podman
 			cmd := exec.Command(containerEngineType, "manifest", "push", imageName)
-			cmd.Stderr = os.Stderr
-			cmd.Stdout = os.Stdout
+			cmd.Stderr = errOut
+			cmd.Stdout = infoOut
 			if err := cmd.Run(); err != nil {
 				return fmt.Errorf("error running %v push: %v", containerEngineType, err)
 			}
@@ -618,7 +781,22 @@ func RunBuild(verbose bool, dir, imageName, dockerfile string, buildArgs []strin
 	return nil
 }
 
+// runBuildCommand runs a build command of the container engine in dir
+func runBuildCommand(containerEngineType string, args []string, dir string, out, errOut io.Writer) error {
+	cmd := exec.Command(containerEngineType, args...)
+	cmd.Dir = dir
+	// BuildKit prints one line per step event instead of redrawing the terminal, which the log and --progress json need
+	cmd.Env = append(os.Environ(), "BUILDKIT_PROGRESS=plain")
+	cmd.Stderr = errOut
+	cmd.Stdout = out
+	return cmd.Run()
+}
+
 func containerEngineVersionCheck(containerEngineType string) error {
+	if config.IsDaemonlessContainerEngine(containerEngineType) {
+		_, err := daemonlessBuilderBinary(containerEngineType)
+		return err
+	}
 	out, err := exec.Command(containerEngineType, "version", "--format", "{{.Server.Version}}").Output()
 	if err != nil {
 		return fmt.Errorf("Cannot connect to the %v, make sure you have it installed and running: %v", containerEngineType, err)
@@ -715,6 +893,36 @@ func initializeContainerBuilder(containerEngineType string, platforms []string)
 	return nil
 }
 
+var (
+	containerBuilderMu    sync.Mutex
+	containerBuilderUsers int
+)
+
+// acquireContainerBuilder initializes the shared buildx builder instance for the first of
+// any concurrent builds, later builds reuse it until every one of them has released it.
+func acquireContainerBuilder(containerEngineType string, platforms []string) error {
+	containerBuilderMu.Lock()
+	defer containerBuilderMu.Unlock()
+	if containerBuilderUsers == 0 {
+		if err := initializeContainerBuilder(containerEngineType, platforms); err != nil {
+			return err
+		}
+	}
+	containerBuilderUsers++
+	return nil
+}
+
+// releaseContainerBuilder removes the shared buildx builder instance once the last build using it is done.
+func releaseContainerBuilder(containerEngineType string) error {
+	containerBuilderMu.Lock()
+	defer containerBuilderMu.Unlock()
+	containerBuilderUsers--
+	if containerBuilderUsers > 0 {
+		return nil
+	}
+	return cleanupContainerBuilder(containerEngineType)
+}
+
 func cleanupContainerBuilder(containerEngineType string) error {
 	//remove existing builder instance
 	_, err := exec.Command(containerEngineType, "buildx", "rm", BuildxBuilderInstance).Output()
@@ -763,7 +971,7 @@ func writeTmpDockerfile(helper langs.LangHelper, dir string, ff *FuncFile) (stri
 		dfLines = append(dfLines, fmt.Sprintf("FROM %s", bi))
 	}
 	dfLines = append(dfLines, "WORKDIR /function")
-	dfLines = append(dfLines, helper.DockerfileBuildCmds(false)...)
+	dfLines = append(dfLines, helper.DockerfileBuildCmds(dir, false)...)
 	if helper.IsMultiStage() {
 		// final stage
 		ri := ff.RunImage
@@ -781,7 +989,7 @@ func writeTmpDockerfile(helper langs.LangHelper, dir string, ff *FuncFile) (stri
 		}
 		dfLines = append(dfLines, fmt.Sprintf("FROM %s", ri))
 		dfLines = append(dfLines, "WORKDIR /function")
-		dfLines = append(dfLines, helper.DockerfileCopyCmds(false)...)
+		dfLines = append(dfLines, helper.DockerfileCopyCmds(dir, false)...)
 	}
 	if ff.Entrypoint != "" {
 		dfLines = append(dfLines, fmt.Sprintf("ENTRYPOINT [%s]", utils.StringToSlice(ff.Entrypoint)))
@@ -797,8 +1005,9 @@ func writeTmpDockerfile(helper langs.LangHelper, dir string, ff *FuncFile) (stri
 }
 
 func writeTmpDockerfileV20180708(helper langs.LangHelper, dir string, ff *FuncFileV20180708, localDebug bool) (string, error) {
-	if ff.Entrypoint == "" && ff.Cmd == "" {
-		return "", errors.New("entrypoint and cmd are missing, you must provide one or the other")
+	dfLines, err := dockerfileLinesV20180708(helper, dir, ff, localDebug)
+	if err != nil {
+		return "", err
 	}
 
 	fd, err := ioutil.TempFile(dir, "Dockerfile-fn-tmp")
@@ -807,6 +1016,20 @@ func writeTmpDockerfileV20180708(helper langs.LangHelper, dir string, ff *FuncFi
 	}
 	defer fd.Close()
 
+	err = writeLines(fd, dfLines)
+	if err != nil {
+		return "", err
+	}
+	return fd.Name(), err
+}
+
+// dockerfileLinesV20180708 generates the Dockerfile of a function that has none of its own
+func dockerfileLinesV20180708(helper langs.LangHelper, dir string, ff *FuncFileV20180708, localDebug bool) ([]string, error) {
+	if ff.Entrypoint == "" && ff.Cmd == "" {
+		return nil, errors.New("entrypoint and cmd are missing, you must provide one or the other")
+	}
+	var err error
+
 	// multi-stage build: https://medium.com/travis-on-docker/multi-stage-docker-builds-for-creating-tiny-go-images-e0e1867efe5a
 	dfLines := []string{}
 	bi := ff.Build_image
@@ -814,9 +1037,17 @@ func writeTmpDockerfileV20180708(helper langs.LangHelper, dir string, ff *FuncFi
 	if bi == "" {
 		bi, err = helper.BuildFromImage()
 		if err != nil {
-			return "", err
+			return nil, err
 		}
 	}
+	// func.lock pins the build and run images by digest
+	lock, err := LoadFuncLock(dir)
+	if err != nil {
+		return nil, err
+	}
+	if bi, err = lock.Pin(bi); err != nil {
+		return nil, err
+	}
 	if helper.IsMultiStage() {
 		// build stage
 		dfLines = append(dfLines, fmt.Sprintf("FROM %s as build-stage", bi))
@@ -824,7 +1055,7 @@ func writeTmpDockerfileV20180708(helper langs.LangHelper, dir string, ff *FuncFi
 		dfLines = append(dfLines, fmt.Sprintf("FROM %s", bi))
 	}
 	dfLines = append(dfLines, "WORKDIR /function")
-	dfLines = append(dfLines, helper.DockerfileBuildCmds(localDebug)...)
+	dfLines = append(dfLines, helper.DockerfileBuildCmds(dir, localDebug)...)
 
 	ri := ff.Run_image
 	if helper.IsMultiStage() {
@@ -832,12 +1063,15 @@ func writeTmpDockerfileV20180708(helper langs.LangHelper, dir string, ff *FuncFi
 		if ri == "" {
 			ri, err = helper.RunFromImage()
 			if err != nil {
-				return "", err
+				return nil, err
 			}
 		}
+		if ri, err = lock.Pin(ri); err != nil {
+			return nil, err
+		}
 		dfLines = append(dfLines, fmt.Sprintf("FROM %s", ri))
 		dfLines = append(dfLines, "WORKDIR /function")
-		dfLines = append(dfLines, helper.DockerfileCopyCmds(localDebug)...)
+		dfLines = append(dfLines, helper.DockerfileCopyCmds(dir, localDebug)...)
 	}
 	// if localDebug,
 	//    if entrypoint is defined, add the debug options in the entry point
@@ -847,7 +1081,7 @@ func writeTmpDockerfileV20180708(helper langs.LangHelper, dir string, ff *FuncFi
 	// FDK java provides default entrypoint in fdk runtime image so we need to handle that
 	fdkDefaultEntrypoint, err := getEntrypointFromImage(ri)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 	finalEntrypoint := fdkDefaultEntrypoint
 	if ff.Entrypoint != "" {
@@ -868,11 +1102,7 @@ func writeTmpDockerfileV20180708(helper langs.LangHelper, dir string, ff *FuncFi
 			dfLines = append(dfLines, fmt.Sprintf("CMD [%s]", utils.StringToSlice(ff.Cmd)))
 		}
 	}
-	err = writeLines(fd, dfLines)
-	if err != nil {
-		return "", err
-	}
-	return fd.Name(), err
+	return dfLines, nil
 }
 
 func writeLines(w io.Writer, lines []string) error {
@@ -901,7 +1131,7 @@ func ExtractConfig(configs []string) map[string]string {
 
 // Push pushes to docker registry.
 func Push(ff *FuncFile) error {
-	containerEngineType, err := GetContainerEngineType()
+	containerEngineType, err := GetRunnableContainerEngineType()
 	if err != nil {
 		return err
 	}
@@ -921,7 +1151,7 @@ func Push(ff *FuncFile) error {
 
 // Push pushes to docker registry.
 func PushV20180708(ff *FuncFileV20180708) error {
-	containerEngineType, err := GetContainerEngineType()
+	containerEngineType, err := GetRunnableContainerEngineType()
 	if err != nil {
 		return err
 	}
@@ -1163,8 +1393,75 @@ func ListTriggersInFunc(c *cli.Context, client *fnclient.Fn, fn *modelsv2.Fn) ([
 	return resTriggers, nil
 }
 
+// ListAllFnsInApp gets every function associated with an app, following all result pages
+func ListAllFnsInApp(client *fnclient.Fn, app *modelsv2.App) ([]*modelsv2.Fn, error) {
+	params := &apifns.ListFnsParams{
+		Context: context.Background(),
+		AppID:   &app.ID,
+	}
+
+	var resFns []*modelsv2.Fn
+	for {
+		resp, err := client.Fns.ListFns(params)
+		if err != nil {
+			return nil, fmt.Errorf("Could not list functions in application %s: %s", app.Name, err)
+		}
+		resFns = append(resFns, resp.Payload.Items...)
+		if resp.Payload.NextCursor == "" {
+			break
+		}
+		params.Cursor = &resp.Payload.NextCursor
+	}
+	return resFns, nil
+}
+
+// ListAllTriggersInApp gets every trigger of every function of an app, following all result pages
+func ListAllTriggersInApp(client *fnclient.Fn, app *modelsv2.App) ([]*modelsv2.Trigger, error) {
+	params := &apitriggers.ListTriggersParams{
+		Context: context.Background(),
+		AppID:   &app.ID,
+	}
+
+	var resTriggers []*modelsv2.Trigger
+	for {
+		resp, err := client.Triggers.ListTriggers(params)
+		if err != nil {
+			return nil, fmt.Errorf("Could not list triggers in application %s: %s", app.Name, err)
+		}
+		resTriggers = append(resTriggers, resp.Payload.Items...)
+		if resp.Payload.NextCursor == "" {
+			break
+		}
+		params.Cursor = &resp.Payload.NextCursor
+	}
+	return resTriggers, nil
+}
+
+// ListAllTriggersInFunc gets every trigger associated with a function, following all result pages
+func ListAllTriggersInFunc(client *fnclient.Fn, fn *modelsv2.Fn) ([]*modelsv2.Trigger, error) {
+	params := &apitriggers.ListTriggersParams{
+		Context: context.Background(),
+		AppID:   &fn.AppID,
+		FnID:    &fn.ID,
+	}
+
+	var resTriggers []*modelsv2.Trigger
+	for {
+		resp, err := client.Triggers.ListTriggers(params)
+		if err != nil {
+			return nil, fmt.Errorf("Could not list triggers in function %s: %s", fn.Name, err)
+		}
+		resTriggers = append(resTriggers, resp.Payload.Items...)
+		if resp.Payload.NextCursor == "" {
+			break
+		}
+		params.Cursor = &resp.Payload.NextCursor
+	}
+	return resTriggers, nil
+}
+
 func RunInitImage(initImage string, fName string) error {
-	containerEngineType, err := GetContainerEngineType()
+	containerEngineType, err := GetRunnableContainerEngineType()
 	if err != nil {
 		return err
 	}
@@ -1253,7 +1550,7 @@ func untarStream(r io.Reader) error {
 }
 
 func PullImage(image string) error {
-	containerEngineType, err := GetContainerEngineType()
+	containerEngineType, err := GetRunnableContainerEngineType()
 	if err != nil {
 		return err
 	}
@@ -1296,6 +1593,11 @@ func getEntrypointFromImage(image string) (string, error) {
 	if err != nil {
 		return "", err
 	}
+	if config.IsDaemonlessContainerEngine(containerEngineType) {
+		// daemonless builders can't pull and inspect the image, without an ENTRYPOINT the
+		// generated Dockerfile inherits the one of the run image anyway
+		return "", nil
+	}
 	// Need to pull image before we can inspect entry point
 	err = PullImage(image)
 	if err != nil {
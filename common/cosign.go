@@ -0,0 +1,164 @@
+/*
+ * Copyright (c) 2019, 2020 Oracle and/or its affiliates. All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package common
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// Image signing providers of signing_details
+const (
+	SigningProviderOCIKMS = "oci-kms"
+	SigningProviderCosign = "cosign"
+)
+
+// SigningProvider returns the provider that signs images, or an empty string when signing_details is empty
+func (s SigningDetails) SigningProvider() string {
+	switch {
+	case s.Provider != "":
+		return s.Provider
+	case s.Key != "" || s.PublicKey != "":
+		return SigningProviderCosign
+	case s.ImageCompartmentId != "" || s.KmsKeyId != "" || s.KmsKeyVersionId != "" || s.SigningAlgorithm != "":
+		return SigningProviderOCIKMS
+	}
+	return ""
+}
+
+// CosignPublicKey returns the key that verifies cosign signatures: public_key, or the .pub file cosign
+// generate-key-pair writes next to a .key file
+func (s SigningDetails) CosignPublicKey() string {
+	if s.PublicKey != "" {
+		return s.PublicKey
+	}
+	if strings.HasSuffix(s.Key, ".key") && !strings.Contains(s.Key, "://") {
+		return strings.TrimSuffix(s.Key, ".key") + ".pub"
+	}
+	// cosign derives the public key of KMS keys itself
+	return s.Key
+}
+
+// WithKeysRelativeTo resolves key files relative to the directory of the func file, so functions deployed
+// with --all find their keys
+func (s SigningDetails) WithKeysRelativeTo(dir string) SigningDetails {
+	resolve := func(key string) string {
+		if key == "" || strings.Contains(key, "://") || filepath.IsAbs(key) {
+			return key
+		}
+		return filepath.Join(dir, key)
+	}
+	s.Key = resolve(s.Key)
+	s.PublicKey = resolve(s.PublicKey)
+	return s
+}
+
+// ImageDigestRef returns the reference of an image by digest, dropping its tag
+func ImageDigestRef(image, digest string) string {
+	if i := strings.Index(image, "@"); i >= 0 {
+		image = image[:i]
+	} else if i := strings.LastIndex(image, ":"); i > strings.LastIndex(image, "/") {
+		image = image[:i]
+	}
+	return image + "@" + digest
+}
+
+func cosignSignArgs(imageRef, key string, transparencyLog bool) []string {
+	args := []string{"sign", "--yes", "--key", key}
+	if !transparencyLog {
+		args = append(args, "--tlog-upload=false")
+	}
+	return append(args, imageRef)
+}
+
+func cosignVerifyArgs(imageRef, publicKey string, transparencyLog bool) []string {
+	args := []string{"verify", "--key", publicKey, "--output", "json"}
+	if !transparencyLog {
+		args = append(args, "--insecure-ignore-tlog=true")
+	}
+	return append(args, imageRef)
+}
+
+func lookCosign() error {
+	if _, err := exec.LookPath("cosign"); err != nil {
+		return fmt.Errorf("signing with cosign keys needs cosign on your PATH: %v", err)
+	}
+	return nil
+}
+
+// CosignSign signs an image with a cosign key and pushes the signature to the registry of the image.
+// Encrypted key files take their password from COSIGN_PASSWORD, or prompt for it.
+func CosignSign(imageRef, key string, transparencyLog bool, out, errOut io.Writer) error {
+	if err := lookCosign(); err != nil {
+		return err
+	}
+	cmd := exec.Command("cosign", cosignSignArgs(imageRef, key, transparencyLog)...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = out
+	cmd.Stderr = errOut
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("error signing %s with cosign: %v", imageRef, err)
+	}
+	return nil
+}
+
+// cosignVerification is the part of the output of cosign verify naming the signed image
+type cosignVerification struct {
+	Critical struct {
+		Image struct {
+			DockerManifestDigest string `json:"docker-manifest-digest"`
+		} `json:"image"`
+	} `json:"critical"`
+}
+
+// CosignVerify checks that an image has a signature made by the key, and returns the digest of the signed image
+func CosignVerify(image, publicKey string, transparencyLog bool) (string, error) {
+	if err := lookCosign(); err != nil {
+		return "", err
+	}
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command("cosign", cosignVerifyArgs(image, publicKey, transparencyLog)...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		reason := strings.TrimSpace(stderr.String())
+		if reason == "" {
+			reason = err.Error()
+		}
+		return "", fmt.Errorf("no valid signature of %s by %s: %s", image, publicKey, reason)
+	}
+	return parseCosignVerification(stdout.Bytes())
+}
+
+func parseCosignVerification(output []byte) (string, error) {
+	var verified []cosignVerification
+	if err := json.Unmarshal(bytes.TrimSpace(output), &verified); err != nil {
+		return "", fmt.Errorf("cannot parse the output of cosign verify: %v", err)
+	}
+	for _, v := range verified {
+		if digest := v.Critical.Image.DockerManifestDigest; digest != "" {
+			return digest, nil
+		}
+	}
+	return "", fmt.Errorf("cosign verify did not report a signed image")
+}
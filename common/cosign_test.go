@@ -0,0 +1,70 @@
+package common
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSigningProvider(t *testing.T) {
+	if p := (SigningDetails{}).SigningProvider(); p != "" {
+		t.Fatalf("expected no provider, got %s", p)
+	}
+	if p := (SigningDetails{KmsKeyId: "ocid1.key.test"}).SigningProvider(); p != SigningProviderOCIKMS {
+		t.Fatalf("expected KMS keys to default to %s, got %s", SigningProviderOCIKMS, p)
+	}
+	if p := (SigningDetails{Key: "cosign.key"}).SigningProvider(); p != SigningProviderCosign {
+		t.Fatalf("expected a key to default to %s, got %s", SigningProviderCosign, p)
+	}
+}
+
+func TestCosignKeys(t *testing.T) {
+	s := SigningDetails{Key: "keys/cosign.key"}.WithKeysRelativeTo("/funcs/hello")
+	if s.Key != "/funcs/hello/keys/cosign.key" {
+		t.Fatalf("expected the key relative to the func dir, got %s", s.Key)
+	}
+	if k := s.CosignPublicKey(); k != "/funcs/hello/keys/cosign.pub" {
+		t.Fatalf("expected the public key next to the private key, got %s", k)
+	}
+	s = SigningDetails{Key: "awskms:///alias/fn"}.WithKeysRelativeTo("/funcs/hello")
+	if k := s.CosignPublicKey(); k != "awskms:///alias/fn" {
+		t.Fatalf("expected a KMS URI to be left alone, got %s", k)
+	}
+}
+
+func TestImageDigestRef(t *testing.T) {
+	for image, expected := range map[string]string{
+		"reg:5000/team/hello:0.0.2":  "reg:5000/team/hello@sha256:abc",
+		"hello":                      "hello@sha256:abc",
+		"reg/hello@sha256:old":       "reg/hello@sha256:abc",
+		"localhost:5000/hello:0.0.1": "localhost:5000/hello@sha256:abc",
+	} {
+		if ref := ImageDigestRef(image, "sha256:abc"); ref != expected {
+			t.Fatalf("expected %s, got %s", expected, ref)
+		}
+	}
+}
+
+func TestCosignArgs(t *testing.T) {
+	expected := []string{"sign", "--yes", "--key", "cosign.key", "--tlog-upload=false", "reg/hello@sha256:abc"}
+	if args := cosignSignArgs("reg/hello@sha256:abc", "cosign.key", false); !reflect.DeepEqual(args, expected) {
+		t.Fatalf("expected %v, got %v", expected, args)
+	}
+	expected = []string{"verify", "--key", "cosign.pub", "--output", "json", "reg/hello:0.0.1"}
+	if args := cosignVerifyArgs("reg/hello:0.0.1", "cosign.pub", true); !reflect.DeepEqual(args, expected) {
+		t.Fatalf("expected %v, got %v", expected, args)
+	}
+}
+
+func TestParseCosignVerification(t *testing.T) {
+	output := `[{"critical":{"identity":{"docker-reference":"reg/hello"},"image":{"docker-manifest-digest":"sha256:abc"},"type":"cosign container image signature"},"optional":null}]`
+	digest, err := parseCosignVerification([]byte(output + "\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if digest != "sha256:abc" {
+		t.Fatalf("expected sha256:abc, got %s", digest)
+	}
+	if _, err := parseCosignVerification([]byte("[]")); err == nil {
+		t.Fatal("expected an error without a signed image")
+	}
+}
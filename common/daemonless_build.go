@@ -0,0 +1,317 @@
+/*
+ * Copyright (c) 2019, 2020 Oracle and/or its affiliates. All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package common
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/fnproject/cli/config"
+)
+
+// kanikoExecutor is where the kaniko executor lives in the official kaniko images
+const kanikoExecutor = "/kaniko/executor"
+
+// daemonlessBuild is a build of a function image by a builder that needs no container engine daemon
+type daemonlessBuild struct {
+	binary     string
+	dir        string
+	imageName  string
+	dockerfile string
+	buildArgs  []string
+	noCache    bool
+	cache      BuildCache
+	platforms  []string
+	// push is false for local builds
+	push bool
+	// archive receives the image of a local build, for builders that keep no local image store
+	archive string
+	// digestFile receives the digest of the pushed image
+	digestFile string
+}
+
+var (
+	pushedDigestsMu sync.Mutex
+	pushedDigests   = map[string]string{}
+)
+
+// PushedImageDigest returns the digest of an image pushed by a daemonless build of this process. Daemonless
+// builders keep no local image store, so it is the only way to know the digest without asking the registry.
+func PushedImageDigest(imageName string) (string, bool) {
+	pushedDigestsMu.Lock()
+	defer pushedDigestsMu.Unlock()
+	digest, ok := pushedDigests[imageName]
+	return digest, ok
+}
+
+// GetRunnableContainerEngineType returns the container engine type for commands that run, pull or push
+// images, which the daemonless builders can't do.
+func GetRunnableContainerEngineType() (string, error) {
+	containerEngineType, err := GetContainerEngineType()
+	if err != nil {
+		return "", err
+	}
+	if config.IsDaemonlessContainerEngine(containerEngineType) {
+		return "", fmt.Errorf("container engine %s can only build and push images with `fn deploy`, this command needs docker or podman", containerEngineType)
+	}
+	return containerEngineType, nil
+}
+
+// daemonlessBuilderBinary returns the command that runs the builder of a daemonless container engine.
+// BuildKit is run through buildctl-daemonless.sh when it is installed, which starts a rootless buildkitd
+// for the build, otherwise buildctl needs BUILDKIT_HOST to point at a running buildkitd.
+func daemonlessBuilderBinary(containerEngineType string) (string, error) {
+	var candidates []string
+	switch containerEngineType {
+	case config.ContainerEngineBuildKit:
+		candidates = []string{"buildctl-daemonless.sh", "buildctl"}
+	case config.ContainerEngineKaniko:
+		candidates = []string{"executor", kanikoExecutor}
+	case config.ContainerEngineBuildah:
+		candidates = []string{"buildah"}
+	default:
+		return "", fmt.Errorf("%s is not a daemonless container engine", containerEngineType)
+	}
+	for _, c := range candidates {
+		if path, err := exec.LookPath(c); err == nil {
+			return path, nil
+		}
+	}
+	return "", fmt.Errorf("Cannot find the %v builder, make sure %s is installed and on your PATH", containerEngineType, strings.Join(candidates, " or "))
+}
+
+// runDaemonlessBuild builds the image and, unless it is a local build, pushes it to its registry. Local
+// builds write the image to archive when the builder keeps no local image store, see daemonlessBuildArchives.
+func runDaemonlessBuild(dir, imageName, dockerfile string, buildArgs []string, noCache bool, cache BuildCache, containerEngineType string, platforms []string, push bool, archive string, out, errOut io.Writer) error {
+	binary, err := daemonlessBuilderBinary(containerEngineType)
+	if err != nil {
+		return err
+	}
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return err
+	}
+	if !filepath.IsAbs(dockerfile) {
+		dockerfile = filepath.Join(absDir, filepath.Base(dockerfile))
+	}
+
+	digestFile, err := ioutil.TempFile("", "fn-image-digest-")
+	if err != nil {
+		return err
+	}
+	digestFile.Close()
+	defer os.Remove(digestFile.Name())
+
+	b := daemonlessBuild{
+		binary:     binary,
+		dir:        absDir,
+		imageName:  imageName,
+		dockerfile: dockerfile,
+		buildArgs:  expandBuildArgs(append(append([]string{}, buildArgs...), "HTTP_PROXY", "HTTPS_PROXY")),
+		noCache:    noCache,
+		cache:      cache,
+		platforms:  platforms,
+		push:       push,
+		archive:    archive,
+		digestFile: digestFile.Name(),
+	}
+	cmds, err := daemonlessBuildCommands(containerEngineType, b)
+	if err != nil {
+		return err
+	}
+	for _, args := range cmds {
+		cmd := exec.Command(args[0], args[1:]...)
+		cmd.Dir = absDir
+		cmd.Stdout = out
+		cmd.Stderr = errOut
+		if err := cmd.Run(); err != nil {
+			return fmt.Errorf("error running %v: %v", filepath.Base(args[0]), err)
+		}
+	}
+
+	if push {
+		if digest := readPushedDigest(containerEngineType, digestFile.Name()); digest != "" {
+			pushedDigestsMu.Lock()
+			pushedDigests[imageName] = digest
+			pushedDigestsMu.Unlock()
+		}
+	}
+	return nil
+}
+
+// daemonlessBuildArchives reports whether local builds of a daemonless container engine have to write the
+// image to an archive. BuildKit and kaniko keep no image once they are done, buildah keeps it in its local storage.
+func daemonlessBuildArchives(containerEngineType string) bool {
+	return containerEngineType != config.ContainerEngineBuildah
+}
+
+// daemonlessBuildCommands returns the commands that build the image and push it when b.push is set
+func daemonlessBuildCommands(containerEngineType string, b daemonlessBuild) ([][]string, error) {
+	switch containerEngineType {
+	case config.ContainerEngineBuildKit:
+		return buildKitCommands(b)
+	case config.ContainerEngineKaniko:
+		return kanikoCommands(b)
+	case config.ContainerEngineBuildah:
+		return buildahCommands(b)
+	}
+	return nil, fmt.Errorf("%s is not a daemonless container engine", containerEngineType)
+}
+
+func buildKitCommands(b daemonlessBuild) ([][]string, error) {
+	args := []string{
+		b.binary, "build",
+		"--frontend", "dockerfile.v0",
+		"--local", "context=" + b.dir,
+		"--local", "dockerfile=" + filepath.Dir(b.dockerfile),
+		"--opt", "filename=" + filepath.Base(b.dockerfile),
+	}
+	if len(b.platforms) > 0 {
+		args = append(args, "--opt", "platform="+strings.Join(b.platforms, ","))
+	}
+	if b.noCache {
+		args = append(args, "--no-cache")
+	}
+	cacheArgs, err := buildKitCacheArgs(b.cache)
+	if err != nil {
+		return nil, err
+	}
+	args = append(args, cacheArgs...)
+	for _, a := range b.buildArgs {
+		args = append(args, "--opt", "build-arg:"+a)
+	}
+	if !b.push && b.archive != "" {
+		args = append(args, "--output", fmt.Sprintf("type=oci,dest=%s,name=%s", b.archive, b.imageName))
+	} else {
+		args = append(args, "--output", fmt.Sprintf("type=image,name=%s,push=%t", b.imageName, b.push))
+	}
+	if b.push {
+		args = append(args, "--metadata-file", b.digestFile)
+	}
+	return [][]string{args}, nil
+}
+
+func kanikoCommands(b daemonlessBuild) ([][]string, error) {
+	if len(b.platforms) > 1 {
+		return nil, fmt.Errorf("kaniko builds a single platform, use buildkit or buildah to build for %s", strings.Join(b.platforms, ", "))
+	}
+	args := []string{
+		b.binary,
+		"--context", "dir://" + b.dir,
+		"--dockerfile", b.dockerfile,
+		"--destination", b.imageName,
+	}
+	if len(b.platforms) == 1 {
+		args = append(args, "--custom-platform", b.platforms[0])
+	}
+	for _, a := range b.buildArgs {
+		args = append(args, "--build-arg", a)
+	}
+	// kaniko only caches layers when asked to with --cache, so --no-cache drops the cache
+	if !b.noCache {
+		cacheArgs, err := kanikoCacheArgs(b.cache)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, cacheArgs...)
+	}
+	if b.push {
+		args = append(args, "--digest-file", b.digestFile)
+	} else {
+		args = append(args, "--no-push")
+		if b.archive != "" {
+			args = append(args, "--tar-path", b.archive)
+		}
+	}
+	return [][]string{args}, nil
+}
+
+func buildahCommands(b daemonlessBuild) ([][]string, error) {
+	build := []string{b.binary, "build", "-f", b.dockerfile}
+	multiArch := len(b.platforms) > 1
+	if multiArch {
+		build = append(build, "--manifest", b.imageName)
+	} else {
+		build = append(build, "-t", b.imageName)
+	}
+	if len(b.platforms) > 0 {
+		build = append(build, "--platform", strings.Join(b.platforms, ","))
+	}
+	if b.noCache {
+		build = append(build, "--no-cache")
+	}
+	cacheArgs, err := buildCacheArgs(config.ContainerEngineBuildah, b.cache)
+	if err != nil {
+		return nil, err
+	}
+	build = append(build, cacheArgs...)
+	for _, a := range b.buildArgs {
+		build = append(build, "--build-arg", a)
+	}
+	build = append(build, b.dir)
+	cmds := [][]string{build}
+
+	if b.push {
+		if multiArch {
+			cmds = append(cmds, []string{b.binary, "manifest", "push", "--all", "--digestfile", b.digestFile, b.imageName, "docker://" + b.imageName})
+		} else {
+			cmds = append(cmds, []string{b.binary, "push", "--digestfile", b.digestFile, b.imageName, "docker://" + b.imageName})
+		}
+	}
+	return cmds, nil
+}
+
+// expandBuildArgs gives build args without a value the value of the environment variable of the same
+// name, like docker build does, and drops them when it is not set.
+func expandBuildArgs(buildArgs []string) []string {
+	var expanded []string
+	for _, a := range buildArgs {
+		if strings.Contains(a, "=") {
+			expanded = append(expanded, a)
+			continue
+		}
+		if v, ok := os.LookupEnv(a); ok {
+			expanded = append(expanded, a+"="+v)
+		}
+	}
+	return expanded
+}
+
+// readPushedDigest reads the digest written by a builder. buildctl writes a JSON metadata file, kaniko
+// and buildah only the digest.
+func readPushedDigest(containerEngineType, path string) string {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	if containerEngineType != config.ContainerEngineBuildKit {
+		return strings.TrimSpace(string(content))
+	}
+	var metadata map[string]interface{}
+	if err := json.Unmarshal(content, &metadata); err != nil {
+		return ""
+	}
+	digest, _ := metadata["containerimage.digest"].(string)
+	return digest
+}
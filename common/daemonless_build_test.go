@@ -0,0 +1,144 @@
+package common
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/fnproject/cli/config"
+)
+
+func testDaemonlessBuild(push bool, platforms ...string) daemonlessBuild {
+	return daemonlessBuild{
+		binary:     "builder",
+		dir:        "/src/hello",
+		imageName:  "registry.example.com/team/hello:0.0.2",
+		dockerfile: "/src/hello/Dockerfile123",
+		buildArgs:  []string{"A=1"},
+		platforms:  platforms,
+		push:       push,
+		digestFile: "/tmp/digest",
+	}
+}
+
+func TestBuildKitCommands(t *testing.T) {
+	cmds, err := daemonlessBuildCommands(config.ContainerEngineBuildKit, testDaemonlessBuild(true, "linux/amd64", "linux/arm64"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := [][]string{{
+		"builder", "build",
+		"--frontend", "dockerfile.v0",
+		"--local", "context=/src/hello",
+		"--local", "dockerfile=/src/hello",
+		"--opt", "filename=Dockerfile123",
+		"--opt", "platform=linux/amd64,linux/arm64",
+		"--opt", "build-arg:A=1",
+		"--output", "type=image,name=registry.example.com/team/hello:0.0.2,push=true",
+		"--metadata-file", "/tmp/digest",
+	}}
+	if !reflect.DeepEqual(cmds, expected) {
+		t.Fatalf("expected %v, got %v", expected, cmds)
+	}
+
+	// local builds export the image, buildctl keeps nothing once it is done
+	b := testDaemonlessBuild(false)
+	b.archive = "/home/me/.fn/images/hello.tar"
+	cmds, err = daemonlessBuildCommands(config.ContainerEngineBuildKit, b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	output := cmds[0][len(cmds[0])-2:]
+	if !reflect.DeepEqual(output, []string{"--output", "type=oci,dest=/home/me/.fn/images/hello.tar,name=registry.example.com/team/hello:0.0.2"}) {
+		t.Fatalf("expected the local build to write an OCI archive, got %v", cmds)
+	}
+}
+
+func TestKanikoCommands(t *testing.T) {
+	cmds, err := daemonlessBuildCommands(config.ContainerEngineKaniko, testDaemonlessBuild(false))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := [][]string{{
+		"builder",
+		"--context", "dir:///src/hello",
+		"--dockerfile", "/src/hello/Dockerfile123",
+		"--destination", "registry.example.com/team/hello:0.0.2",
+		"--build-arg", "A=1",
+		"--no-push",
+	}}
+	if !reflect.DeepEqual(cmds, expected) {
+		t.Fatalf("expected %v, got %v", expected, cmds)
+	}
+
+	b := testDaemonlessBuild(false)
+	b.archive = "/home/me/.fn/images/hello.tar"
+	cmds, err = daemonlessBuildCommands(config.ContainerEngineKaniko, b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(cmds[0][len(cmds[0])-3:], []string{"--no-push", "--tar-path", "/home/me/.fn/images/hello.tar"}) {
+		t.Fatalf("expected the local build to write a tarball, got %v", cmds)
+	}
+
+	if _, err := daemonlessBuildCommands(config.ContainerEngineKaniko, testDaemonlessBuild(true, "linux/amd64", "linux/arm64")); err == nil {
+		t.Fatal("expected kaniko to refuse a multi-platform build")
+	}
+}
+
+func TestBuildahCommands(t *testing.T) {
+	cmds, err := daemonlessBuildCommands(config.ContainerEngineBuildah, testDaemonlessBuild(true, "linux/amd64", "linux/arm64"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	image := "registry.example.com/team/hello:0.0.2"
+	expected := [][]string{
+		{"builder", "build", "-f", "/src/hello/Dockerfile123", "--manifest", image, "--platform", "linux/amd64,linux/arm64", "--build-arg", "A=1", "/src/hello"},
+		{"builder", "manifest", "push", "--all", "--digestfile", "/tmp/digest", image, "docker://" + image},
+	}
+	if !reflect.DeepEqual(cmds, expected) {
+		t.Fatalf("expected %v, got %v", expected, cmds)
+	}
+
+	cmds, err = daemonlessBuildCommands(config.ContainerEngineBuildah, testDaemonlessBuild(false))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cmds) != 1 || !reflect.DeepEqual(cmds[0][4:6], []string{"-t", image}) {
+		t.Fatalf("expected a single tagged build, got %v", cmds)
+	}
+}
+
+func TestExpandBuildArgs(t *testing.T) {
+	os.Setenv("FN_TEST_BUILD_ARG", "set")
+	defer os.Unsetenv("FN_TEST_BUILD_ARG")
+	os.Unsetenv("FN_TEST_UNSET_BUILD_ARG")
+
+	got := expandBuildArgs([]string{"A=1", "FN_TEST_BUILD_ARG", "FN_TEST_UNSET_BUILD_ARG"})
+	expected := []string{"A=1", "FN_TEST_BUILD_ARG=set"}
+	if !reflect.DeepEqual(got, expected) {
+		t.Fatalf("expected %v, got %v", expected, got)
+	}
+}
+
+func TestReadPushedDigest(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fn-digest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	metadata := filepath.Join(dir, "metadata.json")
+	ioutil.WriteFile(metadata, []byte(`{"containerimage.digest":"sha256:abc"}`), 0600)
+	if d := readPushedDigest(config.ContainerEngineBuildKit, metadata); d != "sha256:abc" {
+		t.Fatalf("expected sha256:abc from buildctl metadata, got %q", d)
+	}
+
+	digest := filepath.Join(dir, "digest")
+	ioutil.WriteFile(digest, []byte("sha256:def\n"), 0600)
+	if d := readPushedDigest(config.ContainerEngineKaniko, digest); d != "sha256:def" {
+		t.Fatalf("expected sha256:def from the digest file, got %q", d)
+	}
+}
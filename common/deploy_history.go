@@ -0,0 +1,207 @@
+/*
+ * Copyright (c) 2019, 2020 Oracle and/or its affiliates. All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package common
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/user"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/fnproject/cli/config"
+	"github.com/fnproject/fn_go/modelsv2"
+	"github.com/fnproject/fn_go/provider"
+	"github.com/gofrs/flock"
+)
+
+const (
+	deployHistoryFileName = "deploy-history.jsonl"
+
+	// DeployHistoryActionDeploy is recorded for changes made by fn deploy
+	DeployHistoryActionDeploy = "deploy"
+	// DeployHistoryActionRollback is recorded for changes made by fn rollback
+	DeployHistoryActionRollback = "rollback"
+)
+
+// FnSnapshot is a function with its triggers at a point in time
+type FnSnapshot struct {
+	Fn       *modelsv2.Fn        `json:"fn"`
+	Triggers []*modelsv2.Trigger `json:"triggers,omitempty"`
+}
+
+// DeployHistoryEntry is a single change of a function made by deploy or rollback
+type DeployHistoryEntry struct {
+	Time     time.Time      `json:"time"`
+	Action   string         `json:"action"`
+	Key      DeployStateKey `json:"key"`
+	OldImage string         `json:"old_image,omitempty"`
+	NewImage string         `json:"new_image,omitempty"`
+	// ImageDigest is the registry digest of the new image, empty for local deploys
+	ImageDigest string `json:"image_digest,omitempty"`
+	// GitCommit is the commit checked out in the function directory, suffixed with -dirty
+	// when the working tree had uncommitted changes
+	GitCommit string `json:"git_commit,omitempty"`
+	User      string `json:"user,omitempty"`
+	// Previous is the function before the change, nil when the change created the function
+	Previous *FnSnapshot `json:"previous,omitempty"`
+}
+
+// DeployHistory is an append-only log of function changes, one JSON document per line
+type DeployHistory struct {
+	path string
+	now  func() time.Time
+}
+
+// NewDefaultDeployHistory returns the deploy history in the user's Fn CLI config directory.
+func NewDefaultDeployHistory() *DeployHistory {
+	return NewDeployHistory(filepath.Join(config.GetHomeDir(), ".fn", deployHistoryFileName))
+}
+
+// NewDeployHistory returns a deploy history backed by the given file path.
+func NewDeployHistory(path string) *DeployHistory {
+	return &DeployHistory{
+		path: path,
+		now:  time.Now,
+	}
+}
+
+// NewDeployHistoryEntry returns an entry for a function in the active context and provider.
+func NewDeployHistoryEntry(currentProvider provider.Provider, action, appName, fnName string) DeployHistoryEntry {
+	return DeployHistoryEntry{
+		Action: action,
+		Key:    NewDeployStateKey(currentProvider, appName, fnName),
+		User:   currentUserName(),
+	}
+}
+
+// GitCommitOf returns the commit checked out in dir, with a -dirty suffix when the working tree has
+// uncommitted changes. It returns an empty string when dir isn't in a git repository or git isn't installed.
+func GitCommitOf(dir string) string {
+	out, err := exec.Command("git", "-C", dir, "rev-parse", "HEAD").Output()
+	if err != nil {
+		return ""
+	}
+	commit := strings.TrimSpace(string(out))
+	status, err := exec.Command("git", "-C", dir, "status", "--porcelain").Output()
+	if err == nil && len(bytes.TrimSpace(status)) > 0 {
+		commit += "-dirty"
+	}
+	return commit
+}
+
+func currentUserName() string {
+	if u, err := user.Current(); err == nil && u.Username != "" {
+		return u.Username
+	}
+	if name := os.Getenv("USER"); name != "" {
+		return name
+	}
+	return os.Getenv("USERNAME")
+}
+
+// Append adds an entry to the end of the history, stamping it with the current time.
+func (h *DeployHistory) Append(entry DeployHistoryEntry) error {
+	if h.path == "" {
+		return fmt.Errorf("deploy history path is empty")
+	}
+	if err := os.MkdirAll(filepath.Dir(h.path), 0755); err != nil {
+		return err
+	}
+
+	fileLock := flock.New(h.path + ".lock")
+	if err := fileLock.Lock(); err != nil {
+		return err
+	}
+	defer fileLock.Unlock()
+
+	entry.Time = h.now().UTC()
+	b, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(h.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(b, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// Entries returns every entry of the history, oldest first. Lines that can't be parsed are skipped.
+func (h *DeployHistory) Entries() ([]DeployHistoryEntry, error) {
+	f, err := os.Open(h.path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var entries []DeployHistoryEntry
+	s := bufio.NewScanner(f)
+	// snapshots with large config or annotations can exceed the default line limit
+	s.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for s.Scan() {
+		var entry DeployHistoryEntry
+		if err := json.Unmarshal(s.Bytes(), &entry); err != nil {
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	return entries, s.Err()
+}
+
+// AppEntries returns the entries of every function of an app in a context, oldest first. An empty
+// app name matches every app and an empty context every context.
+func (h *DeployHistory) AppEntries(contextName, appName string) ([]DeployHistoryEntry, error) {
+	entries, err := h.Entries()
+	if err != nil {
+		return nil, err
+	}
+	var appEntries []DeployHistoryEntry
+	for _, e := range entries {
+		if (contextName == "" || e.Key.Context == contextName) && (appName == "" || e.Key.AppName == appName) {
+			appEntries = append(appEntries, e)
+		}
+	}
+	return appEntries, nil
+}
+
+// FunctionEntries returns the entries of a single function, oldest first.
+func (h *DeployHistory) FunctionEntries(key DeployStateKey) ([]DeployHistoryEntry, error) {
+	entries, err := h.Entries()
+	if err != nil {
+		return nil, err
+	}
+	var fnEntries []DeployHistoryEntry
+	for _, e := range entries {
+		if e.Key.sameApp(key) && e.Key.FnName == key.FnName {
+			fnEntries = append(fnEntries, e)
+		}
+	}
+	return fnEntries, nil
+}
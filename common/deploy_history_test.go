@@ -0,0 +1,97 @@
+package common
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/fnproject/fn_go/modelsv2"
+)
+
+func TestDeployHistoryAppendAndFunctionEntries(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "deploy-history.jsonl")
+	history := NewDeployHistory(path)
+
+	hello := DeployStateKey{Context: "ctx", Provider: "default", AppName: "app", FnName: "hello"}
+	world := hello
+	world.FnName = "world"
+
+	entries := []DeployHistoryEntry{
+		{Action: DeployHistoryActionDeploy, Key: hello, NewImage: "hello:0.0.1"},
+		{Action: DeployHistoryActionDeploy, Key: world, NewImage: "world:0.0.1"},
+		{Action: DeployHistoryActionDeploy, Key: hello, OldImage: "hello:0.0.1", NewImage: "hello:0.0.2",
+			Previous: &FnSnapshot{Fn: &modelsv2.Fn{Image: "hello:0.0.1"}, Triggers: []*modelsv2.Trigger{{Name: "hello"}}}},
+	}
+	for _, e := range entries {
+		if err := history.Append(e); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// a corrupt line does not hide the rest of the history
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString("{not json\n")
+	f.Close()
+
+	got, err := history.FunctionEntries(hello)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 {
+		t.Fatalf("expected 2 entries for hello, got %d", len(got))
+	}
+	if got[1].NewImage != "hello:0.0.2" || got[1].Previous == nil || got[1].Previous.Fn.Image != "hello:0.0.1" || len(got[1].Previous.Triggers) != 1 {
+		t.Fatalf("unexpected entry %+v", got[1])
+	}
+	if got[0].Time.IsZero() {
+		t.Fatal("expected entries to be timestamped")
+	}
+}
+
+func TestDeployHistoryAppEntries(t *testing.T) {
+	history := NewDeployHistory(filepath.Join(t.TempDir(), "deploy-history.jsonl"))
+
+	keys := []DeployStateKey{
+		{Context: "dev", AppName: "app", FnName: "hello"},
+		{Context: "dev", AppName: "other", FnName: "hello"},
+		{Context: "prod", AppName: "app", FnName: "hello"},
+	}
+	for _, k := range keys {
+		if err := history.Append(DeployHistoryEntry{Action: DeployHistoryActionDeploy, Key: k, User: "alice", GitCommit: "abc123-dirty"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, tc := range []struct {
+		context, app string
+		want         int
+	}{
+		{"dev", "app", 1},
+		{"dev", "", 2},
+		{"", "app", 2},
+		{"", "", 3},
+		{"staging", "", 0},
+	} {
+		got, err := history.AppEntries(tc.context, tc.app)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(got) != tc.want {
+			t.Fatalf("context %q app %q: expected %d entries, got %d", tc.context, tc.app, tc.want, len(got))
+		}
+	}
+
+	got, _ := history.AppEntries("dev", "app")
+	if got[0].User != "alice" || got[0].GitCommit != "abc123-dirty" {
+		t.Fatalf("audit fields were not stored: %+v", got[0])
+	}
+}
+
+func TestGitCommitOfOutsideRepository(t *testing.T) {
+	if commit := GitCommitOf(t.TempDir()); commit != "" {
+		t.Fatalf("expected no commit outside a git repository, got %q", commit)
+	}
+}
@@ -0,0 +1,176 @@
+/*
+ * Copyright (c) 2019, 2020 Oracle and/or its affiliates. All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package common
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/fnproject/cli/config"
+	"github.com/fnproject/fn_go/provider"
+	"github.com/gofrs/flock"
+)
+
+const (
+	deployStateFileName = "deploy-state.json"
+	deployStateVersion  = 1
+)
+
+// DeployStateKey identifies a function in a provider context, like the invoke endpoint cache does
+type DeployStateKey = InvokeEndpointCacheKey
+
+// DeployStateEntry records what was last deployed for a function
+type DeployStateEntry struct {
+	Key         DeployStateKey `json:"key"`
+	SourceHash  string         `json:"source_hash"`
+	Image       string         `json:"image"`
+	ImageDigest string         `json:"image_digest,omitempty"`
+	// Function is the name of the deployed function when it isn't the name of the key, as with blue-green deploys
+	Function   string    `json:"function,omitempty"`
+	DeployedAt time.Time `json:"deployed_at"`
+}
+
+// FunctionName returns the name of the deployed function, name being the function of the key
+func (e DeployStateEntry) FunctionName(name string) string {
+	if e.Function != "" {
+		return e.Function
+	}
+	return name
+}
+
+// IsImage reports whether image is the recorded image, by tag or, for deploys pinned to it, by digest
+func (e DeployStateEntry) IsImage(image string) bool {
+	return image == e.Image || e.ImageDigest != "" && image == ImageDigestRef(e.Image, e.ImageDigest)
+}
+
+type deployStateData struct {
+	Version int                         `json:"version"`
+	Entries map[string]DeployStateEntry `json:"entries"`
+}
+
+// DeployState stores the source hash and image of the last deploy of every function, so
+// `deploy --all` can skip functions that have not changed since.
+type DeployState struct {
+	path string
+	now  func() time.Time
+}
+
+// NewDefaultDeployState returns the deploy state in the user's Fn CLI config directory.
+func NewDefaultDeployState() *DeployState {
+	return NewDeployState(filepath.Join(config.GetHomeDir(), ".fn", deployStateFileName))
+}
+
+// NewDeployState returns a deploy state backed by the given file path.
+func NewDeployState(path string) *DeployState {
+	return &DeployState{
+		path: path,
+		now:  time.Now,
+	}
+}
+
+// NewDeployStateKey identifies a function in the active context and provider.
+func NewDeployStateKey(currentProvider provider.Provider, appName, fnName string) DeployStateKey {
+	return NewInvokeEndpointCacheKey(currentProvider, appName, fnName)
+}
+
+// Get returns the last deploy recorded for the function.
+func (s *DeployState) Get(key DeployStateKey) (DeployStateEntry, bool) {
+	data, err := readDeployState(s.path)
+	if err != nil {
+		return DeployStateEntry{}, false
+	}
+	entry, ok := data.Entries[key.cacheID()]
+	return entry, ok
+}
+
+// Put records a deploy of the function.
+func (s *DeployState) Put(key DeployStateKey, entry DeployStateEntry) error {
+	if s.path == "" {
+		return fmt.Errorf("deploy state path is empty")
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return err
+	}
+
+	fileLock := flock.New(s.path + ".lock")
+	if err := fileLock.Lock(); err != nil {
+		return err
+	}
+	defer fileLock.Unlock()
+
+	data, err := readDeployState(s.path)
+	if err != nil {
+		return err
+	}
+	entry.Key = key
+	entry.DeployedAt = s.now().UTC()
+	data.Entries[key.cacheID()] = entry
+	return writeDeployState(s.path, data)
+}
+
+func readDeployState(path string) (*deployStateData, error) {
+	data := &deployStateData{
+		Version: deployStateVersion,
+		Entries: map[string]DeployStateEntry{},
+	}
+
+	content, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return data, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(content) == 0 {
+		return data, nil
+	}
+	if err := json.Unmarshal(content, data); err != nil {
+		// a corrupt state only means every function is deployed again
+		return &deployStateData{Version: deployStateVersion, Entries: map[string]DeployStateEntry{}}, nil
+	}
+	if data.Entries == nil {
+		data.Entries = map[string]DeployStateEntry{}
+	}
+	return data, nil
+}
+
+func writeDeployState(path string, data *deployStateData) error {
+	tmp, err := ioutil.TempFile(filepath.Dir(path), ".deploy-state-")
+	if err != nil {
+		return err
+	}
+	tmpName := tmp.Name()
+	defer os.Remove(tmpName)
+
+	enc := json.NewEncoder(tmp)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmpName, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmpName, path)
+}
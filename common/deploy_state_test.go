@@ -0,0 +1,48 @@
+package common
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestDeployStatePutGet(t *testing.T) {
+	state := NewDeployState(filepath.Join(t.TempDir(), "deploy-state.json"))
+	key := DeployStateKey{Context: "ctx", Provider: "default", AppName: "app", FnName: "fn"}
+
+	if _, ok := state.Get(key); ok {
+		t.Fatal("expected no entry in an empty state")
+	}
+	if err := state.Put(key, DeployStateEntry{SourceHash: "sha256:abc", Image: "fn:0.0.2"}); err != nil {
+		t.Fatal(err)
+	}
+
+	entry, ok := state.Get(key)
+	if !ok || entry.SourceHash != "sha256:abc" || entry.Image != "fn:0.0.2" || entry.DeployedAt.IsZero() {
+		t.Fatalf("unexpected entry %+v", entry)
+	}
+
+	other := key
+	other.Context = "other"
+	if _, ok := state.Get(other); ok {
+		t.Fatal("expected entries to be scoped to their context")
+	}
+}
+
+func TestDeployStateEntryIsImage(t *testing.T) {
+	entry := DeployStateEntry{Image: "reg/fn:0.0.2", ImageDigest: "sha256:abc"}
+	if !entry.IsImage("reg/fn:0.0.2") || !entry.IsImage("reg/fn@sha256:abc") {
+		t.Fatal("expected the image to match by tag and by digest")
+	}
+	if entry.IsImage("reg/fn@sha256:def") || (DeployStateEntry{Image: "reg/fn:0.0.2"}).IsImage("reg/fn@") {
+		t.Fatal("expected other digests not to match")
+	}
+}
+
+func TestDeployStateEntryFunctionName(t *testing.T) {
+	if name := (DeployStateEntry{}).FunctionName("hello"); name != "hello" {
+		t.Fatalf("expected the function of the key, got %s", name)
+	}
+	if name := (DeployStateEntry{Function: "hello-0-0-2"}).FunctionName("hello"); name != "hello-0-0-2" {
+		t.Fatalf("expected the deployed function, got %s", name)
+	}
+}
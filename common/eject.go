@@ -0,0 +1,77 @@
+/*
+ * Copyright (c) 2019, 2020 Oracle and/or its affiliates. All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package common
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+
+	"github.com/fnproject/cli/langs"
+)
+
+// GenerateDockerfileV20180708 returns the Dockerfile fn build generates for a function that has no
+// Dockerfile of its own. With localDebug it is the Dockerfile of fn deploy --local-debug.
+func GenerateDockerfileV20180708(dir string, ff *FuncFileV20180708, localDebug bool) (string, error) {
+	if ff.Runtime == FuncfileDockerRuntime {
+		return "", errors.New("functions with runtime docker are built from their own Dockerfile")
+	}
+	if Exists(filepath.Join(dir, "Dockerfile")) {
+		return "", fmt.Errorf("%s has a Dockerfile, fn build uses it instead of generating one", dir)
+	}
+	helper := langs.GetLangHelper(ff.Runtime)
+	if helper == nil {
+		return "", fmt.Errorf("Cannot build, no language helper found for %v", ff.Runtime)
+	}
+	lines, err := dockerfileLinesV20180708(helper, dir, ff, localDebug)
+	if err != nil {
+		return "", err
+	}
+	var b bytes.Buffer
+	if err := writeLines(&b, lines); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
+// EjectDockerfileV20180708 writes the generated Dockerfile of a function next to its func file and
+// switches the function to runtime docker, so it is built from that Dockerfile from then on. It returns
+// the path of the Dockerfile.
+func EjectDockerfileV20180708(fpath string, ff *FuncFileV20180708) (string, error) {
+	dir := filepath.Dir(fpath)
+	content, err := GenerateDockerfileV20180708(dir, ff, false)
+	if err != nil {
+		return "", err
+	}
+	dockerfile := filepath.Join(dir, "Dockerfile")
+	if err := ioutil.WriteFile(dockerfile, []byte(content), 0644); err != nil {
+		return "", err
+	}
+
+	ff.Runtime = FuncfileDockerRuntime
+	// the Dockerfile sets the images, entrypoint and command now
+	ff.Build_image = ""
+	ff.Run_image = ""
+	ff.Entrypoint = ""
+	ff.Cmd = ""
+	if err := storeFuncFileV20180708(fpath, ff); err != nil {
+		return "", err
+	}
+	return dockerfile, nil
+}
@@ -0,0 +1,90 @@
+package common
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/fnproject/cli/config"
+	"github.com/spf13/viper"
+)
+
+// withDaemonlessEngine keeps the Dockerfile generation from pulling the run image to look up its entrypoint
+func withDaemonlessEngine(t *testing.T) {
+	previous := viper.GetString(config.ContainerEngineType)
+	viper.Set(config.ContainerEngineType, config.ContainerEngineKaniko)
+	t.Cleanup(func() { viper.Set(config.ContainerEngineType, previous) })
+}
+
+func testGoFunction(t *testing.T) (string, *FuncFileV20180708) {
+	dir, err := ioutil.TempDir("", "fn-eject")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	ioutil.WriteFile(filepath.Join(dir, "go.mod"), []byte("module func\n"), 0644)
+	ff := &FuncFileV20180708{
+		Schema_version: LatestYamlVersion,
+		Name:           "hello",
+		Version:        "0.0.1",
+		Runtime:        "go",
+		Build_image:    "fnproject/go:dev",
+		Run_image:      "fnproject/go",
+		Entrypoint:     "./func",
+	}
+	fpath := filepath.Join(dir, "func.yaml")
+	if err := EncodeFuncFileV20180708YAML(fpath, ff); err != nil {
+		t.Fatal(err)
+	}
+	return fpath, ff
+}
+
+func TestGenerateDockerfileV20180708(t *testing.T) {
+	withDaemonlessEngine(t)
+	fpath, ff := testGoFunction(t)
+
+	dockerfile, err := GenerateDockerfileV20180708(filepath.Dir(fpath), ff, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(dockerfile, "FROM fnproject/go:dev as build-stage\n") || !strings.Contains(dockerfile, "\nFROM fnproject/go\n") ||
+		!strings.HasSuffix(dockerfile, "ENTRYPOINT [\"./func\"]\n") {
+		t.Fatalf("unexpected Dockerfile:\n%s", dockerfile)
+	}
+
+	ff.Runtime = FuncfileDockerRuntime
+	if _, err := GenerateDockerfileV20180708(filepath.Dir(fpath), ff, false); err == nil {
+		t.Fatal("expected no Dockerfile to be generated for runtime docker")
+	}
+}
+
+func TestEjectDockerfileV20180708(t *testing.T) {
+	withDaemonlessEngine(t)
+	fpath, ff := testGoFunction(t)
+	dir := filepath.Dir(fpath)
+	expected, err := GenerateDockerfileV20180708(dir, ff, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dockerfile, err := EjectDockerfileV20180708(fpath, ff)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if content, _ := ioutil.ReadFile(dockerfile); string(content) != expected {
+		t.Fatalf("expected the ejected Dockerfile to be the generated one, got:\n%s", content)
+	}
+	ejected, err := ParseFuncFileV20180708(fpath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ejected.Runtime != FuncfileDockerRuntime || ejected.Build_image != "" || ejected.Run_image != "" || ejected.Entrypoint != "" || ejected.Name != "hello" {
+		t.Fatalf("unexpected func file after eject: %+v", ejected)
+	}
+
+	if _, err := EjectDockerfileV20180708(fpath, ejected); err == nil {
+		t.Fatal("expected a second eject to fail")
+	}
+}
@@ -0,0 +1,92 @@
+/*
+ * Copyright (c) 2019, 2020 Oracle and/or its affiliates. All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package common
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// FnIgnoreFileName is the file listing paths that fn ignores, one pattern per line
+const FnIgnoreFileName = ".fnignore"
+
+// LoadFnIgnore reads the ignore patterns of the .fnignore file in dir, skipping
+// blank lines and # comments. found is false when dir has no .fnignore file.
+func LoadFnIgnore(dir string) (patterns []string, found bool, err error) {
+	f, err := os.Open(filepath.Join(dir, FnIgnoreFileName))
+	if os.IsNotExist(err) {
+		return nil, false, nil
+	} else if err != nil {
+		return nil, false, err
+	}
+	defer f.Close()
+
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		line := strings.TrimSpace(s.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		patterns = append(patterns, line)
+	}
+	if err := s.Err(); err != nil {
+		return nil, false, err
+	}
+	return patterns, true, nil
+}
+
+// MatchesIgnorePattern reports whether path matches any of the patterns, either against
+// one of its path segments or against the whole path relative to root.
+func MatchesIgnorePattern(patterns []string, root, path string) bool {
+	rel, err := filepath.Rel(root, path)
+	if err != nil {
+		rel = path
+	}
+	rel = filepath.ToSlash(rel)
+	segs := strings.Split(rel, "/")
+
+	for _, p := range patterns {
+		p = strings.TrimSpace(p)
+		if p == "" {
+			continue
+		}
+
+		// segment match (simple, fast)
+		for _, s := range segs {
+			if s == p {
+				return true
+			}
+			if !strings.Contains(p, "/") {
+				if ok, _ := filepath.Match(p, s); ok {
+					return true
+				}
+			}
+		}
+
+		// glob against rel path
+		if ok, _ := filepath.Match(p, rel); ok {
+			return true
+		}
+		// Also try matching with OS separator style
+		if ok, _ := filepath.Match(p, filepath.FromSlash(rel)); ok {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,138 @@
+/*
+ * Copyright (c) 2019, 2020 Oracle and/or its affiliates. All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package common
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+// funcSourceIgnores are never part of a function's sources
+var funcSourceIgnores = []string{".git", ".fn", "Dockerfile-fn-tmp*"}
+
+// FuncSourceHash returns a hash of everything the image of a function is built from: the files
+// in its directory, its func file and the build inputs (build args and any other settings that
+// change the image). The func file version is left out, as it changes on every deploy.
+//
+// Files matching the patterns of the .fnignore files in root and in the function directory are
+// skipped, as are the directories of other functions nested below it.
+func FuncSourceHash(root, fpath string, ff *FuncFileV20180708, buildInputs []string) (string, error) {
+	dir := filepath.Dir(fpath)
+
+	// the patterns of a .fnignore are relative to the directory it is in
+	ignores := []fnIgnore{{dir: dir, patterns: funcSourceIgnores}}
+	for _, d := range []string{root, dir} {
+		patterns, _, err := LoadFnIgnore(d)
+		if err != nil {
+			return "", err
+		}
+		ignores = append(ignores, fnIgnore{dir: d, patterns: patterns})
+		if d == dir {
+			break
+		}
+	}
+
+	h := sha256.New()
+
+	unversioned := *ff
+	unversioned.Version = ""
+	b, err := yaml.Marshal(&unversioned)
+	if err != nil {
+		return "", err
+	}
+	fmt.Fprintf(h, "funcfile %d\n", len(b))
+	h.Write(b)
+	for _, in := range buildInputs {
+		fmt.Fprintf(h, "input %q\n", in)
+	}
+
+	// filepath.Walk visits files in lexical order, so the hash is stable
+	err = filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if path == dir {
+			return nil
+		}
+		if ignored(ignores, path) {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+
+		switch {
+		case info.IsDir():
+			if _, err := FindFuncfile(path); err == nil {
+				// another function, it is hashed on its own
+				return filepath.SkipDir
+			}
+			return nil
+		case path == fpath:
+			// already hashed without its version
+			return nil
+		case info.Mode()&os.ModeSymlink != 0:
+			target, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(h, "link %q %q\n", rel, target)
+			return nil
+		case !info.Mode().IsRegular():
+			return nil
+		}
+
+		fmt.Fprintf(h, "file %q %o %d\n", rel, info.Mode().Perm()&0111, info.Size())
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		_, err = io.Copy(h, f)
+		return err
+	})
+	if err != nil {
+		return "", err
+	}
+	return "sha256:" + hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// fnIgnore are ignore patterns and the directory they are relative to
+type fnIgnore struct {
+	dir      string
+	patterns []string
+}
+
+func ignored(ignores []fnIgnore, path string) bool {
+	for _, i := range ignores {
+		if MatchesIgnorePattern(i.patterns, i.dir, path) {
+			return true
+		}
+	}
+	return false
+}
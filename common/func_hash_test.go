@@ -0,0 +1,98 @@
+package common
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeTestFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestFuncSourceHash(t *testing.T) {
+	root := t.TempDir()
+	writeTestFiles(t, root, map[string]string{
+		".fnignore":           "*.log\n",
+		"hello/func.yaml":     "schema_version: 20180708\nname: hello\nversion: 0.0.1\nruntime: go\n",
+		"hello/func.go":       "package main\n",
+		"hello/debug.log":     "noise",
+		"hello/sub/func.yaml": "schema_version: 20180708\nname: sub\nversion: 0.0.1\nruntime: go\n",
+		"hello/sub/func.go":   "package main\n",
+	})
+	fpath := filepath.Join(root, "hello", "func.yaml")
+
+	hash := func(buildInputs ...string) string {
+		t.Helper()
+		ff, err := ParseFuncFileV20180708(fpath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		h, err := FuncSourceHash(root, fpath, ff, buildInputs)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return h
+	}
+
+	base := hash()
+	if base != hash() {
+		t.Fatal("expected hash to be stable")
+	}
+
+	writeTestFiles(t, root, map[string]string{
+		"hello/debug.log":   "more noise",
+		"hello/sub/func.go": "package main // changed\n",
+		"hello/func.yaml":   "schema_version: 20180708\nname: hello\nversion: 0.0.2\nruntime: go\n",
+	})
+	if h := hash(); h != base {
+		t.Fatal("expected ignored files, nested functions and the version not to change the hash")
+	}
+
+	if h := hash("FOO=bar"); h == base {
+		t.Fatal("expected build inputs to change the hash")
+	}
+
+	writeTestFiles(t, root, map[string]string{"hello/func.go": "package main // changed\n"})
+	if h := hash(); h == base {
+		t.Fatal("expected a source change to change the hash")
+	}
+}
+
+func TestFuncSourceHashRootIgnoresAreRelativeToRoot(t *testing.T) {
+	root := t.TempDir()
+	writeTestFiles(t, root, map[string]string{
+		".fnignore":           "hello/tmp\n",
+		"hello/.fnignore":     "cache\n",
+		"hello/func.yaml":     "schema_version: 20180708\nname: hello\nversion: 0.0.1\nruntime: go\n",
+		"hello/func.go":       "package main\n",
+		"hello/tmp/scratch":   "noise",
+		"hello/cache/objects": "noise",
+	})
+	fpath := filepath.Join(root, "hello", "func.yaml")
+	ff, err := ParseFuncFileV20180708(fpath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	base, err := FuncSourceHash(root, fpath, ff, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	writeTestFiles(t, root, map[string]string{
+		"hello/tmp/scratch":   "more noise",
+		"hello/cache/objects": "more noise",
+	})
+	if h, err := FuncSourceHash(root, fpath, ff, nil); err != nil || h != base {
+		t.Fatalf("expected paths ignored by the root and function .fnignore not to change the hash, got %v", err)
+	}
+}
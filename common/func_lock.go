@@ -0,0 +1,166 @@
+/*
+ * Copyright (c) 2019, 2020 Oracle and/or its affiliates. All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package common
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+// FuncLockFile is the file next to func.yaml that pins the build and run images of a function by digest
+const FuncLockFile = "func.lock"
+
+const funcLockHeader = "# Generated by fn lock, builds use these images until fn lock is run again\n"
+
+// FuncLock maps the build and run images of func.yaml to the same images pinned by digest
+type FuncLock struct {
+	Images map[string]string `yaml:"images"`
+}
+
+// LoadFuncLock reads the func.lock of the function in dir, it returns nil when the function has none
+func LoadFuncLock(dir string) (*FuncLock, error) {
+	b, err := ioutil.ReadFile(filepath.Join(dir, FuncLockFile))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	lock := &FuncLock{}
+	if err := yaml.Unmarshal(b, lock); err != nil {
+		return nil, fmt.Errorf("could not parse %s: %v", FuncLockFile, err)
+	}
+	return lock, nil
+}
+
+// Write stores the lock in the function directory dir
+func (l *FuncLock) Write(dir string) error {
+	b, err := yaml.Marshal(l)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filepath.Join(dir, FuncLockFile), append([]byte(funcLockHeader), b...), 0644)
+}
+
+// Pin returns the image pinned by digest, a lock that does not pin the image is out of date
+func (l *FuncLock) Pin(image string) (string, error) {
+	if l == nil || image == "" {
+		return image, nil
+	}
+	if pinned, ok := l.Images[image]; ok {
+		return pinned, nil
+	}
+	return "", fmt.Errorf("%s does not pin %s, run `fn lock` to update it", FuncLockFile, image)
+}
+
+// LockFuncV20180708 resolves the build and run images of the function to the digests they have in their
+// registries and writes them to func.lock. Like fn build, it stamps the default images of the runtime
+// into func.yaml when it has none.
+func LockFuncV20180708(fpath string, ff *FuncFileV20180708) (*FuncLock, error) {
+	dir := filepath.Dir(fpath)
+	if ff.Runtime == FuncfileDockerRuntime || Exists(filepath.Join(dir, "Dockerfile")) {
+		return nil, fmt.Errorf("%s has its own Dockerfile, pin the images of its FROM lines by digest there", ff.Name)
+	}
+	ff, err := imageStampFuncFileV20180708(fpath, ff)
+	if err != nil {
+		return nil, err
+	}
+	containerEngineType, err := GetContainerEngineType()
+	if err != nil {
+		return nil, err
+	}
+	lock := &FuncLock{Images: map[string]string{}}
+	for _, image := range []string{ff.Build_image, ff.Run_image} {
+		if image == "" {
+			continue
+		}
+		digest, err := resolveImageDigest(containerEngineType, image)
+		if err != nil {
+			return nil, err
+		}
+		lock.Images[image] = pinImage(image, digest)
+	}
+	if len(lock.Images) == 0 {
+		return nil, fmt.Errorf("%s has no build or run image to lock", ff.Name)
+	}
+	return lock, lock.Write(dir)
+}
+
+// pinImage adds the digest to an image, keeping its tag so func.lock stays readable
+func pinImage(image, digest string) string {
+	if i := strings.Index(image, "@"); i >= 0 {
+		image = image[:i]
+	}
+	return image + "@" + digest
+}
+
+// resolveImageDigest returns the digest of the manifest, or manifest list, an image tag points at in its
+// registry. docker asks the registry with buildx, other engines need skopeo or crane.
+func resolveImageDigest(containerEngineType, image string) (string, error) {
+	if containerEngineType == containerEngineTypeDocker {
+		out, err := exec.Command("docker", "buildx", "imagetools", "inspect", "--format", "{{json .Manifest}}", image).Output()
+		if err != nil {
+			return "", fmt.Errorf("could not resolve the digest of %s: %v", image, commandError(err))
+		}
+		return parseManifestDescriptor(out)
+	}
+	if _, err := exec.LookPath("skopeo"); err == nil {
+		out, err := exec.Command("skopeo", "inspect", "--raw", "docker://"+image).Output()
+		if err != nil {
+			return "", fmt.Errorf("could not resolve the digest of %s: %v", image, commandError(err))
+		}
+		return "sha256:" + sha256Hex(out), nil
+	}
+	if _, err := exec.LookPath("crane"); err == nil {
+		out, err := exec.Command("crane", "digest", image).Output()
+		if err != nil {
+			return "", fmt.Errorf("could not resolve the digest of %s: %v", image, commandError(err))
+		}
+		return strings.TrimSpace(string(out)), nil
+	}
+	return "", fmt.Errorf("resolving image digests with %s needs skopeo or crane on your PATH", containerEngineType)
+}
+
+// parseManifestDescriptor returns the digest of the descriptor printed by buildx imagetools inspect
+func parseManifestDescriptor(out []byte) (string, error) {
+	var descriptor struct {
+		Digest string `json:"digest"`
+	}
+	if err := json.Unmarshal(bytes.TrimSpace(out), &descriptor); err != nil {
+		return "", fmt.Errorf("could not parse the manifest of the image: %v", err)
+	}
+	if descriptor.Digest == "" {
+		return "", fmt.Errorf("the manifest of the image has no digest")
+	}
+	return descriptor.Digest, nil
+}
+
+// commandError adds what a failed command printed on stderr to its error
+func commandError(err error) error {
+	if exitErr, ok := err.(*exec.ExitError); ok && len(exitErr.Stderr) > 0 {
+		return fmt.Errorf("%v: %s", err, strings.TrimSpace(string(exitErr.Stderr)))
+	}
+	return err
+}
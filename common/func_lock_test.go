@@ -0,0 +1,64 @@
+package common
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFuncLockPin(t *testing.T) {
+	var missing *FuncLock
+	if i, err := missing.Pin("fnproject/go:dev"); err != nil || i != "fnproject/go:dev" {
+		t.Fatalf("expected images to be left alone without a lock, got %s %v", i, err)
+	}
+	lock := &FuncLock{Images: map[string]string{"fnproject/go:dev": pinImage("fnproject/go:dev", "sha256:abc")}}
+	if i, _ := lock.Pin("fnproject/go:dev"); i != "fnproject/go:dev@sha256:abc" {
+		t.Fatalf("expected the pinned image, got %s", i)
+	}
+	if _, err := lock.Pin("fnproject/go:1.24-dev"); err == nil {
+		t.Fatal("expected an error for an image the lock does not pin")
+	}
+}
+
+func TestParseManifestDescriptor(t *testing.T) {
+	digest, err := parseManifestDescriptor([]byte(`{"mediaType":"application/vnd.oci.image.index.v1+json","digest":"sha256:abc","size":1609}` + "\n"))
+	if err != nil || digest != "sha256:abc" {
+		t.Fatalf("expected sha256:abc, got %s %v", digest, err)
+	}
+	if _, err := parseManifestDescriptor([]byte(`{}`)); err == nil {
+		t.Fatal("expected an error without a digest")
+	}
+}
+
+func TestGenerateDockerfileWithFuncLock(t *testing.T) {
+	withDaemonlessEngine(t)
+	fpath, ff := testGoFunction(t)
+	dir := filepath.Dir(fpath)
+	lock := &FuncLock{Images: map[string]string{
+		"fnproject/go:dev": "fnproject/go:dev@sha256:build",
+		"fnproject/go":     "fnproject/go@sha256:run",
+	}}
+	if err := lock.Write(dir); err != nil {
+		t.Fatal(err)
+	}
+	if loaded, err := LoadFuncLock(dir); err != nil || len(loaded.Images) != 2 {
+		t.Fatalf("expected the lock to be read back, got %+v %v", loaded, err)
+	}
+
+	dockerfile, err := GenerateDockerfileV20180708(dir, ff, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(dockerfile, "FROM fnproject/go:dev@sha256:build as build-stage\n") || !strings.Contains(dockerfile, "\nFROM fnproject/go@sha256:run\n") {
+		t.Fatalf("expected the pinned images, got:\n%s", dockerfile)
+	}
+	images, err := baseImagesV20180708(dir, ff)
+	if err != nil || images[1] != "fnproject/go@sha256:run" {
+		t.Fatalf("expected the provenance to record the pinned images, got %v %v", images, err)
+	}
+
+	ff.Run_image = "fnproject/go:1.24"
+	if _, err := GenerateDockerfileV20180708(dir, ff, false); err == nil {
+		t.Fatal("expected an out of date lock to fail the build")
+	}
+}
@@ -53,7 +53,7 @@ type OutputMap struct {
 type FFTest struct {
 	Name   string     `yaml:"name,omitempty" json:"name,omitempty"`
 	Input  *InputMap  `yaml:"input,omitempty" json:"input,omitempty"`
-	Output *OutputMap `yaml:"outoutput,omitempty" json:"output,omitempty"`
+	Output *OutputMap `yaml:"output,omitempty" json:"output,omitempty"`
 	Err    *string    `yaml:"err,omitempty" json:"err,omitempty"`
 	// Env    map[string]string `yaml:"env,omitempty" json:"env,omitempty"`
 }
@@ -146,6 +146,15 @@ type SigningDetails struct {
 	KmsKeyId           string `yaml:"kms_key_id,omitempty" json:"kms_key_id,omitempty"`
 	KmsKeyVersionId    string `yaml:"kms_key_version_id,omitempty" json:"kms_key_version_id,omitempty"`
 	SigningAlgorithm   string `yaml:"signing_algorithm,omitempty" json:"signing_algorithm,omitempty"`
+
+	// Provider is oci-kms or cosign, it defaults to cosign when a key is set
+	Provider string `yaml:"provider,omitempty" json:"provider,omitempty"`
+	// Key is the cosign private key: a key file, env://VAR or a KMS URI supported by cosign
+	Key string `yaml:"key,omitempty" json:"key,omitempty"`
+	// PublicKey verifies cosign signatures, it defaults to the .pub file next to a .key file
+	PublicKey string `yaml:"public_key,omitempty" json:"public_key,omitempty"`
+	// TransparencyLog records cosign signatures in the Rekor transparency log
+	TransparencyLog bool `yaml:"transparency_log,omitempty" json:"transparency_log,omitempty"`
 }
 
 // FuncFileV20180708 defines the latest internal structure of a func.yaml/json/yml
@@ -165,16 +174,28 @@ type FuncFileV20180708 struct {
 	Timeout      *int32 `yaml:"timeout,omitempty" json:"timeout,omitempty"`
 	IDLE_timeout *int32 `yaml:"idle_timeout,omitempty" json:"idle_timeout,omitempty"`
 
+	// CacheFrom and CacheTo are the build caches of the function, see BuildCache
+	CacheFrom []string `yaml:"cache_from,omitempty" json:"cache_from,omitempty"`
+	CacheTo   []string `yaml:"cache_to,omitempty" json:"cache_to,omitempty"`
+	// Platforms are the platforms of local builds, such as linux/amd64 and linux/arm64
+	Platforms []string `yaml:"platforms,omitempty" json:"platforms,omitempty"`
+
 	Config      map[string]string      `yaml:"config,omitempty" json:"config,omitempty"`
 	Annotations map[string]interface{} `yaml:"annotations,omitempty" json:"annotations,omitempty"`
 	Deploy      *FuncDeployConfig      `yaml:"deploy,omitempty" json:"deploy,omitempty"`
 
 	SigningDetails SigningDetails `yaml:"signing_details,omitempty" json:"signing_details,omitempty""`
+	// EnforceSignature refuses to deploy images that are not signed by a trusted key
+	EnforceSignature bool `yaml:"enforce_signature,omitempty" json:"enforce_signature,omitempty"`
 
 	Build []string `yaml:"build,omitempty" json:"build,omitempty"`
 
-	Expects  Expects   `yaml:"expects,omitempty" json:"expects,omitempty"`
-	Triggers []Trigger `yaml:"triggers,omitempty" json:"triggers,omitempty"`
+	Expects  Expects    `yaml:"expects,omitempty" json:"expects,omitempty"`
+	Triggers []Trigger  `yaml:"triggers,omitempty" json:"triggers,omitempty"`
+	Tests    []FFTest   `yaml:"tests,omitempty" json:"tests,omitempty"`
+	Smoke    *SmokeTest `yaml:"smoke,omitempty" json:"smoke,omitempty"`
+
+	Attestations *Attestations `yaml:"attestations,omitempty" json:"attestations,omitempty"`
 }
 
 // Trigger represents a trigger for a FuncFileV20180708
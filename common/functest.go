@@ -0,0 +1,134 @@
+/*
+ * Copyright (c) 2019, 2020 Oracle and/or its affiliates. All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package common
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+)
+
+// TestName returns the name of the test, falling back to its position in func.yaml.
+func (t FFTest) TestName(index int) string {
+	if t.Name != "" {
+		return t.Name
+	}
+	return fmt.Sprintf("test-%d", index+1)
+}
+
+// InputBody returns the request payload for the test. String bodies are sent as-is,
+// any other value is encoded as JSON.
+func (t FFTest) InputBody() ([]byte, error) {
+	if t.Input == nil || t.Input.Body == nil {
+		return nil, nil
+	}
+	if s, ok := t.Input.Body.(string); ok {
+		return []byte(s), nil
+	}
+	b, err := json.Marshal(normalizeYAMLValue(t.Input.Body))
+	if err != nil {
+		return nil, fmt.Errorf("could not encode test input body: %v", err)
+	}
+	return b, nil
+}
+
+// Check compares the status and body returned by an invocation against the
+// expectations declared in the test.
+//
+// When `err` is set the invocation is expected to fail, and the response body must
+// contain the error text if it is not empty. Otherwise the invocation must succeed and,
+// if `output.body` is set, the response body must match it. String bodies are compared
+// ignoring surrounding whitespace, any other value is compared as JSON.
+func (t FFTest) Check(status int, body []byte) error {
+	failed := status >= http.StatusBadRequest
+	if t.Err != nil {
+		if !failed {
+			return fmt.Errorf("expected an error but the function succeeded with status %d", status)
+		}
+		if *t.Err != "" && !strings.Contains(string(body), *t.Err) {
+			return fmt.Errorf("expected error containing %q, got %q", *t.Err, strings.TrimSpace(string(body)))
+		}
+		return nil
+	}
+	if failed {
+		return fmt.Errorf("function failed with status %d: %s", status, strings.TrimSpace(string(body)))
+	}
+	if t.Output == nil || t.Output.Body == nil {
+		return nil
+	}
+
+	if expected, ok := t.Output.Body.(string); ok {
+		got := strings.TrimSpace(string(body))
+		if strings.TrimSpace(expected) != got {
+			return fmt.Errorf("expected body %q, got %q", strings.TrimSpace(expected), got)
+		}
+		return nil
+	}
+
+	expected := normalizeYAMLValue(t.Output.Body)
+	// round trip through JSON so numbers and nested values compare like the decoded response
+	b, err := json.Marshal(expected)
+	if err != nil {
+		return fmt.Errorf("could not encode expected output body: %v", err)
+	}
+	if err := json.Unmarshal(b, &expected); err != nil {
+		return fmt.Errorf("could not decode expected output body: %v", err)
+	}
+
+	var got interface{}
+	if err := json.Unmarshal(body, &got); err != nil {
+		return fmt.Errorf("expected JSON body %s, got %q", b, strings.TrimSpace(string(body)))
+	}
+	if !reflect.DeepEqual(expected, got) {
+		var compact bytes.Buffer
+		if json.Compact(&compact, body) != nil {
+			compact.Write(body)
+		}
+		return fmt.Errorf("expected body %s, got %s", b, compact.String())
+	}
+	return nil
+}
+
+// normalizeYAMLValue converts the map[interface{}]interface{} values produced by
+// the YAML decoder into map[string]interface{} so they can be encoded as JSON.
+func normalizeYAMLValue(v interface{}) interface{} {
+	switch x := v.(type) {
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(x))
+		for k, val := range x {
+			m[fmt.Sprint(k)] = normalizeYAMLValue(val)
+		}
+		return m
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(x))
+		for k, val := range x {
+			m[k] = normalizeYAMLValue(val)
+		}
+		return m
+	case []interface{}:
+		s := make([]interface{}, len(x))
+		for i, val := range x {
+			s[i] = normalizeYAMLValue(val)
+		}
+		return s
+	default:
+		return v
+	}
+}
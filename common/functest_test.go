@@ -0,0 +1,114 @@
+package common
+
+import (
+	"strings"
+	"testing"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+func parseFuncTests(t *testing.T, content string) []FFTest {
+	t.Helper()
+	ff := &FuncFileV20180708{}
+	if err := yaml.Unmarshal([]byte(content), ff); err != nil {
+		t.Fatalf("failed to parse func.yaml: %v", err)
+	}
+	return ff.Tests
+}
+
+func TestFuncFileTestsParseFromYAML(t *testing.T) {
+	tests := parseFuncTests(t, `
+schema_version: 20180708
+name: hello
+tests:
+- name: greets
+  input:
+    body:
+      name: Bob
+  output:
+    body:
+      message: Hello Bob
+- err: boom
+`)
+	if len(tests) != 2 {
+		t.Fatalf("expected 2 tests, got %d", len(tests))
+	}
+	if tests[0].Output == nil || tests[0].Output.Body == nil {
+		t.Fatal("expected output body to be parsed")
+	}
+	if tests[1].Err == nil || *tests[1].Err != "boom" {
+		t.Fatalf("expected err to be parsed, got %v", tests[1].Err)
+	}
+	if got := tests[1].TestName(1); got != "test-2" {
+		t.Fatalf("expected default test name test-2, got %s", got)
+	}
+}
+
+func TestFFTestInputBody(t *testing.T) {
+	tests := parseFuncTests(t, `
+tests:
+- input:
+    body: plain text
+- input:
+    body:
+      name: Bob
+      tags: [a, b]
+- name: empty
+`)
+	cases := []string{"plain text", `{"name":"Bob","tags":["a","b"]}`, ""}
+	for i, want := range cases {
+		got, err := tests[i].InputBody()
+		if err != nil {
+			t.Fatalf("InputBody() error = %v", err)
+		}
+		if string(got) != want {
+			t.Fatalf("InputBody() = %q, want %q", got, want)
+		}
+	}
+}
+
+func TestFFTestCheck(t *testing.T) {
+	tests := parseFuncTests(t, `
+tests:
+- output:
+    body: Hello World
+- output:
+    body:
+      message: Hello Bob
+      count: 1
+- err: "invalid input"
+- err: ""
+- name: no expectations
+`)
+
+	cases := []struct {
+		test    int
+		status  int
+		body    string
+		wantErr string
+	}{
+		{0, 200, "Hello World\n", ""},
+		{0, 200, "Hello Bob", `expected body "Hello World", got "Hello Bob"`},
+		{1, 200, `{"count": 1, "message": "Hello Bob"}`, ""},
+		{1, 200, `{"message": "Hello Alice", "count": 1}`, "expected body"},
+		{1, 200, `not json`, "expected JSON body"},
+		{1, 502, `{"message": "Hello Bob", "count": 1}`, "function failed with status 502"},
+		{2, 502, `{"message":"invalid input: missing name"}`, ""},
+		{2, 502, `{"message":"something else"}`, "expected error containing"},
+		{2, 200, `ok`, "expected an error"},
+		{3, 500, `anything`, ""},
+		{4, 200, `anything`, ""},
+	}
+	for _, tc := range cases {
+		err := tests[tc.test].Check(tc.status, []byte(tc.body))
+		if tc.wantErr == "" {
+			if err != nil {
+				t.Errorf("test %d with body %q: unexpected error %v", tc.test, tc.body, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+			t.Errorf("test %d with body %q: expected error containing %q, got %v", tc.test, tc.body, tc.wantErr, err)
+		}
+	}
+}
@@ -0,0 +1,75 @@
+/*
+ * Copyright (c) 2019, 2020 Oracle and/or its affiliates. All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package common
+
+import (
+	"bytes"
+	"io"
+	"sync"
+)
+
+// prefixWriterMu serializes writes of all prefix writers, so lines of concurrent
+// builds sharing stdout never interleave
+var prefixWriterMu sync.Mutex
+
+// PrefixWriter writes every line written to it to an underlying writer, prefixed with a fixed string.
+// Incomplete lines are buffered until they are terminated or the writer is closed.
+type PrefixWriter struct {
+	w      io.Writer
+	prefix []byte
+
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+// NewPrefixWriter returns a PrefixWriter that writes to w.
+func NewPrefixWriter(w io.Writer, prefix string) *PrefixWriter {
+	return &PrefixWriter{w: w, prefix: []byte(prefix)}
+}
+
+// Write implements io.Writer.
+func (p *PrefixWriter) Write(b []byte) (int, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.buf.Write(b)
+	for {
+		i := bytes.IndexByte(p.buf.Bytes(), '\n')
+		if i < 0 {
+			return len(b), nil
+		}
+		if err := p.writeLine(p.buf.Next(i + 1)); err != nil {
+			return len(b), err
+		}
+	}
+}
+
+// Close writes any incomplete line that is still buffered.
+func (p *PrefixWriter) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.buf.Len() == 0 {
+		return nil
+	}
+	return p.writeLine(append(p.buf.Next(p.buf.Len()), '\n'))
+}
+
+func (p *PrefixWriter) writeLine(line []byte) error {
+	prefixWriterMu.Lock()
+	defer prefixWriterMu.Unlock()
+	_, err := p.w.Write(append(append([]byte{}, p.prefix...), line...))
+	return err
+}
@@ -0,0 +1,57 @@
+package common
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestPrefixWriterPrefixesLines(t *testing.T) {
+	var out bytes.Buffer
+	w := NewPrefixWriter(&out, "[fn] ")
+
+	fmt.Fprint(w, "Building image ")
+	fmt.Fprint(w, "...")
+	fmt.Fprint(w, "\nStep 1/2\nStep 2/2\nPushing")
+	if err := w.Close(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := "[fn] Building image ...\n[fn] Step 1/2\n[fn] Step 2/2\n[fn] Pushing\n"
+	if out.String() != expected {
+		t.Fatalf("expected %q, got %q", expected, out.String())
+	}
+}
+
+func TestPrefixWriterCloseWithoutPendingLine(t *testing.T) {
+	var out bytes.Buffer
+	w := NewPrefixWriter(&out, "> ")
+	fmt.Fprintln(w, "done")
+	w.Close()
+	if out.String() != "> done\n" {
+		t.Fatalf("expected a single prefixed line, got %q", out.String())
+	}
+}
+
+func TestPrefixWriterConcurrentWrites(t *testing.T) {
+	var out bytes.Buffer
+	w := NewPrefixWriter(&out, "> ")
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				fmt.Fprint(w, ".")
+				fmt.Fprintln(w, "line")
+			}
+		}()
+	}
+	wg.Wait()
+	w.Close()
+	if n := strings.Count(out.String(), "\n"); n != 400 {
+		t.Fatalf("expected 400 lines, got %d", n)
+	}
+}
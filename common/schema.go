@@ -51,6 +51,27 @@ const V20180708Schema = `{
         "run_image": {
             "type": "string"
         },
+        "cache_from": {
+            "type": "array",
+            "items": {
+                "type": "string"
+            }
+        },
+        "cache_to": {
+            "type": "array",
+            "items": {
+                "type": "string"
+            }
+        },
+        "platforms": {
+            "type": "array",
+            "items": {
+                "type": "string"
+            }
+        },
+        "enforce_signature": {
+            "type": "boolean"
+        },
         "entrypoint": {
             "type":"string"
         },
@@ -123,6 +144,68 @@ const V20180708Schema = `{
                 }
             }
         },
+        "attestations": {
+            "type": "object",
+            "properties": {
+                "sbom": {
+                    "type": "string",
+                    "enum": ["spdx", "cyclonedx"]
+                },
+                "provenance": {
+                    "type": "boolean"
+                },
+                "push": {
+                    "type": "boolean"
+                }
+            }
+        },
+        "smoke": {
+            "type": "object",
+            "properties": {
+                "payload": {},
+                "content_type": {
+                    "type": "string"
+                },
+                "expect_status": {
+                    "type": "integer"
+                },
+                "expect_body": {
+                    "type": "string"
+                },
+                "timeout": {
+                    "type": "string"
+                },
+                "rollback": {
+                    "type": "boolean"
+                }
+            }
+        },
+        "tests": {
+            "type": "array",
+            "items": {
+                "type": "object",
+                "properties": {
+                    "name": {
+                        "type": "string"
+                    },
+                    "input": {
+                        "type": "object",
+                        "properties": {
+                            "body": {}
+                        }
+                    },
+                    "output": {
+                        "type": "object",
+                        "properties": {
+                            "body": {}
+                        }
+                    },
+                    "err": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
         "triggers": {
             "type": "array",
             "properties": {
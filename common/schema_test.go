@@ -47,4 +47,79 @@ func TestValidateFileAgainstSchemaAcceptsOCIDeployConfig(t *testing.T) {
 	if err := ValidateFileAgainstSchema("temp.json", V20180708Schema); err != nil {
 		t.Fatalf("ValidateFileAgainstSchema() error = %v", err)
 	}
-}
\ No newline at end of file
+}
+
+func TestValidateFileAgainstSchemaAcceptsTests(t *testing.T) {
+	tmpDir := t.TempDir()
+	oldWd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("failed to get working directory: %v", err)
+	}
+	defer func() { _ = os.Chdir(oldWd) }()
+	if err := os.Chdir(tmpDir); err != nil {
+		t.Fatalf("failed to change working directory: %v", err)
+	}
+
+	jsonFile := filepath.Join(tmpDir, "temp.json")
+	content := `{
+		"schema_version": 20180708,
+		"name": "hello",
+		"version": "0.0.1",
+		"runtime": "go",
+		"entrypoint": "./func",
+		"tests": [
+			{
+				"name": "greets",
+				"input": {"body": {"name": "Bob"}},
+				"output": {"body": {"message": "Hello Bob"}}
+			},
+			{
+				"input": {"body": "plain"},
+				"err": "boom"
+			}
+		]
+	}`
+	if err := os.WriteFile(jsonFile, []byte(content), 0644); err != nil {
+		t.Fatalf("failed to write temp schema file: %v", err)
+	}
+
+	if err := ValidateFileAgainstSchema("temp.json", V20180708Schema); err != nil {
+		t.Fatalf("ValidateFileAgainstSchema() error = %v", err)
+	}
+}
+
+func TestValidateFileAgainstSchemaAcceptsSmoke(t *testing.T) {
+	tmpDir := t.TempDir()
+	oldWd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("failed to get working directory: %v", err)
+	}
+	defer func() { _ = os.Chdir(oldWd) }()
+	if err := os.Chdir(tmpDir); err != nil {
+		t.Fatalf("failed to change working directory: %v", err)
+	}
+
+	jsonFile := filepath.Join(tmpDir, "temp.json")
+	content := `{
+		"schema_version": 20180708,
+		"name": "hello",
+		"version": "0.0.1",
+		"runtime": "go",
+		"entrypoint": "./func",
+		"smoke": {
+			"payload": {"name": "Bob"},
+			"content_type": "application/json",
+			"expect_status": 200,
+			"expect_body": "Hello Bob",
+			"timeout": "10s",
+			"rollback": true
+		}
+	}`
+	if err := os.WriteFile(jsonFile, []byte(content), 0644); err != nil {
+		t.Fatalf("failed to write temp schema file: %v", err)
+	}
+
+	if err := ValidateFileAgainstSchema("temp.json", V20180708Schema); err != nil {
+		t.Fatalf("ValidateFileAgainstSchema() error = %v", err)
+	}
+}
@@ -0,0 +1,119 @@
+/*
+ * Copyright (c) 2019, 2020 Oracle and/or its affiliates. All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package common
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/fnproject/cli/config"
+	"github.com/spf13/viper"
+)
+
+// ImageVerifier checks image signatures made by one key
+type ImageVerifier interface {
+	// VerifyImage checks that the image has a valid signature and returns the digest of the signed image
+	VerifyImage(image string) (string, error)
+}
+
+// CosignVerifier verifies the cosign signatures of a public key
+type CosignVerifier struct {
+	PublicKey       string
+	TransparencyLog bool
+}
+
+// VerifyImage implements ImageVerifier
+func (v CosignVerifier) VerifyImage(image string) (string, error) {
+	return CosignVerify(image, v.PublicKey, v.TransparencyLog)
+}
+
+// SignaturePolicy makes deploys and function updates refuse images that are not signed by a trusted key
+type SignaturePolicy struct {
+	Enforce bool
+	// TrustedKeys are cosign public keys, env://VAR or KMS URIs
+	TrustedKeys []string
+	// TransparencyLog requires the signatures of the trusted keys to be in the Rekor transparency log
+	TransparencyLog bool
+}
+
+// ContextSignaturePolicy returns the policy of the enforce-signature and trusted-keys of the current context.
+// Signatures of the trusted keys must be in the transparency log unless trusted-keys-transparency-log is false.
+func ContextSignaturePolicy() SignaturePolicy {
+	var keys []string
+	// fn update context stores a comma separated string rather than a list
+	for _, k := range viper.GetStringSlice(config.TrustedKeys) {
+		for _, key := range strings.Split(k, ",") {
+			if key = strings.TrimSpace(key); key != "" {
+				keys = append(keys, key)
+			}
+		}
+	}
+	transparencyLog := !viper.IsSet(config.TrustedKeysTransparencyLog) || viper.GetBool(config.TrustedKeysTransparencyLog)
+	return SignaturePolicy{Enforce: viper.GetBool(config.EnforceSignature), TrustedKeys: keys, TransparencyLog: transparencyLog}
+}
+
+// SignaturePolicyV20180708 returns the policy of the context, enforced as well when func.yaml sets enforce_signature
+func (ff *FuncFileV20180708) SignaturePolicyV20180708() SignaturePolicy {
+	policy := ContextSignaturePolicy()
+	policy.Enforce = policy.Enforce || ff.EnforceSignature
+	return policy
+}
+
+// Verifiers returns the verifiers of the trusted keys
+func (p SignaturePolicy) Verifiers() []ImageVerifier {
+	var verifiers []ImageVerifier
+	for _, k := range p.TrustedKeys {
+		verifiers = append(verifiers, CosignVerifier{PublicKey: k, TransparencyLog: p.TransparencyLog})
+	}
+	return verifiers
+}
+
+// EnforceContextSignature checks the image against the signature policy of the context when it is enforced,
+// and returns it pinned to the digest of the signed image, so the tag can't be moved to another image
+// between the check and the update. The image is returned as it is when the policy isn't enforced.
+func EnforceContextSignature(image string) (string, error) {
+	policy := ContextSignaturePolicy()
+	if !policy.Enforce || image == "" {
+		return image, nil
+	}
+	digest, err := EnforceImageSignature(image, policy.Verifiers())
+	if err != nil {
+		return "", err
+	}
+	return ImageDigestRef(image, digest), nil
+}
+
+// EnforceImageSignature checks that the image is signed by one of the trusted keys, and returns the digest
+// of the signed image. A signature whose image digest is unknown doesn't count, so the image can always be
+// pinned to what was verified. The error lists why each key did not verify it.
+func EnforceImageSignature(image string, verifiers []ImageVerifier) (string, error) {
+	if len(verifiers) == 0 {
+		return "", fmt.Errorf("signatures are enforced but no key is trusted to verify %s, set %s in the context or signing_details in func.yaml", image, config.TrustedKeys)
+	}
+	var reasons []string
+	for _, v := range verifiers {
+		digest, err := v.VerifyImage(image)
+		if err == nil && digest == "" {
+			err = fmt.Errorf("the digest of the signed image of %s is unknown", image)
+		}
+		if err == nil {
+			return digest, nil
+		}
+		reasons = append(reasons, err.Error())
+	}
+	return "", fmt.Errorf("refusing to use image %s, signatures are enforced and it is not signed by a trusted key:\n  %s", image, strings.Join(reasons, "\n  "))
+}
@@ -0,0 +1,78 @@
+package common
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/fnproject/cli/config"
+	"github.com/spf13/viper"
+)
+
+type fakeVerifier struct {
+	digest string
+	err    error
+}
+
+func (v fakeVerifier) VerifyImage(image string) (string, error) {
+	return v.digest, v.err
+}
+
+func TestSignaturePolicy(t *testing.T) {
+	t.Cleanup(func() {
+		viper.Set(config.EnforceSignature, nil)
+		viper.Set(config.TrustedKeys, nil)
+		viper.Set(config.TrustedKeysTransparencyLog, nil)
+	})
+	ff := &FuncFileV20180708{EnforceSignature: true}
+	if p := ff.SignaturePolicyV20180708(); !p.Enforce || len(p.Verifiers()) != 0 {
+		t.Fatalf("expected func.yaml to enforce signatures without trusted keys, got %+v", p)
+	}
+
+	viper.Set(config.EnforceSignature, "true")
+	viper.Set(config.TrustedKeys, "/keys/a.pub, /keys/b.pub")
+	p := (&FuncFileV20180708{}).SignaturePolicyV20180708()
+	if !p.Enforce || len(p.TrustedKeys) != 2 || p.TrustedKeys[1] != "/keys/b.pub" {
+		t.Fatalf("expected the policy of the context, got %+v", p)
+	}
+	if v, ok := p.Verifiers()[0].(CosignVerifier); !ok || v.PublicKey != "/keys/a.pub" || !v.TransparencyLog {
+		t.Fatalf("expected a cosign verifier of the trusted key checking the transparency log, got %+v", p.Verifiers()[0])
+	}
+
+	viper.Set(config.TrustedKeysTransparencyLog, "false")
+	if v := ContextSignaturePolicy().Verifiers()[0].(CosignVerifier); v.TransparencyLog {
+		t.Fatalf("expected trusted-keys-transparency-log false to skip the transparency log, got %+v", v)
+	}
+}
+
+func TestEnforceImageSignature(t *testing.T) {
+	if _, err := EnforceImageSignature("reg/hello:0.0.1", nil); err == nil {
+		t.Fatal("expected an error without trusted keys")
+	}
+
+	untrusted := fakeVerifier{err: fmt.Errorf("no valid signature by a.pub")}
+	_, err := EnforceImageSignature("reg/hello:0.0.1", []ImageVerifier{untrusted, fakeVerifier{err: fmt.Errorf("no valid signature by b.pub")}})
+	if err == nil || !strings.Contains(err.Error(), "a.pub") || !strings.Contains(err.Error(), "b.pub") {
+		t.Fatalf("expected the error to list why each key failed, got %v", err)
+	}
+
+	if _, err := EnforceImageSignature("reg/hello:0.0.1", []ImageVerifier{fakeVerifier{}}); err == nil {
+		t.Fatal("expected a signature without the digest of the signed image to be refused")
+	}
+
+	digest, err := EnforceImageSignature("reg/hello:0.0.1", []ImageVerifier{untrusted, fakeVerifier{digest: "sha256:abc"}})
+	if err != nil || digest != "sha256:abc" {
+		t.Fatalf("expected the second key to verify the image, got %s %v", digest, err)
+	}
+}
+
+func TestEnforceContextSignature(t *testing.T) {
+	t.Cleanup(func() { viper.Set(config.EnforceSignature, nil) })
+	if image, err := EnforceContextSignature("reg/hello:0.0.1"); err != nil || image != "reg/hello:0.0.1" {
+		t.Fatalf("expected the image unchanged without an enforced policy, got %s %v", image, err)
+	}
+	viper.Set(config.EnforceSignature, "true")
+	if _, err := EnforceContextSignature("reg/hello:0.0.1"); err == nil {
+		t.Fatal("expected the image to be refused by the enforced policy of the context")
+	}
+}
@@ -0,0 +1,108 @@
+/*
+ * Copyright (c) 2019, 2020 Oracle and/or its affiliates. All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package common
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// DefaultSmokeTimeout is how long a smoke test waits for the function when func.yaml sets no timeout
+const DefaultSmokeTimeout = 30 * time.Second
+
+// SmokeTest is the check fn deploy runs against a function right after updating it
+type SmokeTest struct {
+	// Payload is sent as-is when it is a string, any other value is encoded as JSON
+	Payload     interface{} `yaml:"payload,omitempty" json:"payload,omitempty"`
+	ContentType string      `yaml:"content_type,omitempty" json:"content_type,omitempty"`
+	// ExpectStatus is the status the function must respond with, any 2xx status when it is not set
+	ExpectStatus int `yaml:"expect_status,omitempty" json:"expect_status,omitempty"`
+	// ExpectBody is a regular expression the response body must match
+	ExpectBody string `yaml:"expect_body,omitempty" json:"expect_body,omitempty"`
+	Timeout    string `yaml:"timeout,omitempty" json:"timeout,omitempty"`
+	// Rollback restores the function as it was before the deploy when the check fails
+	Rollback bool `yaml:"rollback,omitempty" json:"rollback,omitempty"`
+}
+
+// Validate checks the timeout and body expression of the smoke test, so a typo fails the deploy
+// before anything is built.
+func (s *SmokeTest) Validate() error {
+	if _, err := s.TimeoutDuration(); err != nil {
+		return err
+	}
+	if s.ExpectStatus != 0 && (s.ExpectStatus < 100 || s.ExpectStatus > 599) {
+		return fmt.Errorf("invalid smoke expect_status %d", s.ExpectStatus)
+	}
+	if _, err := regexp.Compile(s.ExpectBody); err != nil {
+		return fmt.Errorf("invalid smoke expect_body: %v", err)
+	}
+	return nil
+}
+
+// TimeoutDuration returns the smoke test timeout, DefaultSmokeTimeout when it is not set.
+func (s *SmokeTest) TimeoutDuration() (time.Duration, error) {
+	if s.Timeout == "" {
+		return DefaultSmokeTimeout, nil
+	}
+	d, err := time.ParseDuration(s.Timeout)
+	if err != nil {
+		return 0, fmt.Errorf("invalid smoke timeout %q: %v", s.Timeout, err)
+	}
+	if d <= 0 {
+		return 0, fmt.Errorf("invalid smoke timeout %q: must be positive", s.Timeout)
+	}
+	return d, nil
+}
+
+// PayloadBody returns the request body of the smoke test.
+func (s *SmokeTest) PayloadBody() ([]byte, error) {
+	if s.Payload == nil {
+		return nil, nil
+	}
+	if p, ok := s.Payload.(string); ok {
+		return []byte(p), nil
+	}
+	b, err := json.Marshal(normalizeYAMLValue(s.Payload))
+	if err != nil {
+		return nil, fmt.Errorf("could not encode smoke payload: %v", err)
+	}
+	return b, nil
+}
+
+// Check compares the status and body returned by the function against the expectations of the smoke test.
+func (s *SmokeTest) Check(status int, body []byte) error {
+	if s.ExpectStatus != 0 {
+		if status != s.ExpectStatus {
+			return fmt.Errorf("expected status %d, got %d: %s", s.ExpectStatus, status, strings.TrimSpace(string(body)))
+		}
+	} else if status < 200 || status > 299 {
+		return fmt.Errorf("function responded with status %d: %s", status, strings.TrimSpace(string(body)))
+	}
+	if s.ExpectBody != "" {
+		re, err := regexp.Compile(s.ExpectBody)
+		if err != nil {
+			return fmt.Errorf("invalid smoke expect_body: %v", err)
+		}
+		if !re.Match(body) {
+			return fmt.Errorf("expected body matching %q, got %q", s.ExpectBody, strings.TrimSpace(string(body)))
+		}
+	}
+	return nil
+}
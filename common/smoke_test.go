@@ -0,0 +1,75 @@
+package common
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSmokeTestValidate(t *testing.T) {
+	for _, tc := range []struct {
+		smoke SmokeTest
+		valid bool
+	}{
+		{SmokeTest{}, true},
+		{SmokeTest{Timeout: "5s", ExpectStatus: 200, ExpectBody: "^ok$"}, true},
+		{SmokeTest{Timeout: "soon"}, false},
+		{SmokeTest{Timeout: "-1s"}, false},
+		{SmokeTest{ExpectStatus: 42}, false},
+		{SmokeTest{ExpectBody: "("}, false},
+	} {
+		err := tc.smoke.Validate()
+		if tc.valid && err != nil {
+			t.Fatalf("expected %+v to be valid, got %v", tc.smoke, err)
+		}
+		if !tc.valid && err == nil {
+			t.Fatalf("expected %+v to be invalid", tc.smoke)
+		}
+	}
+
+	if d, _ := (&SmokeTest{}).TimeoutDuration(); d != DefaultSmokeTimeout {
+		t.Fatalf("expected the default timeout, got %v", d)
+	}
+	if d, _ := (&SmokeTest{Timeout: "2m"}).TimeoutDuration(); d != 2*time.Minute {
+		t.Fatalf("expected 2m, got %v", d)
+	}
+}
+
+func TestSmokeTestPayloadBody(t *testing.T) {
+	b, err := (&SmokeTest{Payload: "plain"}).PayloadBody()
+	if err != nil || string(b) != "plain" {
+		t.Fatalf("expected string payload to be sent as-is, got %q, %v", b, err)
+	}
+	b, err = (&SmokeTest{Payload: map[interface{}]interface{}{"name": "Bob"}}).PayloadBody()
+	if err != nil || string(b) != `{"name":"Bob"}` {
+		t.Fatalf("expected JSON payload, got %q, %v", b, err)
+	}
+	b, err = (&SmokeTest{}).PayloadBody()
+	if err != nil || b != nil {
+		t.Fatalf("expected no payload, got %q, %v", b, err)
+	}
+}
+
+func TestSmokeTestCheck(t *testing.T) {
+	for _, tc := range []struct {
+		smoke  SmokeTest
+		status int
+		body   string
+		pass   bool
+	}{
+		{SmokeTest{}, 200, "", true},
+		{SmokeTest{}, 204, "", true},
+		{SmokeTest{}, 502, "bad gateway", false},
+		{SmokeTest{ExpectStatus: 404}, 404, "", true},
+		{SmokeTest{ExpectStatus: 200}, 201, "", false},
+		{SmokeTest{ExpectBody: `"status":\s*"ok"`}, 200, `{"status": "ok"}`, true},
+		{SmokeTest{ExpectBody: `"status":\s*"ok"`}, 200, `{"status": "degraded"}`, false},
+	} {
+		err := tc.smoke.Check(tc.status, []byte(tc.body))
+		if tc.pass && err != nil {
+			t.Fatalf("expected %+v to pass with %d %q, got %v", tc.smoke, tc.status, tc.body, err)
+		}
+		if !tc.pass && err == nil {
+			t.Fatalf("expected %+v to fail with %d %q", tc.smoke, tc.status, tc.body)
+		}
+	}
+}
@@ -52,6 +52,10 @@ const (
 	EnvFnRegistry = "registry"
 	EnvFnContext  = "context"
 
+	EnforceSignature           = "enforce-signature"
+	TrustedKeys                = "trusted-keys"
+	TrustedKeysTransparencyLog = "trusted-keys-transparency-log"
+
 	OCI_CLI_AUTH_ENV_VAR                  = "OCI_CLI_AUTH"
 	OCI_CLI_CLOUDSHELL_ENV_VAR            = "OCI_CLI_CLOUD_SHELL"
 	OCI_CLOUDSHELL_OS_NAME                = "Oracle Linux Server"
@@ -279,15 +283,32 @@ func LoadConfiguration(c *cli.Context) error {
 	return nil
 }
 
+// Container engines that build images without a daemon. They can build and push images,
+// but not run them or keep them in a local image store.
+const (
+	ContainerEngineBuildKit = "buildkit"
+	ContainerEngineKaniko   = "kaniko"
+	ContainerEngineBuildah  = "buildah"
+)
+
 func ValidateContainerEngineType(containerEngineType string) error {
 	switch containerEngineType {
-	case "docker", "podman":
+	case "docker", "podman", ContainerEngineBuildKit, ContainerEngineKaniko, ContainerEngineBuildah:
 		return nil
 	default:
 		return errors.New("Invalid Container Engine")
 	}
 }
 
+// IsDaemonlessContainerEngine reports whether the container engine builds images without a daemon
+func IsDaemonlessContainerEngine(containerEngineType string) bool {
+	switch containerEngineType {
+	case ContainerEngineBuildKit, ContainerEngineKaniko, ContainerEngineBuildah:
+		return true
+	}
+	return false
+}
+
 func WriteConfigValueToConfigFile(key, value string) error {
 	home := GetHomeDir()
 
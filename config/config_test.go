@@ -83,6 +83,9 @@ func TestValidateContainerEngineType(t *testing.T) {
 	}{
 		{actual: "docker", expectedErr: ""},
 		{actual: "podman", expectedErr: ""},
+		{actual: "buildkit", expectedErr: ""},
+		{actual: "kaniko", expectedErr: ""},
+		{actual: "buildah", expectedErr: ""},
 		{actual: "default", expectedErr: "Invalid Container Engine"},
 	}
 	for _, c := range testCases {
@@ -111,9 +111,9 @@ type LangHelper interface {
 	// If set to false, it will use a single Docker build step, rather than multi-stage
 	IsMultiStage() bool
 	// Dockerfile build lines for building dependencies or anything else language specific
-	DockerfileBuildCmds(localDebug bool) []string
+	DockerfileBuildCmds(dir string, localDebug bool) []string
 	// DockerfileCopyCmds will run in second/final stage of multi-stage build to copy artifacts form the build stage
-	DockerfileCopyCmds(localDebug bool) []string
+	DockerfileCopyCmds(dir string, localDebug bool) []string
 	// Entrypoint sets the Docker Entrypoint. One of Entrypoint or Cmd is required.
 	Entrypoint() (string, error)
 	// DebugEntrypoint add language specific local debug point to the existing entrypoint
@@ -126,7 +126,7 @@ type LangHelper interface {
 	// CustomMemory allows a helper to specify a base memory amount, return "" to leave unspecified and let the runtime decide.
 	CustomMemory() uint64
 	HasPreBuild() bool
-	PreBuild() error
+	PreBuild(dir string) error
 	AfterBuild() error
 	// HasBoilerplate indicates whether a language has support for generating function boilerplate.
 	HasBoilerplate() bool
@@ -152,21 +152,21 @@ func defaultHandles(h LangHelper, lang string) bool {
 type BaseHelper struct {
 }
 
-func (h *BaseHelper) IsMultiStage() bool                           { return true }
-func (h *BaseHelper) DockerfileBuildCmds(localDebug bool) []string { return []string{} }
-func (h *BaseHelper) DockerfileCopyCmds(localDebug bool) []string  { return []string{} }
-func (h *BaseHelper) Entrypoint() (string, error)                  { return "", nil }
-func (h *BaseHelper) DebugEntrypoint(entryPoint string) string     { return entryPoint }
-func (h *BaseHelper) Cmd() (string, error)                         { return "", nil }
-func (h *BaseHelper) DebugCmd(cmd string) string                   { return cmd }
-func (h *BaseHelper) HasPreBuild() bool                            { return false }
-func (h *BaseHelper) PreBuild() error                              { return nil }
-func (h *BaseHelper) AfterBuild() error                            { return nil }
-func (h *BaseHelper) HasBoilerplate() bool                         { return false }
-func (h *BaseHelper) GenerateBoilerplate(string) error             { return nil }
-func (h *BaseHelper) CustomMemory() uint64                         { return 0 }
-func (h *BaseHelper) FixImagesOnInit() bool                        { return false }
-func (h *BaseHelper) GetLatestFDKVersion() (string, error)         { return "", nil }
+func (h *BaseHelper) IsMultiStage() bool                                       { return true }
+func (h *BaseHelper) DockerfileBuildCmds(dir string, localDebug bool) []string { return []string{} }
+func (h *BaseHelper) DockerfileCopyCmds(dir string, localDebug bool) []string  { return []string{} }
+func (h *BaseHelper) Entrypoint() (string, error)                              { return "", nil }
+func (h *BaseHelper) DebugEntrypoint(entryPoint string) string                 { return entryPoint }
+func (h *BaseHelper) Cmd() (string, error)                                     { return "", nil }
+func (h *BaseHelper) DebugCmd(cmd string) string                               { return cmd }
+func (h *BaseHelper) HasPreBuild() bool                                        { return false }
+func (h *BaseHelper) PreBuild(dir string) error                                { return nil }
+func (h *BaseHelper) AfterBuild() error                                        { return nil }
+func (h *BaseHelper) HasBoilerplate() bool                                     { return false }
+func (h *BaseHelper) GenerateBoilerplate(string) error                         { return nil }
+func (h *BaseHelper) CustomMemory() uint64                                     { return 0 }
+func (h *BaseHelper) FixImagesOnInit() bool                                    { return false }
+func (h *BaseHelper) GetLatestFDKVersion() (string, error)                     { return "", nil }
 
 // exists checks if a file exists
 func exists(name string) bool {
@@ -56,15 +56,15 @@ func (lh *GoLangHelper) RunFromImage() (string, error) {
 	return fmt.Sprintf("fnproject/go:%s", lh.Version), nil
 }
 
-func (h *GoLangHelper) DockerfileBuildCmds(localDebug bool) []string {
+func (h *GoLangHelper) DockerfileBuildCmds(dir string, localDebug bool) []string {
 	r := []string{}
 	// more info on Go multi-stage builds: https://medium.com/travis-on-docker/multi-stage-docker-builds-for-creating-tiny-go-images-e0e1867efe5a
 	// TODO: if we keep the go.sum on user's drive, we can put this after the dep commands and then the dep layers will be cached.
-	vendor := exists("vendor/")
+	vendor := exists(filepath.Join(dir, "vendor/"))
 	// skip dep tool install if vendor is there
-	if !vendor && exists("Gopkg.toml") {
+	if !vendor && exists(filepath.Join(dir, "Gopkg.toml")) {
 		r = append(r, "RUN go get -u github.com/golang/dep/cmd/dep")
-		if exists("Gopkg.lock") {
+		if exists(filepath.Join(dir, "Gopkg.lock")) {
 			r = append(r, "ADD Gopkg.* /go/src/func/")
 			r = append(r, "RUN cd /go/src/func/ && dep ensure --vendor-only")
 			r = append(r, "ADD . /go/src/func/")
@@ -72,7 +72,7 @@ func (h *GoLangHelper) DockerfileBuildCmds(localDebug bool) []string {
 			r = append(r, "ADD . /go/src/func/")
 			r = append(r, "RUN cd /go/src/func/ && dep ensure")
 		}
-	} else if exists("go.mod") {
+	} else if exists(filepath.Join(dir, "go.mod")) {
 		r = append(r, "WORKDIR /go/src/func/")
 		r = append(r, "ENV GO111MODULE=on")
 		if vendor {
@@ -98,7 +98,7 @@ func (h *GoLangHelper) DockerfileBuildCmds(localDebug bool) []string {
 	return r
 }
 
-func (h *GoLangHelper) DockerfileCopyCmds(localDebug bool) []string {
+func (h *GoLangHelper) DockerfileCopyCmds(dir string, localDebug bool) []string {
 	commands := []string{
 		"COPY --from=build-stage /go/src/func/func /function/",
 	}
@@ -145,14 +145,14 @@ func (lh *JavaLangHelper) DebugEntrypoint(entryPoint string) string {
 }
 
 // DockerfileCopyCmds returns the Docker COPY command to copy the compiled Java function jar and dependencies.
-func (h *JavaLangHelper) DockerfileCopyCmds(localDebug bool) []string {
+func (h *JavaLangHelper) DockerfileCopyCmds(dir string, localDebug bool) []string {
 	return []string{
 		"COPY --from=build-stage /function/target/*.jar /function/app/",
 	}
 }
 
 // DockerfileBuildCmds returns the build stage steps to compile the Maven function project.
-func (h *JavaLangHelper) DockerfileBuildCmds(localDebug bool) []string {
+func (h *JavaLangHelper) DockerfileBuildCmds(dir string, localDebug bool) []string {
 	return []string{
 		fmt.Sprintf("ENV MAVEN_OPTS %s", mavenOpts()),
 		"ADD pom.xml /function/pom.xml",
@@ -167,13 +167,8 @@ func (h *JavaLangHelper) DockerfileBuildCmds(localDebug bool) []string {
 func (h *JavaLangHelper) HasPreBuild() bool { return true }
 
 // PreBuild ensures that the expected the function is based is a maven project.
-func (h *JavaLangHelper) PreBuild() error {
-	wd, err := os.Getwd()
-	if err != nil {
-		return err
-	}
-
-	if !exists(filepath.Join(wd, "pom.xml")) {
+func (h *JavaLangHelper) PreBuild(dir string) error {
+	if !exists(filepath.Join(dir, "pom.xml")) {
 		return errors.New("Could not find pom.xml - are you sure this is a Maven project?")
 	}
 
@@ -119,14 +119,14 @@ func (lh *KotlinLangHelper) Cmd() (string, error) {
 }
 
 // DockerfileCopyCmds returns the Docker COPY command to copy the compiled Kotlin function jar and dependencies.
-func (lh *KotlinLangHelper) DockerfileCopyCmds(localDebug bool) []string {
+func (lh *KotlinLangHelper) DockerfileCopyCmds(dir string, localDebug bool) []string {
 	return []string{
 		`COPY --from=build-stage /function/target/*.jar /function/app/`,
 	}
 }
 
 // DockerfileBuildCmds returns the build stage steps to compile the Maven function project.
-func (lh *KotlinLangHelper) DockerfileBuildCmds(localDebug bool) []string {
+func (lh *KotlinLangHelper) DockerfileBuildCmds(dir string, localDebug bool) []string {
 	return []string{
 		fmt.Sprintf(`ENV MAVEN_OPTS %s`, kotlinMavenOpts()),
 		`ADD pom.xml /function/pom.xml`,
@@ -141,13 +141,8 @@ func (lh *KotlinLangHelper) DockerfileBuildCmds(localDebug bool) []string {
 func (lh *KotlinLangHelper) HasPreBuild() bool { return true }
 
 // PreBuild ensures that the expected the function is based is a maven project.
-func (lh *KotlinLangHelper) PreBuild() error {
-	wd, err := os.Getwd()
-	if err != nil {
-		return err
-	}
-
-	if !exists(filepath.Join(wd, "pom.xml")) {
+func (lh *KotlinLangHelper) PreBuild(dir string) error {
+	if !exists(filepath.Join(dir, "pom.xml")) {
 		return errors.New("Could not find pom.xml - are you sure this is a Maven project?")
 	}
 
@@ -111,11 +111,11 @@ func (h *NodeLangHelper) Entrypoint() (string, error) {
 	return "node func.js", nil
 }
 
-func (h *NodeLangHelper) DockerfileBuildCmds(localDebug bool) []string {
+func (h *NodeLangHelper) DockerfileBuildCmds(dir string, localDebug bool) []string {
 	r := []string{}
 	// skip npm -install if node_modules is local - allows local development
-	if exists("package.json") && !exists("node_modules") {
-		if exists("package-lock.json") {
+	if exists(filepath.Join(dir, "package.json")) && !exists(filepath.Join(dir, "node_modules")) {
+		if exists(filepath.Join(dir, "package-lock.json")) {
 			r = append(r, "ADD package-lock.json /function/")
 		}
 
@@ -127,10 +127,10 @@ func (h *NodeLangHelper) DockerfileBuildCmds(localDebug bool) []string {
 	return r
 }
 
-func (h *NodeLangHelper) DockerfileCopyCmds(localDebug bool) []string {
+func (h *NodeLangHelper) DockerfileCopyCmds(dir string, localDebug bool) []string {
 	// excessive but content could be anything really
 	r := []string{"ADD . /function/"}
-	if exists("package.json") && !exists("node_modules") {
+	if exists(filepath.Join(dir, "package.json")) && !exists(filepath.Join(dir, "node_modules")) {
 		r = append(r, "COPY --from=build-stage /function/node_modules/ /function/node_modules/")
 	}
 	r = append(r, "RUN chmod -R o+r /function")
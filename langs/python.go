@@ -91,7 +91,7 @@ func (h *PythonLangHelper) Entrypoint() (string, error) {
 	return "/python/bin/fdk /function/func.py handler", nil
 }
 
-func (h *PythonLangHelper) DockerfileBuildCmds(localDebug bool) []string {
+func (h *PythonLangHelper) DockerfileBuildCmds(dir string, localDebug bool) []string {
 	var r []string
 
 	pip_cmd := `RUN pip3 install --target /python/ --no-cache --no-cache-dir`
@@ -99,8 +99,8 @@ func (h *PythonLangHelper) DockerfileBuildCmds(localDebug bool) []string {
 		r = append(r, fmt.Sprintf("%s debugpy", pip_cmd))
 		r = append(r, "RUN rm -rf /python/bin")
 	}
-	if exists("requirements.txt") {
-		if exists(".pip_cache") {
+	if exists(filepath.Join(dir, "requirements.txt")) {
+		if exists(filepath.Join(dir, ".pip_cache")) {
 			r = append(r, "ADD .pip_cache /function/.pip_cache")
 			pip_cmd += " --no-index --find-links /function/.pip_cache"
 		}
@@ -111,7 +111,7 @@ func (h *PythonLangHelper) DockerfileBuildCmds(localDebug bool) []string {
 			    chmod -R o+r /python`, pip_cmd))
 	}
 	r = append(r, "ADD . /function/")
-	if exists("setup.py") {
+	if exists(filepath.Join(dir, "setup.py")) {
 		r = append(r, fmt.Sprintf("%s .", pip_cmd))
 	}
 	r = append(r, "RUN rm -fr /function/.pip_cache")
@@ -149,7 +149,7 @@ def handler(ctx, data: io.BytesIO = None):
 	reqsPythonSrcBoilerplate = `fdk%s`
 )
 
-func (h *PythonLangHelper) DockerfileCopyCmds(localDebug bool) []string {
+func (h *PythonLangHelper) DockerfileCopyCmds(dir string, localDebug bool) []string {
 	return []string{
 		"COPY --from=build-stage /python /python",
 		"COPY --from=build-stage /function /function",
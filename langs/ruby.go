@@ -56,9 +56,9 @@ func (h *RubyLangHelper) RunFromImage() (string, error) {
 	return fmt.Sprintf("fnproject/ruby:%s", h.Version), nil
 }
 
-func (h *RubyLangHelper) DockerfileBuildCmds(localDebug bool) []string {
+func (h *RubyLangHelper) DockerfileBuildCmds(dir string, localDebug bool) []string {
 	r := []string{}
-	if exists("Gemfile") {
+	if exists(filepath.Join(dir, "Gemfile")) {
 		r = append(r,
 			"ADD Gemfile* /function/",
 			"RUN bundle install",
@@ -67,7 +67,7 @@ func (h *RubyLangHelper) DockerfileBuildCmds(localDebug bool) []string {
 	return r
 }
 
-func (h *RubyLangHelper) DockerfileCopyCmds(localDebug bool) []string {
+func (h *RubyLangHelper) DockerfileCopyCmds(dir string, localDebug bool) []string {
 	return []string{
 		"COPY --from=build-stage /usr/lib/ruby/gems/ /usr/lib/ruby/gems/", // skip this if no Gemfile?  Does it matter?
 		"COPY . /function/",
@@ -1048,6 +1048,10 @@ func (f *fnsCmd) create(c *cli.Context) error {
 		}
 	}
 
+	if fn.Image, err = common.EnforceContextSignature(fn.Image); err != nil {
+		return err
+	}
+
 	a, err := app.GetAppByName(f.client, appName)
 	if err != nil {
 		return err
@@ -1265,6 +1269,12 @@ func (f *fnsCmd) update(c *cli.Context) error {
 		}
 	}
 
+	if image := c.String("image"); image != "" {
+		if fn.Image, err = common.EnforceContextSignature(image); err != nil {
+			return err
+		}
+	}
+
 	err = PutFnWithControl(f.client, fn.ID, fn, control)
 	if err != nil {
 		return err